@@ -0,0 +1,61 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "bring a registry into the state of a manifest",
+	Long: `Make the changes shown by tb plan, i.e. create subjects, update descriptions, set configs and register schemas.
+Nothing is changed and tb exits with status 5 if a schema would be rejected by the compatibility of its subject.
+If a change fails, the changes applied so far are shown and the rest can be applied by running it again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		plan := planManifest(cmd)
+		if !plan.IsAllowed() {
+			showPlan(plan.Changes, "Plan")
+			exitWithCode(fmt.Errorf("nothing is applied since the plan has schemas violating the compatibility of their subjects"), exitCodeIncompatible)
+		}
+
+		applied, err := plan.Apply(context.Background(), newClient())
+		if err != nil {
+			if len(applied) > 0 {
+				showPlan(applied, "Applied")
+			}
+			exitWithError(err)
+		}
+		showPlan(applied, "Applied")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringP("file", "f", "", "path to a manifest (required)")
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestApply(t *testing.T) {
+	defer gock.Off()
+
+	path := writeManifest(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "$").
+		Reply(404).
+		JSON(model.ServerError{ErrorCode: 404, Message: "Subject Not Found"})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "$").
+		BodyString(testDescription).
+		Reply(201).
+		BodyString("0")
+	gock.New(hostForTest).
+		Put("/config/" + testSubject + "$").
+		JSON(model.Config{Compatibility: "FULL"}).
+		Reply(200).
+		BodyString("1")
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/versions$").
+		Reply(201).
+		JSON(model.SchemaId{Id: 1})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookup$").
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"apply", "-f", path}
+	applyCmd.Root().SetArgs(args)
+
+	out, _ := captureOutput(func() {
+		if err := applyCmd.Execute(); err != nil {
+			t.Errorf("apply command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	for _, expect := range []string{
+		"+ create subject `test-subject`",
+		"~ set compatibility of `test-subject`: NONE -> FULL",
+		"+ register person.avsc under `test-subject` as v1.0.0",
+		"Applied: 3 changes",
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("apply command is expected to show `%s` but got\n%s", expect, out)
+		}
+	}
+	if !gock.IsDone() {
+		t.Errorf("the subject, config and schema are expected to be created")
+	}
+}
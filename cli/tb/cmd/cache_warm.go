@@ -0,0 +1,83 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	typebook "github.com/cyberagent/typebook/client/go"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+var cacheWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "prefill a local schema cache",
+	Long: `Prefill a local schema cache with all versions of schemas under the specified subjects.
+If no subject is specified, schemas under all subjects are cached.
+Schemas are cached in the directory given by --cache-dir, or ~/.typebook-cache by default.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if viper.GetBool("offline") {
+			exitWithUsage(cmd, fmt.Errorf("cache cannot be warmed in offline mode"))
+		}
+		subjects, err := cmd.Flags().GetStringSlice("subject")
+		if err != nil {
+			exitWithUsage(cmd, err)
+		}
+
+		// the cache is always attached, in the default directory without --cache-dir, so that --offline finds the schemas
+		client := typebook.NewClient(viper.GetString("url"), append(clientOptions(), typebook.WithDiskCache(newDiskCache()))...)
+		if len(subjects) == 0 {
+			all, err := client.ListSubjects()
+			if err != nil {
+				exitWithError(err)
+			}
+			subjects = all
+		}
+
+		schemas := make([]model.Schema, 0)
+		for _, subject := range subjects {
+			versions, err := client.ListVersions(subject)
+			if err != nil {
+				exitWithError(err)
+			}
+			for _, version := range versions {
+				schema, err := client.GetSchemaBySemVer(subject, version)
+				if err != nil {
+					exitWithError(err)
+				}
+				schemas = append(schemas, *schema)
+			}
+		}
+		showSchemaMetas(schemas...)
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheWarmCmd)
+
+	cacheWarmCmd.Flags().StringSlice("subject", nil, "name of subjects to cache. It can be specified multiple times")
+}
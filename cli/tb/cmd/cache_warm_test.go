@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWarmCache(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions").
+		Reply(200).
+		JSON(`["v1.0.0", "v1.1.0"]`)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(`{"id":1,"subject":"` + testSubject + `","version":"v1.0.0","schema":"{\"type\":\"string\"}"}`)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.1.0").
+		Reply(200).
+		JSON(`{"id":2,"subject":"` + testSubject + `","version":"v1.1.0","schema":"{\"type\":\"string\"}"}`)
+
+	args := []string{"cache", "warm", "--subject", testSubject}
+	cacheWarmCmd.Root().SetArgs(args)
+
+	if err := cacheWarmCmd.Execute(); err != nil {
+		t.Errorf("cache warm command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+	for _, path := range []string{"ids/1.json", "ids/2.json", "subjects/" + testSubject + "/v1.1.0.json"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("%s is expected to be cached but %v", path, err)
+		}
+	}
+}
+
+func TestWarmCacheInDefaultDirectory(t *testing.T) {
+	defer gock.Off()
+
+	home, err := ioutil.TempDir("", "typebook-home-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	homedir.Reset()
+	defer homedir.Reset()
+
+	// persisted since --subject keeps the value of the previous test, to which another one is appended
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions$").
+		Persist().
+		Reply(200).
+		JSON(`["v1.0.0"]`)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0$").
+		Persist().
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"cache", "warm", "--subject", testSubject}
+	cacheWarmCmd.Root().SetArgs(args)
+
+	captureOutput(func() {
+		if err := cacheWarmCmd.Execute(); err != nil {
+			t.Errorf("cache warm command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	for _, path := range []string{"ids/1.json", "subjects/" + testSubject + "/v1.0.0.json"} {
+		if _, err := os.Stat(filepath.Join(home, ".typebook-cache", path)); err != nil {
+			t.Errorf("%s is expected to be cached in the default directory but %v", path, err)
+		}
+	}
+}
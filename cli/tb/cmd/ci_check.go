@@ -0,0 +1,260 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/dirsync"
+)
+
+var ciCheckCmd = &cobra.Command{
+	Use:   "check $dir",
+	Short: "check changed schemas in a directory against a typebook server",
+	Long: `Check schemas laid out as $dir/$subject/*.avsc which are new or changed since $dir/typebook.lock was written by tb sync,
+or every schema with --all, without registering them.
+Each schema is checked against the latest schema under its subject with the compatibility configured to the subject,
+and the version it would be given is predicted in the same way as tb schema create --dry-run.
+With --junit, the result is also written as JUnit XML, where each subject is a test suite and each schema is a test case.
+With --annotations, which is enabled by default on GitHub Actions, a violation is written to stderr as an annotation of the file.
+It exits with status 5 if a schema is invalid or violates the compatibility of its subject.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		dir := args[0]
+		all, _ := cmd.Flags().GetBool("all")
+		junit, _ := cmd.Flags().GetString("junit")
+		annotations, _ := cmd.Flags().GetBool("annotations")
+		if !cmd.Flags().Changed("annotations") {
+			annotations = os.Getenv("GITHUB_ACTIONS") == "true"
+		}
+
+		var files []string
+		var err error
+		if all {
+			files, err = dirsync.SchemaFiles(dir)
+		} else {
+			files, err = dirsync.ChangedFiles(dir)
+		}
+		if err != nil {
+			exitWithUsage(cmd, err)
+		}
+
+		checked, err := dirsync.Check(context.Background(), newClient(), dir, files)
+		if err != nil {
+			exitWithError(err)
+		}
+		if junit != "" {
+			if err := writeJUnit(junit, dir, checked); err != nil {
+				exitWithError(err)
+			}
+		}
+		if annotations {
+			writeAnnotations(stderr, dir, checked)
+		}
+		showCheckedSchemas(checked)
+
+		violated := 0
+		for _, c := range checked {
+			if !c.IsAllowed() {
+				violated++
+			}
+		}
+		if violated > 0 {
+			exitWithCode(fmt.Errorf("%d of %d schemas violate the compatibility of their subjects", violated, len(checked)), exitCodeIncompatible)
+		}
+	},
+}
+
+func init() {
+	ciCmd.AddCommand(ciCheckCmd)
+
+	ciCheckCmd.Flags().Bool("all", false, "check every schema rather than ones changed since the lockfile was written")
+	ciCheckCmd.Flags().String("junit", "", "path to a file to write the result as JUnit XML")
+	ciCheckCmd.Flags().Bool("annotations", false, "write violations as GitHub Actions annotations (default true on GitHub Actions)")
+}
+
+func showCheckedSchemas(checked []dirsync.CheckedSchema) {
+	printResult(checked, func(w io.Writer, wide bool) {
+		if len(checked) == 0 {
+			fmt.Fprintln(w, "No schema is changed")
+			return
+		}
+		renderCheckedSchemas(w, wide, checked)
+	})
+}
+
+func renderCheckedSchemas(w io.Writer, wide bool, checked []dirsync.CheckedSchema) {
+	header := []string{"FILE", "SUBJECT", "RESTRICTION", "VERSION", "RESULT"}
+	if wide {
+		header = append(header, "COMPATIBILITY", "COMPARISON")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	violations := 0
+	for _, c := range checked {
+		result := "PASS"
+		if !c.IsAllowed() {
+			result = "FAIL"
+		}
+		violations += len(c.Violations)
+		row := []string{c.File, c.Subject, c.Restriction, c.Version, result}
+		if wide {
+			compatibility, comparison := "", ""
+			if c.Report != nil {
+				compatibility, comparison = c.Report.Compatibility, c.Report.ComparisonVersion
+			}
+			row = append(row, compatibility, comparison)
+		}
+		table.Append(row)
+	}
+	table.Render()
+
+	if violations == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Violations: %d\n", violations)
+	table = tablewriter.NewWriter(w)
+	table.SetHeader([]string{"FILE", "KIND", "PATH", "MESSAGE"})
+	for _, c := range checked {
+		for _, v := range c.Violations {
+			table.Append([]string{c.File, v.Kind, v.Path, v.Message})
+		}
+	}
+	table.Render()
+}
+
+// violationMessage describes a violation in a line.
+func violationMessage(v dirsync.Violation) string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// writeAnnotations writes violations as error annotations and the versions of allowed schemas as notices
+// in the format of workflow commands of GitHub Actions. Files are annotated with paths joined with dir.
+func writeAnnotations(w io.Writer, dir string, checked []dirsync.CheckedSchema) {
+	for _, c := range checked {
+		file := escapeAnnotationProperty(filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(c.File))))
+		if c.IsAllowed() {
+			fmt.Fprintf(w, "::notice file=%s,title=%s::%s\n", file, escapeAnnotationProperty(c.Subject),
+				escapeAnnotationData(fmt.Sprintf("would be registered under `%s` as %s", c.Subject, c.Version)))
+			continue
+		}
+		for _, v := range c.Violations {
+			fmt.Fprintf(w, "::error file=%s,title=%s::%s\n", file, escapeAnnotationProperty(v.Kind), escapeAnnotationData(violationMessage(v)))
+		}
+	}
+}
+
+var annotationDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+var annotationPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func escapeAnnotationData(s string) string {
+	return annotationDataEscaper.Replace(s)
+}
+
+func escapeAnnotationProperty(s string) string {
+	return annotationPropertyEscaper.Replace(s)
+}
+
+// junitTestSuites is the root of a JUnit XML report, in which each subject is a test suite and each schema is a test case of it.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReportOf builds a report with a test suite for each subject in order of the checked schemas.
+func junitReportOf(dir string, checked []dirsync.CheckedSchema) *junitTestSuites {
+	report := &junitTestSuites{Suites: make([]junitTestSuite, 0)}
+	indices := make(map[string]int)
+	for _, c := range checked {
+		i, ok := indices[c.Subject]
+		if !ok {
+			i = len(report.Suites)
+			indices[c.Subject] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: c.Subject, TestCases: make([]junitTestCase, 0)})
+		}
+		suite := &report.Suites[i]
+
+		tc := junitTestCase{Name: filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(c.File))), ClassName: c.Subject}
+		if !c.IsAllowed() {
+			lines := make([]string, 0, len(c.Violations))
+			for _, v := range c.Violations {
+				lines = append(lines, fmt.Sprintf("%s %s", v.Kind, violationMessage(v)))
+			}
+			tc.Failure = &junitFailure{
+				Message: violationMessage(c.Violations[0]),
+				Type:    c.Violations[0].Kind,
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	return report
+}
+
+func writeJUnit(path, dir string, checked []dirsync.CheckedSchema) error {
+	content, err := xml.MarshalIndent(junitReportOf(dir, checked), "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append([]byte(xml.Header), content...)
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/dirsync"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+const incompatibleSchemaDef = `
+{
+    "namespace": "com.example",
+	"name": "Person",
+    "type": "record",
+    "fields": [
+        {"name": "id", "type": "int"},
+        {"name": "first_name", "type": "long"}
+    ]
+}
+`
+
+func TestCiCheck(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-ci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, testSubject), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, testSubject, "person.avsc"), []byte(incompatibleSchemaDef), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "BACKWARD"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/latest$").
+		Reply(200).
+		JSON(testSchema)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions$").
+		Reply(200).
+		JSON([]string{"v1.0.0"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0$").
+		Reply(200).
+		JSON(testSchema)
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "/properties/compatibility$").
+		Reply(200).
+		BodyString("BACKWARD")
+
+	junit := filepath.Join(dir, "report.xml")
+	args := []string{"ci", "check", dir, "--junit", junit, "--annotations"}
+	ciCheckCmd.Root().SetArgs(args)
+	defer ciCheckCmd.Flags().Set("junit", "")
+	defer ciCheckCmd.Flags().Set("annotations", "false")
+
+	out, errOut := captureOutput(func() {
+		expectExit(t, exitCodeIncompatible, func() {
+			ciCheckCmd.Execute()
+		})
+	})
+	for _, expect := range []string{testSubject + "/person.avsc", "FAIL", "v2.0.0", "TYPE_MISMATCH", "$.first_name"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("ci check command is expected to show `%s` but got\n%s", expect, out)
+		}
+	}
+	annotation := "::error file=" + filepath.ToSlash(filepath.Join(dir, testSubject, "person.avsc")) + ",title=TYPE_MISMATCH::$.first_name: "
+	if !strings.Contains(errOut, annotation) {
+		t.Errorf("ci check command is expected to write an annotation but got\n%s", errOut)
+	}
+
+	content, err := ioutil.ReadFile(junit)
+	if err != nil {
+		t.Fatalf("a JUnit report is expected to be written: %v", err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(content, &report); err != nil {
+		t.Fatalf("the JUnit report is expected to be XML but got %s", content)
+	}
+	if report.Tests != 1 || report.Failures != 1 || len(report.Suites) != 1 || report.Suites[0].Name != testSubject ||
+		report.Suites[0].TestCases[0].Failure == nil || report.Suites[0].TestCases[0].Failure.Type != model.TypeMismatch {
+		t.Errorf("the JUnit report is expected to have a failure of TYPE_MISMATCH but got %s", content)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the compatibility and the version are expected to be checked")
+	}
+}
+
+func TestCiCheckNoChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "typebook-ci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"ci", "check", dir}
+	ciCheckCmd.Root().SetArgs(args)
+
+	out, _ := captureOutput(func() {
+		if err := ciCheckCmd.Execute(); err != nil {
+			t.Errorf("ci check command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	if !strings.Contains(out, "No schema is changed") {
+		t.Errorf("ci check command is expected to show no change but got\n%s", out)
+	}
+}
+
+func TestJUnitReportBySubject(t *testing.T) {
+	violation := dirsync.Violation{Kind: model.TypeMismatch, Path: "/fields/1/type", Message: "int is not compatible with long"}
+	checked := []dirsync.CheckedSchema{
+		{File: "page-views/v1.avsc", Subject: "page-views"},
+		{File: "page-views/v2.avsc", Subject: "page-views", Violations: []dirsync.Violation{violation}},
+		{File: "user-events/v1.avsc", Subject: "user-events"},
+	}
+	report := junitReportOf("schemas", checked)
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 2 {
+		t.Fatalf("a report is expected to have 3 tests with 1 failure in 2 suites but got %+v", report)
+	}
+	pageViews, userEvents := report.Suites[0], report.Suites[1]
+	if pageViews.Name != "page-views" || pageViews.Tests != 2 || pageViews.Failures != 1 || pageViews.TestCases[1].Name != "schemas/page-views/v2.avsc" {
+		t.Errorf("page-views is expected to be a suite with 2 tests and 1 failure but got %+v", pageViews)
+	}
+	if userEvents.Name != "user-events" || userEvents.Tests != 1 || userEvents.Failures != 0 {
+		t.Errorf("user-events is expected to be a suite with 1 test but got %+v", userEvents)
+	}
+}
+
+func TestEscapeAnnotation(t *testing.T) {
+	if s := escapeAnnotationData("50% of\nlines"); s != "50%25 of%0Alines" {
+		t.Errorf("escapeAnnotationData = %s", s)
+	}
+	if s := escapeAnnotationProperty("a:b,c"); s != "a%3Ab%2Cc" {
+		t.Errorf("escapeAnnotationProperty = %s", s)
+	}
+}
@@ -0,0 +1,101 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/codegen"
+)
+
+var codegenGoCmd = &cobra.Command{
+	Use:   "go",
+	Short: "generate Go types from a schema",
+	Long: `Generate Go types from a schema specified by a subject and a version.
+version is optional. If omitted, the latest schema under the subject is used.
+Available form of version is semantic version (e.g. v1.0.0) or major version (e.g. v1).
+Records are generated as structs, enums as string types with constants for their symbols and unions as pointers or structs.
+The subject, version, ID and definition of the schema are embedded as constants, which a serializer can be pinned to.
+The package name defaults to $GOPACKAGE so that it can be used with go generate.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("subject", cmd.Flags().Lookup("subject"))
+		viper.BindPFlag("version", cmd.Flags().Lookup("version"))
+		viper.BindPFlag("package", cmd.Flags().Lookup("package"))
+		viper.BindPFlag("out", cmd.Flags().Lookup("out"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		subject := viper.GetString("subject")
+		if subject == "" {
+			exitWithUsage(cmd, fmt.Errorf("subject is not specified"))
+		}
+		pkg := viper.GetString("package")
+		if pkg == "" {
+			pkg = os.Getenv("GOPACKAGE")
+		}
+		if pkg == "" {
+			pkg = "main"
+		}
+
+		schema := getSchemaByVersion(cmd, newClient(), subject, viper.GetString("version"))
+		src, err := codegen.Go(schema, pkg)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to generate code: %v", err))
+		}
+
+		generated := &generatedCode{Subject: subject, Version: schema.Version.String(), Package: pkg}
+		if out := viper.GetString("out"); out != "" {
+			if err := ioutil.WriteFile(out, src, 0644); err != nil {
+				exitWithError(err)
+			}
+			generated.Path = out
+			printMessage(generated, "Go types are generated from %s of `%s` in %s\n", generated.Version, subject, out)
+		} else {
+			generated.Source = string(src)
+			printMessage(generated, "%s", generated.Source)
+		}
+	},
+}
+
+func init() {
+	codegenCmd.AddCommand(codegenGoCmd)
+
+	codegenGoCmd.Flags().String("subject", "", "name of subject (required)")
+	codegenGoCmd.Flags().String("version", "", "version of schema (optional)")
+	codegenGoCmd.Flags().String("package", "", "name of package of generated code (default $GOPACKAGE or main)")
+	codegenGoCmd.Flags().String("out", "", "path to a file to write generated code (default stdout)")
+}
+
+// generatedCode is the document of generated code. Source is empty if it is written to Path.
+type generatedCode struct {
+	Subject string `json:"subject"`
+	Version string `json:"version"`
+	Package string `json:"package"`
+	Path    string `json:"path,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCodegenGo(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-codegen-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(testSchema)
+
+	out := filepath.Join(dir, "person.go")
+	args := []string{"codegen", "go", "--subject", testSubject, "--version", "v1.0.0", "--package", "events", "--out", out}
+	codegenGoCmd.Root().SetArgs(args)
+
+	if err := codegenGoCmd.Execute(); err != nil {
+		t.Errorf("codegen go command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+	src, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("generated code is expected to be written in %s: %v", out, err)
+	}
+	for _, expect := range []string{"package events", "type Person struct", `PersonVersion  = "v1.0.0"`} {
+		if !strings.Contains(string(src), expect) {
+			t.Errorf("generated code is expected to contain `%s`\n%s", expect, src)
+		}
+	}
+}
@@ -24,11 +24,15 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
 	"github.com/cyberagent/typebook/client/go/model"
 )
 
@@ -39,18 +43,25 @@ var compatibilityCheckCmd = &cobra.Command{
 This takes a path to schema file or definition as the first argument.
 A path should begin with @.
 If version is omitted it compares with the latest schema under the subject.
-Possible values for version is what represents major version (e.g. v1) or semantic version (e.g. v1.0.0)`,
+Possible values for version is what represents major version (e.g. v1) or semantic version (e.g. v1.0.0)
+
+The compatibility is calculated locally without a typebook server if --offline is set,
+comparing with a schema in the cache directory, or if --against is given, comparing with the given schema.
+Then it also shows which of NONE, FORWARD, BACKWARD or FULL the compatibility is.
+It exits with status 5 if the schema is incompatible.`,
 	Args: cobra.ExactArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("subject", cmd.Flags().Lookup("subject"))
 		viper.BindPFlag("version", cmd.Flags().Lookup("version"))
+		viper.BindPFlag("against", cmd.Flags().Lookup("against"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		subject := viper.GetString("subject")
 		version := viper.GetString("version")
-		if subject == "" {
-			exitWithError(fmt.Errorf("subject is not specified"))
+		against := viper.GetString("against")
+		if subject == "" && against == "" {
+			exitWithUsage(cmd, fmt.Errorf("subject is not specified"))
 		}
 
 		content, err := valueOrFromPath(args[0])
@@ -58,24 +69,56 @@ Possible values for version is what represents major version (e.g. v1) or semant
 			exitWithError(err)
 		}
 
+		if against != "" {
+			comparison, err := valueOrFromPath(against)
+			if err != nil {
+				exitWithError(err)
+			}
+			showCompatibilityReport(localCompatibilityReport(string(content), string(comparison)))
+			return
+		}
+
 		client := newClient()
+		var (
+			check   func() (*model.Compatibility, *model.Error)
+			explain func() (*model.CompatibilityReport, *model.Error)
+		)
 		if version == "" {
-			showIsCompatible(func() (*model.Compatibility, *model.Error) {
+			check = func() (*model.Compatibility, *model.Error) {
 				return client.CheckCompatibilityWithLatest(subject, string(content))
-			})
+			}
+			explain = func() (*model.CompatibilityReport, *model.Error) {
+				return client.ReportCompatibilityWithLatest(subject, string(content))
+			}
 		} else if model.IsMajorVer(version) {
 			majorVer, _ := strconv.Atoi(version[1:])
-			showIsCompatible(func() (*model.Compatibility, *model.Error) {
+			check = func() (*model.Compatibility, *model.Error) {
 				return client.CheckCompatibilityWithMajorVersion(subject, majorVer, string(content))
-			})
+			}
+			explain = func() (*model.CompatibilityReport, *model.Error) {
+				return client.ReportCompatibilityWithMajorVersion(subject, majorVer, string(content))
+			}
 		} else if model.IsSemVer(version) {
 			semver, _ := model.NewSemVer(version)
-			showIsCompatible(func() (*model.Compatibility, *model.Error) {
+			check = func() (*model.Compatibility, *model.Error) {
 				return client.CheckCompatibilityWithSemVer(subject, *semver, string(content))
-			})
+			}
+			explain = func() (*model.CompatibilityReport, *model.Error) {
+				return client.ReportCompatibilityWithSemVer(subject, *semver, string(content))
+			}
 		} else {
 			exitWithUsage(cmd, fmt.Errorf("invalid format version `%s` Valid forms are major version (e.g. v1) or semantic version (e.g. v1.0.0)", version))
 		}
+
+		if viper.GetBool("offline") {
+			report, err := explain()
+			if err != nil {
+				exitWithError(err)
+			}
+			showCompatibilityReport(report)
+			return
+		}
+		showIsCompatible(check, explain)
 	},
 }
 
@@ -84,17 +127,75 @@ func init() {
 
 	compatibilityCheckCmd.Flags().String("subject", "", "name of subject (required)")
 	compatibilityCheckCmd.Flags().String("version", "", "version of comparison (optional).")
+	compatibilityCheckCmd.Flags().String("against", "", "schema to compare with instead of a registered one (@$path | $definition)")
 }
 
-func showIsCompatible(f func() (*model.Compatibility, *model.Error)) {
-	if compatibility, err := f(); err != nil {
+// localCompatibilityReport calculates compatibility between target and comparison without a typebook server.
+func localCompatibilityReport(target, comparison string) *model.CompatibilityReport {
+	targetSchema, err := avro.Parse(target)
+	if err != nil {
 		exitWithError(err)
-	} else {
-		if compatibility.IsCompatible {
-			fmt.Println("Compatible")
+	}
+	comparisonSchema, err := avro.Parse(comparison)
+	if err != nil {
+		exitWithError(err)
+	}
+	return compatibility.Report(targetSchema, comparisonSchema)
+}
+
+// showCompatibilityReport shows whether the target schema is compatible, the compatibility between the schemas
+// and every incompatibility found. The wide table also shows the reader's and writer's types of each incompatibility.
+// It exits with exitCodeIncompatible if the target schema is incompatible.
+func showCompatibilityReport(report *model.CompatibilityReport) {
+	printResult(report, func(w io.Writer, wide bool) {
+		if report.IsCompatible {
+			fmt.Fprintln(w, "Compatible")
 		} else {
-			fmt.Println("Incompatible")
+			fmt.Fprintln(w, "Incompatible")
+		}
+		if report.Compatibility != "" {
+			fmt.Fprintf(w, "Compatibility: %s\n", report.Compatibility)
+		}
+		if len(report.Incompatibilities) > 0 {
+			table := tablewriter.NewWriter(w)
+			header := []string{"DIRECTION", "PATH", "KIND", "MESSAGE"}
+			if wide {
+				header = append(header, "READER", "WRITER")
+			}
+			table.SetHeader(header)
+			for _, i := range report.Incompatibilities {
+				row := []string{i.Direction, i.Path, i.Kind, i.Message}
+				if wide {
+					row = append(row, i.Reader, i.Writer)
+				}
+				table.Append(row)
+			}
+			table.Render()
+		}
+		fmt.Fprintln(w)
+	})
+
+	if !report.IsCompatible {
+		exit(exitCodeIncompatible)
+	}
+}
+
+// showIsCompatible shows the result of check done by a typebook server.
+// If the schema is incompatible, it tries to explain why with the report given by explain.
+func showIsCompatible(check func() (*model.Compatibility, *model.Error), explain func() (*model.CompatibilityReport, *model.Error)) {
+	result, err := check()
+	if err != nil {
+		exitWithError(err)
+	}
+	if !result.IsCompatible {
+		report, err := explain()
+		if err == nil {
+			// the server is the source of truth about whether the schema is compatible
+			report.IsCompatible = false
+			showCompatibilityReport(report)
+			return
 		}
-		fmt.Println()
+		fmt.Fprintf(stderr, "details of incompatibility are unavailable: %s\n", err.Error())
 	}
+	showCompatibilityReport(&model.CompatibilityReport{IsCompatible: result.IsCompatible, Incompatibilities: []model.Incompatibility{}})
 }
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"gopkg.in/h2non/gock.v1"
 
+	typebook "github.com/cyberagent/typebook/client/go"
 	"github.com/cyberagent/typebook/client/go/model"
 )
 
@@ -52,9 +55,9 @@ func TestCompatibilityCheckValueWithMajorVer(t *testing.T) {
 	args := []string{"compatibility", "check", schemaDef, "--subject", testSubject, "--version", "v1"}
 	compatibilityCheckCmd.Root().SetArgs(args)
 
-	if err := compatibilityCheckCmd.Execute(); err != nil {
-		t.Errorf("compatibility check command is expected to be success with args %v but an error was occured %v", args, err)
-	}
+	expectExit(t, exitCodeIncompatible, func() {
+		compatibilityCheckCmd.Execute()
+	})
 }
 
 func TestCompatibilityCheckValueWithSemVer(t *testing.T) {
@@ -73,7 +76,84 @@ func TestCompatibilityCheckValueWithSemVer(t *testing.T) {
 	args := []string{"compatibility", "check", schemaDef, "--subject", testSubject, "--version", "v1.2.1"}
 	compatibilityCheckCmd.Root().SetArgs(args)
 
+	expectExit(t, exitCodeIncompatible, func() {
+		compatibilityCheckCmd.Execute()
+	})
+}
+
+func TestCompatibilityCheckOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := typebook.NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(&testSchema)
+
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	os.Setenv("TYPEBOOK_OFFLINE", "true")
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+	defer os.Unsetenv("TYPEBOOK_OFFLINE")
+
+	// no request should be issued
+	args := []string{"compatibility", "check", schemaDef, "--subject", testSubject, "--version", "v1.0.0"}
+	compatibilityCheckCmd.Root().SetArgs(args)
+
+	if err := compatibilityCheckCmd.Execute(); err != nil {
+		t.Errorf("compatibility check command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+}
+
+func TestCompatibilityCheckAgainst(t *testing.T) {
+	// no request should be issued
+	args := []string{"compatibility", "check", fmt.Sprintf("@%s", sampleSchemaPath), "--against", fmt.Sprintf("@%s", sampleSchemaPath)}
+	compatibilityCheckCmd.Root().SetArgs(args)
+	defer compatibilityCheckCmd.Flags().Set("against", "")
+
 	if err := compatibilityCheckCmd.Execute(); err != nil {
 		t.Errorf("compatibility check command is expected to be success with args %v but an error was occured %v", args, err)
 	}
 }
+
+func TestCompatibilityCheckIncompatibleWithDetails(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	gock.New(hostForTest).
+		Post("/compatibility/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(model.Compatibility{IsCompatible: false})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"compatibility", "check", fmt.Sprintf("@%s", sampleSchemaPath), "--subject", testSubject, "--version", "v1.0.0", "--output", "json"}
+	compatibilityCheckCmd.Root().SetArgs(args)
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	out, _ := captureOutput(func() {
+		expectExit(t, exitCodeIncompatible, func() {
+			compatibilityCheckCmd.Execute()
+		})
+	})
+	report := new(model.CompatibilityReport)
+	if err := json.Unmarshal([]byte(out), report); err != nil {
+		t.Errorf("compatibility check command is expected to print a report in JSON but got %s", out)
+	} else if report.IsCompatible || len(report.Incompatibilities) == 0 {
+		t.Errorf("compatibility check command is expected to report incompatibilities but got %+v", report)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the comparison schema is expected to be retrieved to explain the incompatibility")
+	}
+}
@@ -24,7 +24,7 @@ package cmd
 
 import (
 	"bytes"
-	"os"
+	"io"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -42,8 +42,20 @@ func init() {
 	RootCmd.AddCommand(configCmd)
 }
 
+// configDeletion is the document of a deleted config, or a deleted property if Property is not empty.
+type configDeletion struct {
+	Subject  string `json:"subject"`
+	Property string `json:"property,omitempty"`
+}
+
 func showConfig(conf *model.Config) {
-	table := tablewriter.NewWriter(os.Stdout)
+	printResult(conf, func(w io.Writer, wide bool) {
+		renderConfig(w, conf)
+	})
+}
+
+func renderConfig(w io.Writer, conf *model.Config) {
+	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"PROPERTY", "VALUE"})
 	table.Append([]string{model.CompatibilityProp, conf.Compatibility})
 	table.Render()
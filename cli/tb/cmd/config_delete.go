@@ -58,13 +58,13 @@ The deleted property will be back to the default.`, propertyDescriptions()),
 			if _, err := client.DeleteConfig(subject); err != nil {
 				exitWithError(err)
 			}
-			fmt.Printf("Config of the subject `%s` is deleted successfully.\n", subject)
+			printMessage(&configDeletion{Subject: subject}, "Config of the subject `%s` is deleted successfully.\n", subject)
 		} else if len(args) == 1 { // config delete $property
 			property := args[0]
 			if _, err := client.DeleteProperty(subject, property); err != nil {
 				exitWithError(err)
 			}
-			fmt.Printf("Config `%s` of the subject `%s` is deleted successfully. \n", property, subject)
+			printMessage(&configDeletion{Subject: subject, Property: property}, "Config `%s` of the subject `%s` is deleted successfully. \n", property, subject)
 		} else {
 			exitWithError(fmt.Errorf("too much arguments"))
 		}
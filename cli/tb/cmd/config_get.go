@@ -24,6 +24,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -64,8 +65,10 @@ Available properties are following for now,
 			if err != nil {
 				exitWithError(err)
 			}
-			fmt.Println(value)
-			fmt.Println()
+			printResult(&model.Property{Subject: subject, Property: property, Value: value}, func(w io.Writer, wide bool) {
+				fmt.Fprintln(w, value)
+				fmt.Fprintln(w)
+			})
 		} else {
 			exitWithError(fmt.Errorf("too much arguments"))
 		}
@@ -25,6 +25,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -71,14 +72,16 @@ Available properties are following for now,
 			if _, err := client.SetConfig(subject, *conf); err != nil {
 				exitWithError(err)
 			} else {
-				fmt.Printf("Config is set to the subject `%s` as follows,\n", subject)
-				showConfig(conf)
+				printResult(conf, func(w io.Writer, wide bool) {
+					fmt.Fprintf(w, "Config is set to the subject `%s` as follows,\n", subject)
+					renderConfig(w, conf)
+				})
 			}
 		} else if len(args) == 2 { // set the value to specific property
 			if _, err := client.SetProperty(subject, args[0], args[1]); err != nil {
 				exitWithError(err)
 			} else {
-				fmt.Printf("Property `%s` is set to the subject `%s` with value `%s`\n", args[0], subject, args[1])
+				printMessage(&model.Property{Subject: subject, Property: args[0], Value: args[1]}, "Property `%s` is set to the subject `%s` with value `%s`\n", args[0], subject, args[1])
 			}
 		} else {
 			exitWithUsage(cmd, fmt.Errorf("too much arguments"))
@@ -0,0 +1,120 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/archive"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export a whole registry to an archive",
+	Long: `Export subjects, their descriptions and configs and every schema with its ID and semver to a gzipped tar archive.
+Only subjects matching --subject, a glob pattern such as 'user-*', are exported if it is given. It can be specified multiple times.
+The archive is deterministic, i.e. exporting the same registry always produces the same file, so it can be kept under version control.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("out", cmd.Flags().Lookup("out"))
+		viper.BindPFlag("concurrency", cmd.Flags().Lookup("concurrency"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		out := viper.GetString("out")
+		if out == "" {
+			exitWithUsage(cmd, fmt.Errorf("path to an archive is not specified"))
+		}
+		patterns, err := cmd.Flags().GetStringSlice("subject")
+		if err != nil {
+			exitWithUsage(cmd, err)
+		}
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				exitWithUsage(cmd, fmt.Errorf("invalid subject pattern `%s`: %v", pattern, err))
+			}
+		}
+
+		exporter := archive.NewExporter(newClient())
+		exporter.Subjects = patterns
+		exporter.Concurrency = viper.GetInt("concurrency")
+		registry, err := exporter.Export(context.Background())
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := writeArchive(out, registry); err != nil {
+			exitWithError(err)
+		}
+
+		exported := &exportedArchive{
+			Path:          out,
+			FormatVersion: archive.FormatVersion,
+			Subjects:      len(registry.Subjects),
+			Schemas:       registry.Schemas(),
+		}
+		printMessage(exported, "Registry is exported to %s with %d subjects and %d schemas\n", out, exported.Subjects, exported.Schemas)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("out", "", "path to an archive to write (required)")
+	exportCmd.Flags().StringSlice("subject", nil, "glob pattern of subjects to export. It can be specified multiple times")
+	exportCmd.Flags().Int("concurrency", archive.DefaultConcurrency, "maximum number of concurrent requests")
+}
+
+// exportedArchive is the document of an archive written by tb export.
+type exportedArchive struct {
+	Path          string `json:"path"`
+	FormatVersion int    `json:"format_version"`
+	Subjects      int    `json:"subjects"`
+	Schemas       int    `json:"schemas"`
+}
+
+// writeArchive writes the registry to a temporary file and renames it to path,
+// so that an existing archive is not broken by a failure on the way.
+func writeArchive(path string, registry *archive.Registry) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".typebook-export-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := archive.Write(tmp, registry); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
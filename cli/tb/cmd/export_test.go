@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/archive"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestExport(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	gock.New(hostForTest).
+		Get("/subjects$").
+		Reply(200).
+		JSON([]string{testSubject, "page-views"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "$").
+		Reply(200).
+		JSON(map[string]string{"name": testSubject, "description": testDescription})
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "FULL"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions$").
+		Reply(200).
+		JSON([]string{"v1.0.0"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(testSchema)
+
+	out := filepath.Join(dir, "registry.tar.gz")
+	args := []string{"export", "--out", out, "--subject", "test-*", "--concurrency", "2"}
+	exportCmd.Root().SetArgs(args)
+
+	if err := exportCmd.Execute(); err != nil {
+		t.Errorf("export command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("an archive is expected to be written in %s: %v", out, err)
+	}
+	defer f.Close()
+	registry, err := archive.Read(f)
+	if err != nil {
+		t.Fatalf("the exported archive is expected to be readable: %v", err)
+	}
+	if len(registry.Subjects) != 1 || registry.Subjects[0].Description != testDescription ||
+		registry.Subjects[0].Config.Compatibility != "FULL" || len(registry.Subjects[0].Schemas) != 1 {
+		t.Errorf("only `%s` is expected to be exported with its config and schema but got %+v", testSubject, registry.Subjects)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the subject, config and schema are expected to be retrieved")
+	}
+}
@@ -0,0 +1,165 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/archive"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import $archive",
+	Short: "import a registry from an archive",
+	Long: `Import subjects, their descriptions and configs and schemas from an archive written by tb export.
+Schemas are registered in order of their versions and those already present with the same versions are skipped,
+so an interrupted import can be resumed by running it again and importing the same archive twice changes nothing.
+Configs are set after schemas are registered.
+If a schema is assigned a different version from the archive, it is reported as a drift and tb exits with status 5.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		registry, err := archive.Read(f)
+		f.Close()
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to read %s: %v", args[0], err))
+		}
+
+		importRegistry(newClient(), registry)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+}
+
+// importedRegistry is the document of a registry imported by tb import and tb migrate.
+type importedRegistry struct {
+	Subjects []importedSubject `json:"subjects"`
+	Drifts   int               `json:"drifts"`
+}
+
+type importedSubject struct {
+	Name               string           `json:"name"`
+	Created            bool             `json:"created"`
+	DescriptionUpdated bool             `json:"description_updated"`
+	ConfigUpdated      bool             `json:"config_updated"`
+	Schemas            []importedSchema `json:"schemas"`
+}
+
+// importedSchema is an imported schema, whose status is REGISTERED, PRESENT or DRIFTED.
+type importedSchema struct {
+	SourceId      int64  `json:"source_id"`
+	Id            int64  `json:"id"`
+	Version       string `json:"version"`
+	ActualVersion string `json:"actual_version"`
+	Status        string `json:"status"`
+}
+
+func importedRegistryOf(report *archive.ImportReport) *importedRegistry {
+	imported := &importedRegistry{Subjects: make([]importedSubject, 0, len(report.Subjects))}
+	for _, s := range report.Subjects {
+		subject := importedSubject{
+			Name:               s.Name,
+			Created:            s.Created,
+			DescriptionUpdated: s.DescriptionUpdated,
+			ConfigUpdated:      s.ConfigUpdated,
+			Schemas:            make([]importedSchema, 0, len(s.Schemas)),
+		}
+		for _, schema := range s.Schemas {
+			status := "PRESENT"
+			switch {
+			case schema.IsDrifted():
+				status = "DRIFTED"
+				imported.Drifts++
+			case schema.Registered:
+				status = "REGISTERED"
+			}
+			subject.Schemas = append(subject.Schemas, importedSchema{
+				SourceId:      schema.SourceId,
+				Id:            schema.Id,
+				Version:       schema.Version.String(),
+				ActualVersion: schema.ActualVersion.String(),
+				Status:        status,
+			})
+		}
+		imported.Subjects = append(imported.Subjects, subject)
+	}
+	return imported
+}
+
+// importRegistry imports the registry into the destination and shows what is done.
+// It exits with exitCodeIncompatible if any schema drifts, or with the status for the error if the import fails.
+func importRegistry(destination archive.Destination, registry *archive.Registry) {
+	report, err := archive.NewImporter(destination).Import(context.Background(), registry)
+	imported := importedRegistryOf(report)
+	printResult(imported, func(w io.Writer, wide bool) {
+		for _, s := range imported.Subjects {
+			switch {
+			case s.Created:
+				fmt.Fprintf(w, "Subject `%s` is created\n", s.Name)
+			case s.DescriptionUpdated:
+				fmt.Fprintf(w, "Description of the subject `%s` is updated\n", s.Name)
+			}
+			if s.ConfigUpdated {
+				fmt.Fprintf(w, "Config of the subject `%s` is updated\n", s.Name)
+			}
+		}
+		renderImportedSchemas(w, wide, imported.Subjects)
+	})
+	if err != nil {
+		exitWithError(fmt.Errorf("import stopped, which can be resumed by running it again: %v", err))
+	}
+	if imported.Drifts > 0 {
+		exitWithCode(fmt.Errorf("%d schemas are assigned different versions from the source", imported.Drifts), exitCodeIncompatible)
+	}
+}
+
+func renderImportedSchemas(w io.Writer, wide bool, subjects []importedSubject) {
+	header := []string{"SUBJECT", "VERSION", "ACTUAL VERSION", "STATUS"}
+	if wide {
+		header = append(header, "SOURCE ID", "ID")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	for _, s := range subjects {
+		for _, schema := range s.Schemas {
+			row := []string{s.Name, schema.Version, schema.ActualVersion, schema.Status}
+			if wide {
+				row = append(row, fmt.Sprint(schema.SourceId), fmt.Sprint(schema.Id))
+			}
+			table.Append(row)
+		}
+	}
+	table.Render()
+}
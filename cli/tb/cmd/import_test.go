@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/archive"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestImport(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-import-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registry.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := &archive.Registry{Subjects: []archive.Subject{{
+		Subject: model.Subject{Name: testSubject, Description: testDescription},
+		Config:  model.Config{Compatibility: "FULL"},
+		Schemas: []model.Schema{{Id: 7, Subject: testSubject, Version: model.SemVer{Major: 1}, Definition: schemaDef}},
+	}}}
+	if err := archive.Write(f, registry); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "$").
+		Reply(404).
+		JSON(model.ServerError{ErrorCode: 404, Message: "Subject Not Found"})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "$").
+		BodyString(testDescription).
+		Reply(201).
+		BodyString("0")
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookupAll$").
+		Reply(200).
+		JSON([]model.Schema{})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/versions$").
+		Reply(201).
+		JSON(model.SchemaId{Id: 1})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookup$").
+		Reply(200).
+		JSON(testSchema)
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "NONE"})
+	gock.New(hostForTest).
+		Put("/config/" + testSubject + "$").
+		JSON(model.Config{Compatibility: "FULL"}).
+		Reply(200).
+		BodyString("1")
+
+	args := []string{"import", path, "--output", "json"}
+	importCmd.Root().SetArgs(args)
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	out, _ := captureOutput(func() {
+		if err := importCmd.Execute(); err != nil {
+			t.Errorf("import command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	var imported importedRegistry
+	if err := json.Unmarshal([]byte(out), &imported); err != nil {
+		t.Fatalf("import command is expected to print JSON but got %s", out)
+	}
+	if len(imported.Subjects) != 1 || !imported.Subjects[0].Created || !imported.Subjects[0].ConfigUpdated || imported.Drifts != 0 {
+		t.Errorf("the subject is expected to be created with its config but got %+v", imported)
+	}
+	expect := importedSchema{SourceId: 7, Id: 1, Version: "v1.0.0", ActualVersion: "v1.0.0", Status: "REGISTERED"}
+	if schemas := imported.Subjects[0].Schemas; len(schemas) != 1 || schemas[0] != expect {
+		t.Errorf("the schema is expected to be imported as %+v but got %+v", expect, schemas)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the subject, schema and config are expected to be created")
+	}
+}
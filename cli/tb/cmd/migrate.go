@@ -0,0 +1,80 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/archive"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "migrate subjects from a registry to another",
+	Long: `Migrate subjects, their descriptions and configs and schemas from the typebook server at --from to the one at --to,
+in the same way as tb export followed by tb import.
+Only subjects matching --subject, a glob pattern such as 'user-*', are migrated if it is given. It can be specified multiple times.
+Schemas already present in the destination with the same versions are skipped, so a migration can be resumed by running it again.
+If a schema is assigned a different version from the source, it is reported as a drift and tb exits with status 5.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		if from == "" || to == "" {
+			exitWithUsage(cmd, fmt.Errorf("both --from and --to must be specified"))
+		}
+		patterns, err := cmd.Flags().GetStringSlice("subject")
+		if err != nil {
+			exitWithUsage(cmd, err)
+		}
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				exitWithUsage(cmd, fmt.Errorf("invalid subject pattern `%s`: %v", pattern, err))
+			}
+		}
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+		exporter := archive.NewExporter(newClientFor(from))
+		exporter.Subjects = patterns
+		exporter.Concurrency = concurrency
+		registry, err := exporter.Export(context.Background())
+		if err != nil {
+			exitWithError(err)
+		}
+
+		importRegistry(newClientFor(to), registry)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().String("from", "", "URL of the typebook server to migrate from (required)")
+	migrateCmd.Flags().String("to", "", "URL of the typebook server to migrate to (required)")
+	migrateCmd.Flags().StringSlice("subject", nil, "glob pattern of subjects to migrate. It can be specified multiple times")
+	migrateCmd.Flags().Int("concurrency", archive.DefaultConcurrency, "maximum number of concurrent requests to export")
+}
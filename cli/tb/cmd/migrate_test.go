@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestMigrateDrift(t *testing.T) {
+	defer gock.Off()
+
+	from, to := "from.example:8888", "to.example:8888"
+	source := model.Schema{Id: 9, Subject: testSubject, Version: model.SemVer{Major: 2}, Definition: schemaDef}
+
+	gock.New("http://" + from).
+		Get("/subjects$").
+		Reply(200).
+		JSON([]string{testSubject})
+	gock.New("http://" + from).
+		Get("/subjects/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Subject{Name: testSubject})
+	gock.New("http://" + from).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "NONE"})
+	gock.New("http://" + from).
+		Get("/subjects/" + testSubject + "/versions$").
+		Reply(200).
+		JSON([]string{"v2.0.0"})
+	gock.New("http://" + from).
+		Get("/subjects/" + testSubject + "/versions/v2.0.0$").
+		Reply(200).
+		JSON(source)
+
+	// the subject already exists in the destination, where the schema is registered with v1.0.0 and is not registered again
+	gock.New("http://" + to).
+		Get("/subjects/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Subject{Name: testSubject})
+	gock.New("http://" + to).
+		Post("/subjects/" + testSubject + "/schema/lookupAll$").
+		Reply(200).
+		JSON([]model.Schema{testSchema})
+	gock.New("http://" + to).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "NONE"})
+
+	args := []string{"migrate", "--from", from, "--to", to}
+	migrateCmd.Root().SetArgs(args)
+
+	out, _ := captureOutput(func() {
+		expectExit(t, exitCodeIncompatible, func() {
+			migrateCmd.Execute()
+		})
+	})
+	if !gock.IsDone() {
+		t.Errorf("the schema is expected to be migrated, but %d requests are not issued", len(gock.Pending()))
+	}
+	if !strings.Contains(out, "DRIFTED") {
+		t.Errorf("the schema is expected to be reported as a drift but got\n%s", out)
+	}
+}
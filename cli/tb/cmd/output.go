@@ -0,0 +1,266 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Output formats given by --output.
+const (
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// Exit codes of tb, which are also written in error documents.
+const (
+	exitCodeError        = 1 // unexpected errors
+	exitCodeUsage        = 2 // invalid arguments or flags
+	exitCodeNotFound     = 3 // a subject, a schema or a config does not exist
+	exitCodeRejected     = 4 // a typebook server rejected the request, e.g. an invalid or duplicated one
+	exitCodeIncompatible = 5 // a schema is incompatible or violates the compatibility restriction
+	exitCodeUnauthorized = 6 // authentication or authorization failed
+	exitCodeUnavailable  = 7 // a typebook server cannot be reached or failed
+)
+
+var exitKinds = map[int]string{
+	exitCodeError:        "error",
+	exitCodeUsage:        "usage",
+	exitCodeNotFound:     "not_found",
+	exitCodeRejected:     "rejected",
+	exitCodeIncompatible: "incompatible",
+	exitCodeUnauthorized: "unauthorized",
+	exitCodeUnavailable:  "unavailable",
+}
+
+// Destinations of outputs and the way to exit, which are replaced in tests.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+	exit             = os.Exit
+)
+
+// errorDocument is written to stderr on failure unless the output format is table or wide.
+type errorDocument struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Kind     string `json:"kind"`
+	Status   int    `json:"status,omitempty"` // the error code returned by a typebook server
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func initOutputFlags() {
+	if RootCmd.PersistentFlags().Lookup("output") == nil {
+		RootCmd.PersistentFlags().StringP("output", "o", outputTable, "output format (table | wide | json | yaml)")
+		viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
+	}
+	if RootCmd.PersistentFlags().Lookup("template") == nil {
+		RootCmd.PersistentFlags().String("template", "", "Go template applied to the JSON document of the result, which takes precedence over --output")
+		viper.BindPFlag("template", RootCmd.PersistentFlags().Lookup("template"))
+	}
+}
+
+// checkOutputFlags fails early if --output is invalid.
+func checkOutputFlags() {
+	outputFormat()
+}
+
+// outputFormat returns the format given by --output. It exits if the format is unknown.
+func outputFormat() string {
+	format := viper.GetString("output")
+	switch format {
+	case outputTable, outputWide, outputJSON, outputYAML:
+		return format
+	}
+	RootCmd.PersistentFlags().Set("output", outputTable) // not to fail again while reporting the error
+	exitWithCode(fmt.Errorf("invalid output format `%s`. Valid formats are table, wide, json or yaml", format), exitCodeUsage)
+	return ""
+}
+
+// isStructuredOutput returns whether the result is written as a document rather than a table.
+func isStructuredOutput() bool {
+	format := outputFormat()
+	return viper.GetString("template") != "" || format == outputJSON || format == outputYAML
+}
+
+// printResult writes v as a document in the format given by --output or --template.
+// For table and wide formats, table is called instead to render v in a human readable way.
+func printResult(v interface{}, table func(w io.Writer, wide bool)) {
+	if tmpl := viper.GetString("template"); tmpl != "" {
+		if err := executeTemplate(stdout, tmpl, v); err != nil {
+			exitWithCode(err, exitCodeUsage)
+		}
+		return
+	}
+
+	switch format := outputFormat(); format {
+	case outputJSON, outputYAML:
+		if err := writeDocument(stdout, format, v); err != nil {
+			exitWithError(err)
+		}
+	default:
+		table(stdout, format == outputWide)
+	}
+}
+
+// printMessage is printResult whose table is a formatted message.
+func printMessage(v interface{}, format string, a ...interface{}) {
+	printResult(v, func(w io.Writer, wide bool) {
+		fmt.Fprintf(w, format, a...)
+	})
+}
+
+// writeDocument writes v in JSON or YAML.
+// YAML is converted from JSON so that both have the same keys and values.
+func writeDocument(w io.Writer, format string, v interface{}) error {
+	if format == outputJSON {
+		js, err := prettyJSON(v, 2)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(js))
+		return err
+	}
+
+	generic, err := genericOf(v)
+	if err != nil {
+		return err
+	}
+	y, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(y)
+	return err
+}
+
+// executeTemplate applies the template to the JSON document of v, so fields are referred by their JSON keys, e.g. {{.id}}.
+func executeTemplate(w io.Writer, text string, v interface{}) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	generic, err := genericOf(v)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(w, generic); err != nil {
+		return fmt.Errorf("failed to execute template: %v", err)
+	}
+	return nil
+}
+
+// genericOf converts v into maps and slices through JSON. Numbers are kept as json.Number not to lose precision.
+func genericOf(v interface{}) (interface{}, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// exitCodeOf classifies an error into an exit code.
+func exitCodeOf(err error) int {
+	modelErr, ok := err.(*model.Error)
+	if !ok {
+		return exitCodeError
+	}
+	if modelErr.ServerError != nil {
+		switch code := modelErr.ServerError.ErrorCode; {
+		case code == 404:
+			return exitCodeNotFound
+		case code == 401 || code == 403:
+			return exitCodeUnauthorized
+		case code >= 500:
+			return exitCodeUnavailable
+		case code >= 400:
+			return exitCodeRejected
+		}
+	}
+	for _, e := range modelErr.ClientError {
+		if _, ok := e.(*url.Error); ok {
+			return exitCodeUnavailable
+		}
+		if _, ok := e.(net.Error); ok {
+			return exitCodeUnavailable
+		}
+	}
+	return exitCodeError
+}
+
+func exitWithError(err error) {
+	exitWithCode(err, exitCodeOf(err))
+}
+
+func exitWithUsage(cmd *cobra.Command, err error) {
+	if !isStructuredOutput() {
+		fmt.Fprintln(stderr, err.Error())
+		cmd.SetOutput(stderr)
+		cmd.Usage()
+	}
+	exitWithCode(err, exitCodeUsage)
+}
+
+// exitWithCode reports the error and exits with the code.
+// The error is written as a document in JSON, or YAML if the output format is yaml, unless the format is table or wide.
+func exitWithCode(err error, code int) {
+	if isStructuredOutput() {
+		body := errorBody{Kind: exitKinds[code], Message: err.Error(), ExitCode: code}
+		if modelErr, ok := err.(*model.Error); ok && modelErr.ServerError != nil {
+			body.Status = modelErr.ServerError.ErrorCode
+		}
+		format := outputJSON
+		if viper.GetString("output") == outputYAML {
+			format = outputYAML
+		}
+		writeDocument(stderr, format, errorDocument{Error: body})
+	} else {
+		log.New(stderr, "", log.LstdFlags).Println(err.Error())
+	}
+	exit(code)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestPrintResult(t *testing.T) {
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+	defer RootCmd.PersistentFlags().Set("template", "")
+
+	subject := &model.Subject{Name: testSubject, Description: testDescription}
+	table := func(w io.Writer, wide bool) {
+		fmt.Fprintf(w, "table wide=%v", wide)
+	}
+
+	cases := []struct {
+		output   string
+		template string
+		expect   string
+	}{
+		{outputTable, "", "table wide=false"},
+		{outputWide, "", "table wide=true"},
+		{outputJSON, "", "{\n  \"name\": \"test-subject\",\n  \"description\": \"This is test\"\n}\n"},
+		{outputYAML, "", "description: This is test\nname: test-subject\n"},
+		{outputJSON, "{{.name}}: {{.description}}", "test-subject: This is test"},
+	}
+	for _, c := range cases {
+		RootCmd.PersistentFlags().Set("output", c.output)
+		RootCmd.PersistentFlags().Set("template", c.template)
+		out, _ := captureOutput(func() {
+			printResult(subject, table)
+		})
+		if out != c.expect {
+			t.Errorf("printResult with --output %s --template %q prints %q, wants %q", c.output, c.template, out, c.expect)
+		}
+	}
+}
+
+func TestErrorDocument(t *testing.T) {
+	defer gock.Off()
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject).
+		Reply(404).
+		JSON(model.ServerError{ErrorCode: 404, Message: "Subject Not Found"})
+
+	args := []string{"subject", "get", testSubject, "--output", "json"}
+	subjectGetCmd.Root().SetArgs(args)
+
+	out, errOut := captureOutput(func() {
+		expectExit(t, exitCodeNotFound, func() {
+			subjectGetCmd.Execute()
+		})
+	})
+	if out != "" {
+		t.Errorf("nothing is expected to be printed to stdout on failure but got %s", out)
+	}
+	doc := new(errorDocument)
+	if err := json.Unmarshal([]byte(errOut), doc); err != nil {
+		t.Fatalf("an error document is expected to be printed to stderr but got %s", errOut)
+	}
+	expect := errorBody{Kind: "not_found", Status: 404, Message: "Subject Not Found", ExitCode: exitCodeNotFound}
+	if doc.Error != expect {
+		t.Errorf("error document = %+v, wants %+v", doc.Error, expect)
+	}
+}
+
+func TestInvalidOutput(t *testing.T) {
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	args := []string{"subject", "get", testSubject, "--output", "xml"}
+	subjectGetCmd.Root().SetArgs(args)
+
+	// no request should be issued
+	_, errOut := captureOutput(func() {
+		expectExit(t, exitCodeUsage, func() {
+			subjectGetCmd.Execute()
+		})
+	})
+	if !strings.Contains(errOut, "invalid output format `xml`") {
+		t.Errorf("invalid output format is expected to be reported but got %s", errOut)
+	}
+}
+
+func TestExitCodeOf(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect int
+	}{
+		{fmt.Errorf("unexpected"), exitCodeError},
+		{model.NewError(&model.ServerError{ErrorCode: 404}, nil), exitCodeNotFound},
+		{model.NewError(&model.ServerError{ErrorCode: 409}, nil), exitCodeRejected},
+		{model.NewError(&model.ServerError{ErrorCode: 422}, nil), exitCodeRejected},
+		{model.NewError(&model.ServerError{ErrorCode: 401}, nil), exitCodeUnauthorized},
+		{model.NewError(&model.ServerError{ErrorCode: 503}, nil), exitCodeUnavailable},
+		{model.NewError(nil, []error{&url.Error{Op: "Get", URL: "http://foo.bar", Err: fmt.Errorf("refused")}}), exitCodeUnavailable},
+		{model.NewError(nil, []error{fmt.Errorf("invalid schema")}), exitCodeError},
+	}
+	for _, c := range cases {
+		if actual := exitCodeOf(c.err); actual != c.expect {
+			t.Errorf("exitCodeOf(%v) = %d, wants %d", c.err, actual, c.expect)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/manifest"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "show changes to bring a registry into the state of a manifest",
+	Long: `Compare a manifest, which lists subjects with their descriptions, configs and schema files in YAML or JSON,
+with a typebook server and show the changes that tb apply would make.
+Subjects not listed in the manifest are left as they are, and so are descriptions and configs not given.
+The version of a schema to register is predicted in the same way as tb schema create --dry-run.
+It exits with status 5 if a schema would be rejected by the compatibility of its subject.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		plan := planManifest(cmd)
+		showPlan(plan.Changes, "Plan")
+		if !plan.IsAllowed() {
+			exitWithCode(fmt.Errorf("the plan has schemas violating the compatibility of their subjects"), exitCodeIncompatible)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringP("file", "f", "", "path to a manifest (required)")
+}
+
+// planManifest loads the manifest given by --file and plans changes against the server.
+func planManifest(cmd *cobra.Command) *manifest.Plan {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		exitWithUsage(cmd, fmt.Errorf("manifest is not specified"))
+	}
+	m, err := manifest.Load(path)
+	if err != nil {
+		exitWithUsage(cmd, err)
+	}
+	plan, err := m.Plan(context.Background(), newClient())
+	if err != nil {
+		exitWithError(err)
+	}
+	return plan
+}
+
+// showPlan shows changes as a change set, in which `+` is an addition, `~` is a modification
+// and `!` is a schema to be rejected. The summary is prefixed with title.
+func showPlan(changes []manifest.Change, title string) {
+	printResult(&manifest.Plan{Changes: changes}, func(w io.Writer, wide bool) {
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "No changes. The registry is up to date.")
+			return
+		}
+		for _, c := range changes {
+			renderChange(w, c)
+		}
+		fmt.Fprintf(w, "%s: %d changes\n", title, len(changes))
+	})
+}
+
+func renderChange(w io.Writer, c manifest.Change) {
+	switch c.Action {
+	case manifest.CreateSubject:
+		fmt.Fprintf(w, "+ create subject `%s` with description %q\n", c.Subject, c.To)
+	case manifest.UpdateDescription:
+		fmt.Fprintf(w, "~ update description of `%s`: %q -> %q\n", c.Subject, c.From, c.To)
+	case manifest.SetCompatibility:
+		fmt.Fprintf(w, "~ set compatibility of `%s`: %s -> %s\n", c.Subject, c.From, c.To)
+	case manifest.RegisterSchema:
+		version := "a version decided by preceding schemas"
+		if c.Version != "" {
+			version = c.Version
+		}
+		if !c.IsAllowed {
+			fmt.Fprintf(w, "! register %s under `%s` as %s, which violates its compatibility\n", c.File, c.Subject, version)
+			return
+		}
+		fmt.Fprintf(w, "+ register %s under `%s` as %s\n", c.File, c.Subject, version)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/manifest"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// writeManifest writes a manifest of the test subject with a schema in a new temporary directory and returns its path.
+func writeManifest(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "typebook-manifest-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "person.avsc"), []byte(schemaDef), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := "subjects:\n  - name: " + testSubject + "\n    description: " + testDescription + "\n    config:\n      compatibility: FULL\n    schemas: [person.avsc]\n"
+	path := filepath.Join(dir, "registry.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlan(t *testing.T) {
+	defer gock.Off()
+
+	path := writeManifest(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Subject{Name: testSubject, Description: "old"})
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Config{Compatibility: "NONE"})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookup$").
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"plan", "-f", path, "--output", "json"}
+	planCmd.Root().SetArgs(args)
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	out, _ := captureOutput(func() {
+		if err := planCmd.Execute(); err != nil {
+			t.Errorf("plan command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	var plan manifest.Plan
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("plan command is expected to print JSON but got %s", out)
+	}
+	expect := []manifest.Change{
+		{Action: manifest.UpdateDescription, Subject: testSubject, From: "old", To: testDescription, IsAllowed: true},
+		{Action: manifest.SetCompatibility, Subject: testSubject, From: "NONE", To: "FULL", IsAllowed: true},
+	}
+	if !reflect.DeepEqual(plan.Changes, expect) {
+		t.Errorf("plan = %+v, wants %+v", plan.Changes, expect)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the subject, config and schema are expected to be compared")
+	}
+}
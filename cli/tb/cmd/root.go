@@ -24,11 +24,11 @@ package cmd
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"log"
-	"os"
+	"net/http"
+	"path/filepath"
 	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -48,13 +48,15 @@ var RootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		// cobra has already shown the error with the usage
+		exit(exitCodeUsage)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initFlags)
+	// flags should be defined before they are parsed, while the config is read after that
+	initFlags()
+	cobra.OnInitialize(initConfig, checkOutputFlags)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -62,8 +64,7 @@ func initConfig() {
 
 	home, err := homedir.Dir()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	// Search config in home directory with name ".typebook" (without extension).
@@ -71,7 +72,8 @@ func initConfig() {
 	viper.SetConfigName(".typebook")
 
 	viper.SetEnvPrefix("TYPEBOOK")
-	viper.AutomaticEnv() // read in environment variables that match
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_")) // e.g. ca-cert is read from TYPEBOOK_CA_CERT
+	viper.AutomaticEnv()                                   // read in environment variables that match
 
 	// If a config file is found, read it in.
 	viper.ReadInConfig()
@@ -79,9 +81,46 @@ func initConfig() {
 
 func initFlags() {
 	if RootCmd.PersistentFlags().Lookup("url") == nil {
-		RootCmd.PersistentFlags().String("url", "127.0.0.1:8888", "URL of a typebook server")
+		RootCmd.PersistentFlags().String("url", "127.0.0.1:8888", "URL of a typebook server (e.g. host:port or https://host:port/path). Multiple URLs can be separated by commas")
 		viper.BindPFlag("url", RootCmd.PersistentFlags().Lookup("url"))
 	}
+	if RootCmd.PersistentFlags().Lookup("ca-cert") == nil {
+		RootCmd.PersistentFlags().String("ca-cert", "", "path to PEM encoded CA certificates to verify a typebook server")
+		viper.BindPFlag("ca-cert", RootCmd.PersistentFlags().Lookup("ca-cert"))
+	}
+	if RootCmd.PersistentFlags().Lookup("client-cert") == nil {
+		RootCmd.PersistentFlags().String("client-cert", "", "path to PEM encoded client certificate for mutual TLS")
+		viper.BindPFlag("client-cert", RootCmd.PersistentFlags().Lookup("client-cert"))
+	}
+	if RootCmd.PersistentFlags().Lookup("client-key") == nil {
+		RootCmd.PersistentFlags().String("client-key", "", "path to PEM encoded private key of the client certificate")
+		viper.BindPFlag("client-key", RootCmd.PersistentFlags().Lookup("client-key"))
+	}
+	if RootCmd.PersistentFlags().Lookup("server-name") == nil {
+		RootCmd.PersistentFlags().String("server-name", "", "server name to verify the certificate of a typebook server")
+		viper.BindPFlag("server-name", RootCmd.PersistentFlags().Lookup("server-name"))
+	}
+	if RootCmd.PersistentFlags().Lookup("user") == nil {
+		RootCmd.PersistentFlags().String("user", "", "user name for basic authentication")
+		viper.BindPFlag("user", RootCmd.PersistentFlags().Lookup("user"))
+	}
+	if RootCmd.PersistentFlags().Lookup("password") == nil {
+		RootCmd.PersistentFlags().String("password", "", "password for basic authentication")
+		viper.BindPFlag("password", RootCmd.PersistentFlags().Lookup("password"))
+	}
+	if RootCmd.PersistentFlags().Lookup("token") == nil {
+		RootCmd.PersistentFlags().String("token", "", "bearer token for authentication. It takes precedence over basic authentication")
+		viper.BindPFlag("token", RootCmd.PersistentFlags().Lookup("token"))
+	}
+	if RootCmd.PersistentFlags().Lookup("cache-dir") == nil {
+		RootCmd.PersistentFlags().String("cache-dir", "", "directory to cache schemas retrieved from a typebook server. Schemas are not cached if empty unless --offline is set")
+		viper.BindPFlag("cache-dir", RootCmd.PersistentFlags().Lookup("cache-dir"))
+	}
+	if RootCmd.PersistentFlags().Lookup("offline") == nil {
+		RootCmd.PersistentFlags().Bool("offline", false, "serve schemas only from the cache directory without connecting to a typebook server")
+		viper.BindPFlag("offline", RootCmd.PersistentFlags().Lookup("offline"))
+	}
+	initOutputFlags()
 }
 
 // string begin with `@` is considered as a path
@@ -99,20 +138,67 @@ func valueOrFromPath(value string) ([]byte, error) {
 	}
 }
 
-func exitWithError(err error) {
-	log.Fatalln(err.Error())
+func newClient() *typebook.Client {
+	return newClientFor(viper.GetString("url"))
+}
+
+// newClientFor creates a client of the typebook server at url with the options given by flags and configs.
+func newClientFor(url string) *typebook.Client {
+	opts := clientOptions()
+	offline := viper.GetBool("offline")
+	if viper.GetString("cache-dir") != "" || offline {
+		opts = append(opts, typebook.WithDiskCache(newDiskCache()))
+	}
+	if offline {
+		opts = append(opts, typebook.WithOffline())
+	}
+	return typebook.NewClient(url, opts...)
 }
 
-func exitWithUsage(cmd *cobra.Command, err error) {
-	fmt.Println(err.Error())
-	cmd.Usage()
-	os.Exit(1)
+// clientOptions returns the options given by flags and configs except for the cache and offline mode.
+func clientOptions() []typebook.Option {
+	opts := []typebook.Option{
+		typebook.WithHTTPClient(&http.Client{Timeout: 5 * time.Second}),
+		typebook.WithRetryPolicy(typebook.DefaultRetryPolicy),
+	}
+
+	tlsOpts := typebook.TLSOptions{
+		CACertFile:     viper.GetString("ca-cert"),
+		ClientCertFile: viper.GetString("client-cert"),
+		ClientKeyFile:  viper.GetString("client-key"),
+		ServerName:     viper.GetString("server-name"),
+	}
+	if tlsOpts != (typebook.TLSOptions{}) {
+		tlsConfig, err := typebook.LoadTLSConfig(tlsOpts)
+		if err != nil {
+			exitWithError(err)
+		}
+		opts = append(opts, typebook.WithTLSConfig(tlsConfig))
+	}
+
+	if token := viper.GetString("token"); token != "" {
+		opts = append(opts, typebook.WithAuth(typebook.BearerToken(token)))
+	} else if user := viper.GetString("user"); user != "" {
+		opts = append(opts, typebook.WithAuth(typebook.BasicAuth(user, viper.GetString("password"))))
+	}
+	return opts
 }
 
-func newClient() *typebook.Client {
-	client := typebook.NewClient(viper.GetString("url"))
-	client.SuperAgent.Timeout(5000000000) // 5 sec
-	return client
+// newDiskCache opens the directory specified by --cache-dir, or `~/.typebook-cache` by default.
+func newDiskCache() *typebook.DiskCache {
+	dir := viper.GetString("cache-dir")
+	if dir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			exitWithError(err)
+		}
+		dir = filepath.Join(home, ".typebook-cache")
+	}
+	cache, err := typebook.NewDiskCache(dir)
+	if err != nil {
+		exitWithError(err)
+	}
+	return cache
 }
 
 func prettyJSON(v interface{}, indent int) ([]byte, error) {
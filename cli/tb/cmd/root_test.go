@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
+	"testing"
 
 	"gopkg.in/h2non/gock.v1"
-
-	typebook "github.com/cyberagent/typebook/client/go"
 )
 
 const (
@@ -14,8 +14,34 @@ const (
 	testDescription = "This is test"
 )
 
+// exitCode is thrown as a panic instead of exiting in tests.
+type exitCode int
+
 func init() {
 	os.Setenv("TYPEBOOK_URL", hostForTest)
 	gock.DisableNetworking()
-	typebook.DisableTransportSwap = true // to avoid overwriting gock's intercept transport with gorequest's superagent transport
+	exit = func(code int) {
+		panic(exitCode(code))
+	}
+}
+
+// expectExit runs f and reports an error unless it exits with the code.
+func expectExit(t *testing.T, code int, f func()) {
+	defer func() {
+		if r := recover(); r != exitCode(code) {
+			t.Errorf("exit code is expected to be %d, but got %v", code, r)
+		}
+	}()
+	f()
+}
+
+// captureOutput returns what f writes to stdout and stderr.
+func captureOutput(f func()) (string, string) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	stdout, stderr = out, errOut
+	defer func() {
+		stdout, stderr = os.Stdout, os.Stderr
+	}()
+	f()
+	return out.String(), errOut.String()
 }
@@ -24,13 +24,16 @@ package cmd
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	typebook "github.com/cyberagent/typebook/client/go"
+	"github.com/cyberagent/typebook/client/go/avro"
 	"github.com/cyberagent/typebook/client/go/model"
 )
 
@@ -49,22 +52,94 @@ func init() {
 	schemaCmd.PersistentFlags().String("subject", "", "name of subject")
 }
 
+// schemaMeta is the document of a schema without its definition.
+type schemaMeta struct {
+	Id      int64  `json:"id"`
+	Subject string `json:"subject"`
+	Version string `json:"version"`
+}
+
+func schemaMetaOf(schema model.Schema) schemaMeta {
+	return schemaMeta{Id: schema.Id, Subject: schema.Subject, Version: schema.Version.String()}
+}
+
+// showSchemaMeta shows the ID, subject and version of a schema.
+func showSchemaMeta(schema model.Schema) {
+	printResult(schemaMetaOf(schema), func(w io.Writer, wide bool) {
+		renderSchemaMetas(w, wide, schema)
+	})
+}
+
+// showSchemaMetas shows the IDs, subjects and versions of schemas.
 func showSchemaMetas(schemas ...model.Schema) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"ID", "SUBJECT", "VERSION"})
+	metas := make([]schemaMeta, 0, len(schemas))
 	for _, schema := range schemas {
-		table.Append([]string{strconv.FormatInt(schema.Id, 10), schema.Subject, schema.Version.String()})
+		metas = append(metas, schemaMetaOf(schema))
+	}
+	printResult(metas, func(w io.Writer, wide bool) {
+		renderSchemaMetas(w, wide, schemas...)
+	})
+}
+
+// renderSchemaMetas renders schemas in a table. The wide table also has the fingerprints of the schemas.
+func renderSchemaMetas(w io.Writer, wide bool, schemas ...model.Schema) {
+	table := tablewriter.NewWriter(w)
+	header := []string{"ID", "SUBJECT", "VERSION"}
+	if wide {
+		header = append(header, "FINGERPRINT")
+	}
+	table.SetHeader(header)
+	for _, schema := range schemas {
+		row := []string{strconv.FormatInt(schema.Id, 10), schema.Subject, schema.Version.String()}
+		if wide {
+			fingerprint := ""
+			if parsed, err := avro.Parse(schema.Definition); err == nil {
+				fingerprint = fmt.Sprintf("%016x", avro.Fingerprint(parsed))
+			}
+			row = append(row, fingerprint)
+		}
+		table.Append(row)
 	}
 	table.Render()
 }
 
 func showSchemaVersions(versions ...model.SemVer) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"VERSION"})
+	docs := make([]string, 0, len(versions))
 	for _, version := range versions {
-		table.Append([]string{version.String()})
+		docs = append(docs, version.String())
 	}
-	table.Render()
+	printResult(docs, func(w io.Writer, wide bool) {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"VERSION"})
+		for _, version := range docs {
+			table.Append([]string{version})
+		}
+		table.Render()
+	})
+}
+
+// getSchemaByVersion retrieves a schema under the subject with the version given as a command line flag.
+// The latest schema is retrieved if version is empty.
+func getSchemaByVersion(cmd *cobra.Command, client *typebook.Client, subject, version string) *model.Schema {
+	var (
+		schema *model.Schema
+		err    *model.Error
+	)
+	if version == "" {
+		schema, err = client.GetLatestSchema(subject)
+	} else if model.IsMajorVer(version) {
+		majorVer, _ := strconv.Atoi(version[1:])
+		schema, err = client.GetSchemaByMajorVersion(subject, majorVer)
+	} else if model.IsSemVer(version) {
+		semver, _ := model.NewSemVer(version)
+		schema, err = client.GetSchemaBySemVer(subject, *semver)
+	} else {
+		exitWithUsage(cmd, fmt.Errorf("invalid format version `%s`. Valid forms are major version (e.g. v1) or semantic version (e.g. v1.0.0)", version))
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	return schema
 }
 
 func getPrettySchemaDef(schema *model.Schema) (string, error) {
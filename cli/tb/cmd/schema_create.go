@@ -24,9 +24,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/model"
 )
 
 var schemaCreateCmd = &cobra.Command{
@@ -35,8 +40,13 @@ var schemaCreateCmd = &cobra.Command{
 	Long: `Create a new schema under the specified subject.
 Unique ID and semantic version are assigned to the schema taking compatibility with existing schemas into account.
 This command takes one argument that represents a path to a schema file or definition itself.
-A path should begin with @.`,
+A path should begin with @.
+If --dry-run is set, it only shows the version the schema would get without registering it,
+together with the compatibility against each existing schema that the version is decided from.`,
 	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		subject := viper.GetString("subject")
@@ -47,12 +57,17 @@ A path should begin with @.`,
 		client := newClient()
 		if content, err := valueOrFromPath(args[0]); err != nil {
 			exitWithError(err)
+		} else if viper.GetBool("dry-run") {
+			if prediction, err := client.PredictNextVersion(subject, string(content)); err != nil {
+				exitWithError(err)
+			} else {
+				showVersionPrediction(prediction)
+			}
 		} else {
 			if id, err := client.RegisterSchema(subject, string(content)); err != nil {
 				exitWithError(err)
 			} else {
-				fmt.Printf("Schema is registered successfully with ID `%d`", id.Id)
-				fmt.Println()
+				printMessage(id, "Schema is registered successfully with ID `%d`\n", id.Id)
 			}
 		}
 	},
@@ -60,4 +75,36 @@ A path should begin with @.`,
 
 func init() {
 	schemaCmd.AddCommand(schemaCreateCmd)
+
+	schemaCreateCmd.Flags().Bool("dry-run", false, "show the version the schema would get without registering it")
+}
+
+func showVersionPrediction(prediction *model.VersionPrediction) {
+	printResult(prediction, func(w io.Writer, wide bool) {
+		if prediction.IsNew {
+			fmt.Fprintf(w, "Schema would be registered with version `%s`\n", prediction.Version.String())
+		} else {
+			fmt.Fprintf(w, "Schema is the same as the latest one with ID `%d` and version `%s`, so no schema would be registered\n", prediction.ExistingId, prediction.Version.String())
+		}
+		if prediction.Restriction != "" {
+			fmt.Fprintf(w, "Compatibility restriction: %s\n", prediction.Restriction)
+		}
+
+		if len(prediction.Comparisons) > 0 {
+			table := tablewriter.NewWriter(w)
+			table.SetHeader([]string{"ID", "VERSION", "COMPATIBILITY", "DECISIVE"})
+			for _, c := range prediction.Comparisons {
+				decisive := ""
+				if c.IsDecisive {
+					decisive = "*"
+				}
+				table.Append([]string{strconv.FormatInt(c.Id, 10), c.Version.String(), c.Compatibility, decisive})
+			}
+			table.Render()
+		}
+	})
+
+	if !prediction.IsAllowed {
+		exitWithCode(fmt.Errorf("schema would be rejected since it violates compatibility restriction (%s) of this subject", prediction.Restriction), exitCodeIncompatible)
+	}
 }
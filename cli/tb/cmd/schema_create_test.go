@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"gopkg.in/h2non/gock.v1"
@@ -56,3 +57,40 @@ func TestSchemaCreateFromValue(t *testing.T) {
 		t.Errorf("schema create command is expected to be success with args %v but an error was occured %v", args, err)
 	}
 }
+
+func TestSchemaCreateDryRun(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	// no schema should be registered
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions").
+		Reply(200).
+		JSON([]string{"v1.0.0"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(testSchema)
+	gock.New(hostForTest).
+		Get("/config/" + testSubject + "/properties/compatibility").
+		Reply(200).
+		BodyString("NONE")
+
+	args := []string{"schema", "create", fmt.Sprintf("@%s", sampleSchemaPath), "--subject", testSubject, "--dry-run"}
+	schemaCreateCmd.Root().SetArgs(args)
+	defer schemaCreateCmd.Flags().Set("dry-run", "false")
+
+	if err := schemaCreateCmd.Execute(); err != nil {
+		t.Errorf("schema create command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+	if !gock.IsDone() {
+		t.Errorf("existing schemas and the compatibility restriction are expected to be retrieved")
+	}
+}
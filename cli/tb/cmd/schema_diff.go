@@ -0,0 +1,195 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/diff"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+var schemaDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "show changes between two schemas",
+	Long: `Show semantic changes between two schemas, such as added, removed or renamed fields,
+type promotions and changes of defaults and docs, rather than differences of their texts.
+--from and --to take a version of a schema under the subject, i.e. major version (e.g. v1) or semantic version (e.g. v1.0.0),
+or a path to a schema file which begins with @. The latest schema under the subject is used for an omitted one.
+Changes are colorized when the output is a terminal unless --color is never.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("from", cmd.Flags().Lookup("from"))
+		viper.BindPFlag("to", cmd.Flags().Lookup("to"))
+		viper.BindPFlag("color", cmd.Flags().Lookup("color"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		subject := viper.GetString("subject")
+		from := viper.GetString("from")
+		to := viper.GetString("to")
+		color := viper.GetString("color")
+		if from == "" && to == "" {
+			exitWithUsage(cmd, fmt.Errorf("either --from or --to should be specified"))
+		}
+		if color != "auto" && color != "always" && color != "never" {
+			exitWithUsage(cmd, fmt.Errorf("invalid color `%s`. Valid values are auto, always or never", color))
+		}
+
+		fromLabel, fromSchema := resolveSchema(cmd, subject, from)
+		toLabel, toSchema := resolveSchema(cmd, subject, to)
+		result := &schemaDiff{From: fromLabel, To: toLabel, Changes: diff.Diff(fromSchema, toSchema)}
+		if !strings.HasPrefix(from, "@") || !strings.HasPrefix(to, "@") {
+			result.Subject = subject
+		}
+		showSchemaDiff(result, color == "always" || (color == "auto" && isTerminal(stdout)))
+	},
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaDiffCmd)
+
+	schemaDiffCmd.Flags().String("from", "", "version of the old schema or a path to its file (@$path). The latest schema if omitted")
+	schemaDiffCmd.Flags().String("to", "", "version of the new schema or a path to its file (@$path). The latest schema if omitted")
+	schemaDiffCmd.Flags().String("color", "auto", "colorize changes (auto | always | never)")
+}
+
+// schemaDiff is the document of changes between two schemas.
+// From and To are the versions of registered schemas or the paths to schema files.
+type schemaDiff struct {
+	Subject string         `json:"subject,omitempty"`
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Changes []model.Change `json:"changes"`
+}
+
+// resolveSchema reads a schema from a file if value is a path, otherwise retrieves a registered one under the subject.
+// It returns the label of the schema with the parsed one.
+func resolveSchema(cmd *cobra.Command, subject, value string) (string, avro.Schema) {
+	var (
+		label      string
+		definition string
+	)
+	if strings.HasPrefix(value, "@") {
+		content, err := valueOrFromPath(value)
+		if err != nil {
+			exitWithError(err)
+		}
+		label, definition = value[1:], string(content)
+	} else {
+		if subject == "" {
+			exitWithUsage(cmd, fmt.Errorf("subject is not specified"))
+		}
+		schema := getSchemaByVersion(cmd, newClient(), subject, value)
+		label, definition = schema.Version.String(), schema.Definition
+	}
+
+	parsed, err := avro.Parse(definition)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to parse schema %s: %v", label, err))
+	}
+	return label, parsed
+}
+
+// showSchemaDiff shows changes like a unified diff, where added ones are marked by `+`, removed ones by `-` and the others by `~`.
+// The wide table also shows what each node was and becomes.
+func showSchemaDiff(result *schemaDiff, colorize bool) {
+	printResult(result, func(w io.Writer, wide bool) {
+		lines := []string{"--- " + result.From, "+++ " + result.To}
+		if len(result.Changes) == 0 {
+			lines = append(lines, "No changes")
+		}
+
+		buf := new(bytes.Buffer)
+		tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+		for _, c := range result.Changes {
+			columns := []string{changeMark(c.Kind) + " " + c.Path, c.Kind, c.Message}
+			if wide {
+				columns = append(columns, c.From, c.To)
+			}
+			fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		}
+		tw.Flush()
+		if buf.Len() > 0 {
+			lines = append(lines, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")...)
+		}
+
+		for _, line := range lines {
+			if colorize {
+				line = colorizeLine(line)
+			}
+			fmt.Fprintln(w, line)
+		}
+	})
+}
+
+func changeMark(kind string) string {
+	switch kind {
+	case model.FieldAdded, model.UnionBranchAdded, model.SymbolAdded, model.DefaultAdded:
+		return "+"
+	case model.FieldRemoved, model.UnionBranchRemoved, model.SymbolRemoved, model.DefaultRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// ANSI escape sequences for colors
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+func colorizeLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++"):
+		return colorBold + line + colorReset
+	case strings.HasPrefix(line, "+"):
+		return colorGreen + line + colorReset
+	case strings.HasPrefix(line, "-"):
+		return colorRed + line + colorReset
+	case strings.HasPrefix(line, "~"):
+		return colorYellow + line + colorReset
+	}
+	return line
+}
+
+// isTerminal reports whether w is a terminal. NO_COLOR disables colors as https://no-color.org suggests.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestSchemaDiff(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	newer := testSchema
+	newer.Id = 2
+	newer.Version = model.SemVer{Major: 1, Minor: 1, Patch: 0}
+	newer.Definition = `{"namespace": "com.example", "name": "Person", "type": "record", "fields": [
+	  {"name": "id", "type": "long"},
+	  {"name": "first_name", "type": "string"},
+	  {"name": "last_name", "type": "string", "default": ""}
+	]}`
+
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.0.0").
+		Reply(200).
+		JSON(testSchema)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/latest").
+		Reply(200).
+		JSON(newer)
+
+	args := []string{"schema", "diff", "--subject", testSubject, "--from", "v1.0.0", "--color", "never"}
+	schemaDiffCmd.Root().SetArgs(args)
+	defer schemaDiffCmd.Flags().Set("from", "")
+	defer schemaDiffCmd.Flags().Set("color", "auto")
+
+	out, _ := captureOutput(func() {
+		if err := schemaDiffCmd.Execute(); err != nil {
+			t.Errorf("schema diff command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	for _, expect := range []string{"--- v1.0.0", "+++ v1.1.0", "~ $.id", "TYPE_PROMOTED", "+ $.last_name", "FIELD_ADDED"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("schema diff command is expected to print %q but got\n%s", expect, out)
+		}
+	}
+}
+
+func TestSchemaDiffFiles(t *testing.T) {
+	args := []string{"schema", "diff", "--from", fmt.Sprintf("@%s", sampleSchemaPath), "--to", fmt.Sprintf("@%s", sampleSchemaPath), "--output", "json"}
+	schemaDiffCmd.Root().SetArgs(args)
+	defer schemaDiffCmd.Flags().Set("from", "")
+	defer schemaDiffCmd.Flags().Set("to", "")
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	// no request should be issued
+	out, _ := captureOutput(func() {
+		if err := schemaDiffCmd.Execute(); err != nil {
+			t.Errorf("schema diff command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	result := new(schemaDiff)
+	if err := json.Unmarshal([]byte(out), result); err != nil {
+		t.Fatalf("schema diff command is expected to print JSON but got %s", out)
+	}
+	if result.Subject != "" || result.From != sampleSchemaPath || len(result.Changes) != 0 {
+		t.Errorf("schema diff of the same files is expected to have no changes but got %+v", result)
+	}
+}
+
+func TestColorizeLine(t *testing.T) {
+	cases := map[string]string{
+		"+ $.a  FIELD_ADDED":   colorGreen,
+		"- $.a  FIELD_REMOVED": colorRed,
+		"~ $.a  DOC_CHANGED":   colorYellow,
+		"--- v1.0.0":           colorBold,
+	}
+	for line, color := range cases {
+		if actual := colorizeLine(line); actual != color+line+colorReset {
+			t.Errorf("colorizeLine(%q) = %q, wants it in %q", line, actual, color)
+		}
+	}
+}
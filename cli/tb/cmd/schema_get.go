@@ -29,6 +29,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/cyberagent/typebook/client/go/avro"
 	"github.com/cyberagent/typebook/client/go/model"
 )
 
@@ -38,10 +39,12 @@ var schemaGetCmd = &cobra.Command{
 	Long: `Retrieve a schema based on an ID or the pair of a subject and a version.
 Either id or subject should be provided. If both are specified, id takes a precedence.
 version is optional. If omitted, the latest schema under the subject is retrieved.
-Available form of version is semantic version (e.g. v1.0.0) or major version (e.g. v1).`,
+Available form of version is semantic version (e.g. v1.0.0) or major version (e.g. v1).
+With --canonical, the schema is printed in Parsing Canonical Form of Avro.`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
 		viper.BindPFlag("version", cmd.Flags().Lookup("version"))
+		viper.BindPFlag("canonical", cmd.Flags().Lookup("canonical"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -82,6 +85,8 @@ func init() {
 	schemaGetCmd.Flags().Int("id", -1, "ID of schema")
 
 	schemaGetCmd.Flags().String("version", "", "version of schema")
+
+	schemaGetCmd.Flags().Bool("canonical", false, "print the schema in Parsing Canonical Form")
 }
 
 func showSchemaDef(f func() (*model.Schema, *model.Error)) {
@@ -89,9 +94,17 @@ func showSchemaDef(f func() (*model.Schema, *model.Error)) {
 	if err != nil {
 		exitWithError(err)
 	}
-	if js, err := getPrettySchemaDef(schema); err != nil {
+	if viper.GetBool("canonical") {
+		parsed, err := avro.Parse(schema.Definition)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to parse schema: %v", err))
+		}
+		canonical := *schema
+		canonical.Definition = avro.CanonicalForm(parsed)
+		printMessage(&canonical, "%s\n", canonical.Definition)
+	} else if js, err := getPrettySchemaDef(schema); err != nil {
 		exitWithError(fmt.Errorf("failed to decode schema: %v", err))
 	} else {
-		fmt.Println(js)
+		printMessage(schema, "%s\n", js)
 	}
 }
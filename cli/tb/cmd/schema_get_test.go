@@ -81,3 +81,19 @@ func TestSchemaGetById(t *testing.T) {
 		t.Errorf("schema get command is expected to be success with args %v but an error was occured %v", args, err)
 	}
 }
+
+func TestSchemaGetCanonical(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(hostForTest).
+		Get("/schemas/ids/1").
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"schema", "get", "--id", "1", "--canonical"}
+	schemaGetCmd.Root().SetArgs(args)
+
+	if err := schemaGetCmd.Execute(); err != nil {
+		t.Errorf("schema get command is expected to be success with args %v but an error was occured %v", args, err)
+	}
+}
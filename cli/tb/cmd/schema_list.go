@@ -22,14 +22,195 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
 
 var schemaListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "list metadata of schemas",
-	Long:  "List metadata of schemas.",
+	Long: `List metadata of schemas, i.e. ID, subject, version, fingerprint and the number of fields,
+under the specified subject or under all subjects if --subject is omitted.
+Schemas can be filtered by a major version with --major, by the lowest version with --since,
+or only the latest schema of each major version can be listed with --latest-per-major.
+They are sorted by subject and version unless --sort is given.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("major", cmd.Flags().Lookup("major"))
+		viper.BindPFlag("since", cmd.Flags().Lookup("since"))
+		viper.BindPFlag("latest-per-major", cmd.Flags().Lookup("latest-per-major"))
+		viper.BindPFlag("sort", cmd.Flags().Lookup("sort"))
+		viper.BindPFlag("desc", cmd.Flags().Lookup("desc"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		subject := viper.GetString("subject")
+		major := viper.GetInt("major")
+		latestPerMajor := viper.GetBool("latest-per-major")
+		order := viper.GetString("sort")
+		var since *model.SemVer
+		if s := viper.GetString("since"); s != "" {
+			semver, err := model.NewSemVer(s)
+			if err != nil {
+				exitWithUsage(cmd, fmt.Errorf("invalid format version `%s`. Valid form is semantic version (e.g. v1.0.0)", s))
+			}
+			since = semver
+		}
+		if order != "subject" && order != "version" && order != "id" {
+			exitWithUsage(cmd, fmt.Errorf("invalid sort key `%s`. Valid keys are subject, version or id", order))
+		}
+
+		client := newClient()
+		subjects := []string{subject}
+		if subject == "" {
+			all, err := client.ListSubjects()
+			if err != nil {
+				exitWithError(err)
+			}
+			subjects = all
+		}
+
+		schemas := make([]model.Schema, 0)
+		for _, subject := range subjects {
+			versions, err := client.ListVersions(subject)
+			if err != nil {
+				exitWithError(err)
+			}
+			// versions are filtered before retrieving schemas not to issue needless requests
+			for _, version := range filterVersions(versions, major, since, latestPerMajor) {
+				schema, err := client.GetSchemaBySemVer(subject, version)
+				if err != nil {
+					exitWithError(err)
+				}
+				schemas = append(schemas, *schema)
+			}
+		}
+
+		sortSchemas(schemas, order, viper.GetBool("desc"))
+		showSchemaSummaries(schemas...)
+	},
 }
 
 func init() {
 	schemaCmd.AddCommand(schemaListCmd)
+
+	schemaListCmd.Flags().Int("major", -1, "list only schemas of the major version")
+	schemaListCmd.Flags().String("since", "", "list only schemas of the version or later (e.g. v1.3.0)")
+	schemaListCmd.Flags().Bool("latest-per-major", false, "list only the latest schema of each major version")
+	schemaListCmd.Flags().String("sort", "subject", "sort key (subject | version | id)")
+	schemaListCmd.Flags().Bool("desc", false, "sort in descending order")
+}
+
+// filterVersions returns versions of the major version (all if major is negative) not lower than since (all if nil).
+// If latestPerMajor is set, only the highest version of each major version is returned.
+func filterVersions(versions []model.SemVer, major int, since *model.SemVer, latestPerMajor bool) []model.SemVer {
+	latest := make(map[int]model.SemVer)
+	filtered := make([]model.SemVer, 0, len(versions))
+	for _, version := range versions {
+		if major >= 0 && version.Major != major {
+			continue
+		}
+		if since != nil && version.Less(*since) {
+			continue
+		}
+		if current, ok := latest[version.Major]; !ok || current.Less(version) {
+			latest[version.Major] = version
+		}
+		filtered = append(filtered, version)
+	}
+
+	if !latestPerMajor {
+		return filtered
+	}
+	latestOnly := make([]model.SemVer, 0, len(latest))
+	for _, version := range filtered {
+		if version == latest[version.Major] {
+			latestOnly = append(latestOnly, version)
+		}
+	}
+	return latestOnly
+}
+
+// sortSchemas sorts schemas by the key, i.e. subject (and version), version (and subject) or id.
+func sortSchemas(schemas []model.Schema, key string, desc bool) {
+	less := func(a, b model.Schema) bool {
+		switch key {
+		case "version":
+			if a.Version != b.Version {
+				return a.Version.Less(b.Version)
+			}
+			return a.Subject < b.Subject
+		case "id":
+			return a.Id < b.Id
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		return a.Version.Less(b.Version)
+	}
+	sort.SliceStable(schemas, func(i, j int) bool {
+		if desc {
+			return less(schemas[j], schemas[i])
+		}
+		return less(schemas[i], schemas[j])
+	})
+}
+
+// schemaSummary is the document of a schema listed by tb schema list.
+// Fingerprint is the CRC-64-AVRO fingerprint of the Parsing Canonical Form, and Fields is the number of fields of a record.
+type schemaSummary struct {
+	Id          int64  `json:"id"`
+	Subject     string `json:"subject"`
+	Version     string `json:"version"`
+	Name        string `json:"name,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Fields      int    `json:"fields"`
+}
+
+func summaryOf(schema model.Schema) schemaSummary {
+	summary := schemaSummary{Id: schema.Id, Subject: schema.Subject, Version: schema.Version.String()}
+	if parsed, err := avro.Parse(schema.Definition); err == nil {
+		summary.Fingerprint = fmt.Sprintf("%016x", avro.Fingerprint(parsed))
+		if named, ok := parsed.(avro.NamedSchema); ok {
+			summary.Name = named.FullName()
+		}
+		if record, ok := parsed.(*avro.RecordSchema); ok {
+			summary.Fields = len(record.Fields)
+		}
+	}
+	return summary
+}
+
+// showSchemaSummaries shows schemas with their fingerprints and the numbers of fields.
+// The wide table also shows the full names of the schemas.
+func showSchemaSummaries(schemas ...model.Schema) {
+	summaries := make([]schemaSummary, 0, len(schemas))
+	for _, schema := range schemas {
+		summaries = append(summaries, summaryOf(schema))
+	}
+	printResult(summaries, func(w io.Writer, wide bool) {
+		table := tablewriter.NewWriter(w)
+		header := []string{"ID", "SUBJECT", "VERSION", "FINGERPRINT", "FIELDS"}
+		if wide {
+			header = append(header, "NAME")
+		}
+		table.SetHeader(header)
+		for _, s := range summaries {
+			row := []string{strconv.FormatInt(s.Id, 10), s.Subject, s.Version, s.Fingerprint, strconv.Itoa(s.Fields)}
+			if wide {
+				row = append(row, s.Name)
+			}
+			table.Append(row)
+		}
+		table.Render()
+	})
 }
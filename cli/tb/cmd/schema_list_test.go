@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestSchemaList(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TYPEBOOK_CACHE_DIR", dir)
+	defer os.Unsetenv("TYPEBOOK_CACHE_DIR")
+
+	gock.New(hostForTest).
+		Get("/subjects$").
+		Reply(200).
+		JSON([]string{testSubject})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions").
+		Reply(200).
+		JSON([]string{"v1.0.0", "v1.10.0", "v1.2.0", "v2.0.0"})
+	// only the latest schema of each major version should be retrieved
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v1.10.0").
+		Reply(200).
+		JSON(`{"id":3,"subject":"` + testSubject + `","version":"v1.10.0","schema":"{\"type\":\"string\"}"}`)
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "/versions/v2.0.0").
+		Reply(200).
+		JSON(model.Schema{Id: 4, Subject: testSubject, Version: model.SemVer{Major: 2}, Definition: schemaDef})
+
+	// --subject set by other tests persists, so it is cleared to list schemas under all subjects
+	schemaCmd.PersistentFlags().Set("subject", "")
+	args := []string{"schema", "list", "--latest-per-major", "--desc", "--output", "json"}
+	schemaListCmd.Root().SetArgs(args)
+	defer schemaListCmd.Flags().Set("latest-per-major", "false")
+	defer schemaListCmd.Flags().Set("desc", "false")
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	out, _ := captureOutput(func() {
+		if err := schemaListCmd.Execute(); err != nil {
+			t.Errorf("schema list command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	var summaries []schemaSummary
+	if err := json.Unmarshal([]byte(out), &summaries); err != nil {
+		t.Fatalf("schema list command is expected to print JSON but got %s", out)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("schema list command is expected to list 2 schemas but got %+v", summaries)
+	}
+	person := summaries[0]
+	if person.Id != 4 || person.Version != "v2.0.0" || person.Name != "com.example.Person" || person.Fields != 2 || len(person.Fingerprint) != 16 {
+		t.Errorf("the first schema is expected to be v2.0.0 of com.example.Person with 2 fields but got %+v", person)
+	}
+	if summaries[1].Version != "v1.10.0" {
+		t.Errorf("the second schema is expected to be v1.10.0 but got %+v", summaries[1])
+	}
+	if !gock.IsDone() {
+		t.Errorf("subjects, versions and schemas are expected to be retrieved")
+	}
+}
+
+func TestFilterVersions(t *testing.T) {
+	v := func(version string) model.SemVer {
+		semver, _ := model.NewSemVer(version)
+		return *semver
+	}
+	versions := []model.SemVer{v("v1.0.0"), v("v1.10.0"), v("v1.2.0"), v("v1.2.1"), v("v2.0.0"), v("v2.1.0")}
+	since := &model.SemVer{Major: 1, Minor: 2}
+	cases := []struct {
+		major          int
+		since          *model.SemVer
+		latestPerMajor bool
+		expect         []model.SemVer
+	}{
+		{-1, nil, false, versions},
+		{1, nil, false, []model.SemVer{v("v1.0.0"), v("v1.10.0"), v("v1.2.0"), v("v1.2.1")}},
+		{-1, since, false, []model.SemVer{v("v1.10.0"), v("v1.2.0"), v("v1.2.1"), v("v2.0.0"), v("v2.1.0")}},
+		{-1, nil, true, []model.SemVer{v("v1.10.0"), v("v2.1.0")}},
+		{2, since, true, []model.SemVer{v("v2.1.0")}},
+	}
+	for _, c := range cases {
+		if actual := filterVersions(versions, c.major, c.since, c.latestPerMajor); !reflect.DeepEqual(actual, c.expect) {
+			t.Errorf("filterVersions(%v, %d, %v, %v) = %v, wants %v", versions, c.major, c.since, c.latestPerMajor, actual, c.expect)
+		}
+	}
+}
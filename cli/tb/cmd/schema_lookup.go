@@ -68,7 +68,7 @@ A path should begin with @.`,
 			if meta, err := client.LookupSchema(subject, string(content)); err != nil {
 				exitWithError(err)
 			} else {
-				showSchemaMetas(*meta)
+				showSchemaMeta(*meta)
 			}
 		}
 	},
@@ -23,7 +23,7 @@
 package cmd
 
 import (
-	"os"
+	"io"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -41,8 +41,22 @@ func init() {
 	RootCmd.AddCommand(subjectCmd)
 }
 
+// showSubject shows a subject and its description.
+func showSubject(subject *model.Subject) {
+	printResult(subject, func(w io.Writer, wide bool) {
+		renderSubjects(w, subject)
+	})
+}
+
+// showSubjects shows subjects and their descriptions.
 func showSubjects(subjects ...*model.Subject) {
-	table := tablewriter.NewWriter(os.Stdout)
+	printResult(subjects, func(w io.Writer, wide bool) {
+		renderSubjects(w, subjects...)
+	})
+}
+
+func renderSubjects(w io.Writer, subjects ...*model.Subject) {
+	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"NAME", "DESCRIPTION"})
 	for _, subject := range subjects {
 		table.Append([]string{subject.Name, subject.Description})
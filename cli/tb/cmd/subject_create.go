@@ -23,10 +23,10 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/model"
 )
 
 // available options
@@ -49,10 +49,10 @@ Subject is a unit of data set. Schemas are evolved under a subject.`,
 		description := viper.GetString("description")
 
 		client := newClient()
-		if id, err := client.CreateSubject(name, description); err != nil {
+		if _, err := client.CreateSubject(name, description); err != nil {
 			exitWithError(err)
-		} else if id == 0 {
-			fmt.Printf("Subject `%s` is created.\n", name)
+		} else {
+			printMessage(&model.Subject{Name: name, Description: description}, "Subject `%s` is created.\n", name)
 		}
 	},
 }
@@ -24,8 +24,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/model"
 )
 
 // configDeleteCmd represents the delete command
@@ -42,8 +45,12 @@ If some schemas remains under the subject, this command will fail.`,
 		client := newClient()
 		if deletedRows, err := client.DeleteSubject(name); err != nil {
 			exitWithError(err)
-		} else if deletedRows == 1 {
-			fmt.Printf("Subject `%s` is deleted.\n", name)
+		} else {
+			printResult(&model.Subject{Name: name}, func(w io.Writer, wide bool) {
+				if deletedRows == 1 {
+					fmt.Fprintf(w, "Subject `%s` is deleted.\n", name)
+				}
+			})
 		}
 	},
 }
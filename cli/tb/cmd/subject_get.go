@@ -39,7 +39,7 @@ var subjectGetCmd = &cobra.Command{
 		if subject, err := client.GetSubject(name); err != nil {
 			exitWithError(err)
 		} else {
-			showSubjects(subject)
+			showSubject(subject)
 		}
 	},
 }
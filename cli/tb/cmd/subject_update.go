@@ -24,9 +24,12 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/cyberagent/typebook/client/go/model"
 )
 
 var subjectUpdateCmd = &cobra.Command{
@@ -45,8 +48,12 @@ var subjectUpdateCmd = &cobra.Command{
 		client := newClient()
 		if updatedRows, err := client.UpdateDescription(name, description); err != nil {
 			exitWithError(err)
-		} else if updatedRows == 1 {
-			fmt.Printf("the description for Subject `%s` is updated.\n", name)
+		} else {
+			printResult(&model.Subject{Name: name, Description: description}, func(w io.Writer, wide bool) {
+				if updatedRows == 1 {
+					fmt.Fprintf(w, "the description for Subject `%s` is updated.\n", name)
+				}
+			})
 		}
 	},
 }
@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/cyberagent/typebook/client/go/dirsync"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync $dir",
+	Short: "register schemas laid out in a directory",
+	Long: `Register schemas laid out as $dir/$subject/*.avsc, each directory of which is a subject created if it does not exist.
+Schemas are looked up under their subjects and those not found are registered in order of subjects and file names.
+The ID and semver of each file are recorded in $dir/typebook.lock, and files not changed since then are skipped without any request,
+so syncing an unchanged directory is a no-op.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		synced, err := dirsync.Sync(context.Background(), newClient(), args[0])
+		if err != nil {
+			if len(synced) > 0 {
+				showSyncedSchemas(synced)
+			}
+			exitWithError(err)
+		}
+		showSyncedSchemas(synced)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(syncCmd)
+}
+
+// syncedSchema is the document of a schema file synced by tb sync.
+type syncedSchema struct {
+	File    string `json:"file"`
+	Subject string `json:"subject"`
+	Id      int64  `json:"id"`
+	Version string `json:"version"`
+	Digest  string `json:"digest"`
+	Status  string `json:"status"`
+}
+
+func showSyncedSchemas(synced []dirsync.SyncedSchema) {
+	docs := make([]syncedSchema, 0, len(synced))
+	for _, s := range synced {
+		docs = append(docs, syncedSchema{File: s.File, Subject: s.Subject, Id: s.Id, Version: s.Version, Digest: s.Digest, Status: string(s.Status)})
+	}
+	printResult(docs, func(w io.Writer, wide bool) {
+		renderSyncedSchemas(w, wide, docs)
+	})
+}
+
+func renderSyncedSchemas(w io.Writer, wide bool, docs []syncedSchema) {
+	header := []string{"FILE", "SUBJECT", "VERSION", "STATUS"}
+	if wide {
+		header = append(header, "ID", "DIGEST")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	for _, doc := range docs {
+		row := []string{doc.File, doc.Subject, doc.Version, doc.Status}
+		if wide {
+			row = append(row, fmt.Sprint(doc.Id), doc.Digest)
+		}
+		table.Append(row)
+	}
+	table.Render()
+}
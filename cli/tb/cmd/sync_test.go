@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/cyberagent/typebook/client/go/dirsync"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestSync(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "typebook-sync-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, testSubject), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, testSubject, "person.avsc"), []byte(schemaDef), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookup$").
+		Reply(404).
+		JSON(model.ServerError{ErrorCode: 404, Message: "Schema Not Found"})
+	gock.New(hostForTest).
+		Get("/subjects/" + testSubject + "$").
+		Reply(200).
+		JSON(model.Subject{Name: testSubject})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/versions$").
+		Reply(201).
+		JSON(model.SchemaId{Id: 1})
+	gock.New(hostForTest).
+		Post("/subjects/" + testSubject + "/schema/lookup$").
+		Reply(200).
+		JSON(testSchema)
+
+	args := []string{"sync", dir, "--output", "json"}
+	syncCmd.Root().SetArgs(args)
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+
+	out, _ := captureOutput(func() {
+		if err := syncCmd.Execute(); err != nil {
+			t.Errorf("sync command is expected to be success with args %v but an error was occured %v", args, err)
+		}
+	})
+	var synced []syncedSchema
+	if err := json.Unmarshal([]byte(out), &synced); err != nil {
+		t.Fatalf("sync command is expected to print JSON but got %s", out)
+	}
+	if len(synced) != 1 || synced[0].File != testSubject+"/person.avsc" || synced[0].Version != "v1.0.0" || synced[0].Status != "REGISTERED" {
+		t.Errorf("person.avsc is expected to be registered but got %+v", synced)
+	}
+	lock, err := dirsync.ReadLock(filepath.Join(dir, dirsync.LockfileName))
+	if err != nil || len(lock.Schemas) != 1 || lock.Schemas[0].Id != 1 {
+		t.Errorf("the lockfile is expected to record person.avsc but got %+v, %v", lock, err)
+	}
+	if !gock.IsDone() {
+		t.Errorf("the schema is expected to be registered")
+	}
+}
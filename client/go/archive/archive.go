@@ -0,0 +1,244 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package archive exports the whole contents of a typebook registry to a portable archive and reads it back.
+//
+// An archive is a gzipped tar file laid out as follows, whose entries are written in a fixed order
+// without timestamps, so that exporting the same registry always produces the same bytes.
+//
+//	manifest.json                          the format version and the names of subjects
+//	subjects/(subject)/subject.json        the name and description of a subject
+//	subjects/(subject)/config.json         the config of a subject
+//	subjects/(subject)/schemas/(semver).json  each schema with its ID and semver
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// FormatVersion is the version of the archive layout written by Write.
+// Read rejects an archive with a newer version.
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	subjectsDir  = "subjects"
+	subjectFile  = "subject.json"
+	configFile   = "config.json"
+	schemasDir   = "schemas"
+)
+
+// Registry is the contents of a typebook registry.
+type Registry struct {
+	Subjects []Subject
+}
+
+// Subject is a subject with its config and all versions of its schemas.
+type Subject struct {
+	model.Subject
+	Config  model.Config
+	Schemas []model.Schema
+}
+
+// Schemas returns the number of schemas under all subjects.
+func (r *Registry) Schemas() int {
+	n := 0
+	for _, s := range r.Subjects {
+		n += len(s.Schemas)
+	}
+	return n
+}
+
+// sort sorts subjects by name and schemas by version, which is the order they are written in.
+func (r *Registry) sort() {
+	sort.Slice(r.Subjects, func(i, j int) bool { return r.Subjects[i].Name < r.Subjects[j].Name })
+	for _, s := range r.Subjects {
+		schemas := s.Schemas
+		sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version.Less(schemas[j].Version) })
+	}
+}
+
+type manifest struct {
+	FormatVersion int      `json:"format_version"`
+	Subjects      []string `json:"subjects"`
+}
+
+// Write writes the registry to w as an archive.
+// Subjects and schemas of the registry are sorted in place.
+func Write(w io.Writer, registry *Registry) error {
+	registry.sort()
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(registry.Subjects))
+	for _, s := range registry.Subjects {
+		if s.Name == "" || strings.Contains(s.Name, "/") {
+			return fmt.Errorf("invalid subject name `%s` to archive", s.Name)
+		}
+		names = append(names, s.Name)
+	}
+	if err := writeEntry(tw, manifestFile, manifest{FormatVersion: FormatVersion, Subjects: names}); err != nil {
+		return err
+	}
+	for _, s := range registry.Subjects {
+		dir := path.Join(subjectsDir, s.Name)
+		if err := writeEntry(tw, path.Join(dir, subjectFile), s.Subject); err != nil {
+			return err
+		}
+		if err := writeEntry(tw, path.Join(dir, configFile), s.Config); err != nil {
+			return err
+		}
+		for _, schema := range s.Schemas {
+			if err := writeEntry(tw, path.Join(dir, schemasDir, schema.Version.String()+".json"), schema); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, v interface{}) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	// neither the time nor the owner is recorded for the archive to be deterministic
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(body)),
+		ModTime:  time.Unix(0, 0),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(body)
+	return err
+}
+
+// Read reads a registry from an archive written by Write.
+func Read(r io.Reader) (*Registry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var m *manifest
+	subjects := make(map[string]*Subject)
+	subjectOf := func(name string) *Subject {
+		if s, ok := subjects[name]; ok {
+			return s
+		}
+		s := &Subject{Subject: model.Subject{Name: name}, Schemas: make([]model.Schema, 0)}
+		subjects[name] = s
+		return s
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(header.Name, "/")
+		switch {
+		case header.Name == manifestFile:
+			m = new(manifest)
+			if err := unmarshalEntry(header.Name, body, m); err != nil {
+				return nil, err
+			}
+			if m.FormatVersion > FormatVersion {
+				return nil, fmt.Errorf("archive format version %d is not supported. Supported version is up to %d", m.FormatVersion, FormatVersion)
+			}
+		case len(parts) == 3 && parts[0] == subjectsDir && parts[2] == subjectFile:
+			s := subjectOf(parts[1])
+			if err := unmarshalEntry(header.Name, body, &s.Subject); err != nil {
+				return nil, err
+			}
+		case len(parts) == 3 && parts[0] == subjectsDir && parts[2] == configFile:
+			if err := unmarshalEntry(header.Name, body, &subjectOf(parts[1]).Config); err != nil {
+				return nil, err
+			}
+		case len(parts) == 4 && parts[0] == subjectsDir && parts[2] == schemasDir:
+			var schema model.Schema
+			if err := unmarshalEntry(header.Name, body, &schema); err != nil {
+				return nil, err
+			}
+			s := subjectOf(parts[1])
+			s.Schemas = append(s.Schemas, schema)
+		default:
+			return nil, fmt.Errorf("unknown entry `%s` in the archive", header.Name)
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("the archive has no %s", manifestFile)
+	}
+
+	registry := &Registry{Subjects: make([]Subject, 0, len(m.Subjects))}
+	for _, name := range m.Subjects {
+		s, ok := subjects[name]
+		if !ok {
+			return nil, fmt.Errorf("subject `%s` listed in %s is missing in the archive", name, manifestFile)
+		}
+		delete(subjects, name)
+		registry.Subjects = append(registry.Subjects, *s)
+	}
+	for name := range subjects {
+		return nil, fmt.Errorf("subject `%s` in the archive is not listed in %s", name, manifestFile)
+	}
+	registry.sort()
+	return registry, nil
+}
+
+func unmarshalEntry(name string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid entry `%s` in the archive: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	registry, err := NewExporter(newFakeSource()).Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first, second bytes.Buffer
+	if err := Write(&first, registry); err != nil {
+		t.Fatalf("Write should not be an error but an error was occurred: %v", err)
+	}
+	// the same registry in a different order should be written into the same bytes
+	registry.Subjects[0], registry.Subjects[2] = registry.Subjects[2], registry.Subjects[0]
+	if err := Write(&second, registry); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("Write is expected to be deterministic")
+	}
+
+	read, err := Read(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("Read should not be an error but an error was occurred: %v", err)
+	}
+	if !reflect.DeepEqual(read, registry) {
+		t.Errorf("Read(Write(%+v)) = %+v", registry, read)
+	}
+
+	names := make([]string, 0)
+	gz, _ := gzip.NewReader(bytes.NewReader(first.Bytes()))
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	if names[0] != "manifest.json" || names[1] != "subjects/page-views/subject.json" ||
+		names[2] != "subjects/page-views/config.json" || names[3] != "subjects/page-views/schemas/v1.0.0.json" {
+		t.Errorf("entries of the archive are not laid out as expected: %v", names)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	archiveOf := func(entries map[string]string, order ...string) io.Reader {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, name := range order {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(entries[name]))})
+			tw.Write([]byte(entries[name]))
+		}
+		tw.Close()
+		gz.Close()
+		return &buf
+	}
+
+	testCases := []struct {
+		entries map[string]string
+		order   []string
+		message string
+	}{
+		{
+			entries: map[string]string{"manifest.json": `{"format_version":2,"subjects":[]}`},
+			order:   []string{"manifest.json"},
+			message: "not supported",
+		},
+		{
+			entries: map[string]string{"subjects/a/subject.json": `{"name":"a"}`},
+			order:   []string{"subjects/a/subject.json"},
+			message: "no manifest.json",
+		},
+		{
+			entries: map[string]string{"manifest.json": `{"format_version":1,"subjects":["a"]}`},
+			order:   []string{"manifest.json"},
+			message: "missing",
+		},
+		{
+			entries: map[string]string{"manifest.json": `{"format_version":1,"subjects":[]}`, "README": ""},
+			order:   []string{"manifest.json", "README"},
+			message: "unknown entry",
+		},
+		{
+			entries: map[string]string{
+				"manifest.json":                  `{"format_version":1,"subjects":["a"]}`,
+				"subjects/a/schemas/v1.0.0.json": `{"id":1}`,
+			},
+			order:   []string{"manifest.json", "subjects/a/schemas/v1.0.0.json"},
+			message: "invalid entry",
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := Read(archiveOf(testCase.entries, testCase.order...))
+		if err == nil || !strings.Contains(err.Error(), testCase.message) {
+			t.Errorf("Read is expected to fail with `%s` for %v but got %v", testCase.message, testCase.order, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archive
+
+import (
+	"context"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// DefaultConcurrency is the number of concurrent requests issued by an Exporter by default.
+const DefaultConcurrency = 4
+
+// Source is a registry to export. Both *typebook.Client and *typebook.CachedClient implement it.
+type Source interface {
+	ListSubjectsContext(ctx context.Context) ([]string, *model.Error)
+	GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error)
+	GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error)
+	ListVersionsContext(ctx context.Context, subject string) ([]model.SemVer, *model.Error)
+	GetSchemaBySemVerContext(ctx context.Context, subject string, semver model.SemVer) (*model.Schema, *model.Error)
+}
+
+// Exporter retrieves subjects with their configs and all versions of their schemas from a Source.
+type Exporter struct {
+	// Subjects are glob patterns of subjects to export in the syntax of path.Match (e.g. `user-*`).
+	// All subjects are exported if it is empty.
+	Subjects []string
+	// Concurrency is the maximum number of concurrent requests. DefaultConcurrency is used if it is not positive.
+	Concurrency int
+
+	source Source
+}
+
+// NewExporter creates an Exporter which exports all subjects from the source.
+func NewExporter(source Source) *Exporter {
+	return &Exporter{source: source, Concurrency: DefaultConcurrency}
+}
+
+// Export retrieves subjects matching the patterns and everything under them.
+// It fails as soon as one of the requests fails, in which case the other requests are cancelled.
+func (e *Exporter) Export(ctx context.Context) (*Registry, error) {
+	for _, pattern := range e.Subjects {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	names, merr := e.source.ListSubjectsContext(ctx)
+	if merr != nil {
+		return nil, merr
+	}
+	names = e.filter(names)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	g := &group{sem: make(chan struct{}, concurrency), cancel: cancel}
+
+	subjects := make([]Subject, len(names))
+	for i := range names {
+		s, name := &subjects[i], names[i]
+		g.spawn(func() error {
+			subject, err := e.source.GetSubjectContext(ctx, name)
+			if err != nil {
+				return err
+			}
+			s.Subject = *subject
+			return nil
+		})
+		g.spawn(func() error {
+			config, err := e.source.GetConfigContext(ctx, name)
+			if err != nil {
+				return err
+			}
+			s.Config = *config
+			return nil
+		})
+		g.spawn(func() error {
+			versions, err := e.source.ListVersionsContext(ctx, name)
+			if err != nil {
+				return err
+			}
+			s.Schemas = make([]model.Schema, len(versions))
+			for j := range versions {
+				schema, version := &s.Schemas[j], versions[j]
+				g.spawn(func() error {
+					got, err := e.source.GetSchemaBySemVerContext(ctx, name, version)
+					if err != nil {
+						return err
+					}
+					*schema = *got
+					return nil
+				})
+			}
+			return nil
+		})
+	}
+	if err := g.wait(); err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{Subjects: subjects}
+	registry.sort()
+	return registry, nil
+}
+
+// ExportTo exports subjects in the same way as Export and writes them to w as an archive.
+func (e *Exporter) ExportTo(ctx context.Context, w io.Writer) (*Registry, error) {
+	registry, err := e.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := Write(w, registry); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// filter returns names of subjects which match any of the patterns.
+func (e *Exporter) filter(names []string) []string {
+	if len(e.Subjects) == 0 {
+		return names
+	}
+	matched := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, pattern := range e.Subjects {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// group runs functions concurrently up to the capacity of sem and keeps the first error,
+// on which cancel is called to abort the others.
+type group struct {
+	wg     sync.WaitGroup
+	sem    chan struct{}
+	cancel func()
+
+	once sync.Once
+	err  error
+}
+
+// spawn runs f in a new goroutine. It may be called by a function run by the group.
+func (g *group) spawn(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.sem <- struct{}{}
+		defer func() { <-g.sem }()
+		if err := f(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+func (g *group) wait() error {
+	g.wg.Wait()
+	return g.err
+}
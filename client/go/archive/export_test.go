@@ -0,0 +1,152 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// fakeSource is an in-memory Source which counts requests.
+type fakeSource struct {
+	subjects map[string]Subject
+	requests int32
+	failOn   string
+}
+
+func (fs *fakeSource) request(subject string) *model.Error {
+	atomic.AddInt32(&fs.requests, 1)
+	if fs.failOn != "" && subject == fs.failOn {
+		return model.NewError(&model.ServerError{ErrorCode: 500, Message: "Internal Server Error"}, nil)
+	}
+	return nil
+}
+
+func (fs *fakeSource) ListSubjectsContext(ctx context.Context) ([]string, *model.Error) {
+	names := make([]string, 0)
+	for name := range fs.subjects {
+		names = append(names, name)
+	}
+	return names, fs.request("")
+}
+
+func (fs *fakeSource) GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error) {
+	s := fs.subjects[name].Subject
+	return &s, fs.request(name)
+}
+
+func (fs *fakeSource) GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error) {
+	c := fs.subjects[subject].Config
+	return &c, fs.request(subject)
+}
+
+func (fs *fakeSource) ListVersionsContext(ctx context.Context, subject string) ([]model.SemVer, *model.Error) {
+	versions := make([]model.SemVer, 0)
+	for _, schema := range fs.subjects[subject].Schemas {
+		versions = append(versions, schema.Version)
+	}
+	return versions, fs.request(subject)
+}
+
+func (fs *fakeSource) GetSchemaBySemVerContext(ctx context.Context, subject string, semver model.SemVer) (*model.Schema, *model.Error) {
+	for _, schema := range fs.subjects[subject].Schemas {
+		if schema.Version == semver {
+			return &schema, fs.request(subject)
+		}
+	}
+	return nil, model.NewError(&model.ServerError{ErrorCode: 404, Message: "Schema Not Found"}, nil)
+}
+
+func newFakeSource() *fakeSource {
+	subjects := make(map[string]Subject)
+	for _, name := range []string{"user-events", "user-profiles", "page-views"} {
+		s := Subject{Subject: model.Subject{Name: name, Description: "events of " + name}, Config: model.Config{Compatibility: "BACKWARD"}}
+		// versions are listed in a different order from their IDs
+		for i, version := range []model.SemVer{{Major: 1, Minor: 10}, {Major: 1}, {Major: 1, Minor: 2}} {
+			s.Schemas = append(s.Schemas, model.Schema{
+				Id:         int64(len(subjects)*10 + i),
+				Subject:    name,
+				Version:    version,
+				Definition: fmt.Sprintf(`{"type":"fixed","name":"F","size":%d}`, i+1),
+			})
+		}
+		subjects[name] = s
+	}
+	return &fakeSource{subjects: subjects}
+}
+
+func TestExport(t *testing.T) {
+	source := newFakeSource()
+	exporter := NewExporter(source)
+	exporter.Subjects = []string{"user-*"}
+	exporter.Concurrency = 2
+
+	registry, err := exporter.Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export should not be an error but an error was occurred: %v", err)
+	}
+	if len(registry.Subjects) != 2 || registry.Subjects[0].Name != "user-events" || registry.Subjects[1].Name != "user-profiles" {
+		t.Fatalf("subjects matching user-* are expected to be exported in order of their names but got %+v", registry.Subjects)
+	}
+	events := registry.Subjects[0]
+	if events.Description != "events of user-events" || events.Config.Compatibility != "BACKWARD" {
+		t.Errorf("the description and config of user-events are expected to be exported but got %+v", events)
+	}
+	versions := make([]string, 0)
+	for _, schema := range events.Schemas {
+		versions = append(versions, schema.Version.String())
+	}
+	if expect := []string{"v1.0.0", "v1.2.0", "v1.10.0"}; !reflect.DeepEqual(versions, expect) {
+		t.Errorf("schemas are expected to be sorted by version as %v but got %v", expect, versions)
+	}
+	if events.Schemas[2].Id != source.subjects["user-events"].Schemas[0].Id {
+		t.Errorf("each schema is expected to keep its ID but got %+v", events.Schemas)
+	}
+	if registry.Schemas() != 6 {
+		t.Errorf("6 schemas are expected to be exported but got %d", registry.Schemas())
+	}
+	// ListSubjects, then GetSubject, GetConfig, ListVersions and 3 schemas for each of 2 subjects
+	if source.requests != 13 {
+		t.Errorf("13 requests are expected to be issued but got %d", source.requests)
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	source := newFakeSource()
+	source.failOn = "page-views"
+	_, err := NewExporter(source).Export(context.Background())
+	if merr, ok := err.(*model.Error); !ok || merr.ServerError == nil || merr.ErrorCode != 500 {
+		t.Errorf("Export is expected to fail with the error of the failed request but got %v", err)
+	}
+
+	exporter := NewExporter(newFakeSource())
+	exporter.Subjects = []string{"[user"}
+	if _, err := exporter.Export(context.Background()); err == nil {
+		t.Errorf("Export should fail with an invalid pattern")
+	}
+}
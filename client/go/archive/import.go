@@ -0,0 +1,213 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archive
+
+import (
+	"context"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Destination is a registry to import into, typically a *typebook.Client.
+type Destination interface {
+	GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error)
+	CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error)
+	UpdateDescriptionContext(ctx context.Context, name, description string) (int64, *model.Error)
+	GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error)
+	SetConfigContext(ctx context.Context, subject string, config model.Config) (int64, *model.Error)
+	LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error)
+	LookupAllSchemasContext(ctx context.Context, subject, definition string) ([]model.Schema, *model.Error)
+	RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error)
+}
+
+// Importer replays a registry on a Destination.
+type Importer struct {
+	destination Destination
+}
+
+// NewImporter creates an Importer which imports into the destination.
+func NewImporter(destination Destination) *Importer {
+	return &Importer{destination: destination}
+}
+
+// ImportReport is the result of an import.
+type ImportReport struct {
+	Subjects []SubjectImport
+}
+
+// SubjectImport is the result of importing a subject.
+type SubjectImport struct {
+	Name               string
+	Created            bool
+	DescriptionUpdated bool
+	ConfigUpdated      bool
+	Schemas            []SchemaImport
+}
+
+// SchemaImport is the result of importing a schema.
+// SourceId and Version are the ID and semver in the archive, while Id and ActualVersion are those in the destination.
+// Registered is false if the schema was already present in the destination, with the same version or not.
+type SchemaImport struct {
+	SourceId      int64
+	Id            int64
+	Version       model.SemVer
+	ActualVersion model.SemVer
+	Registered    bool
+}
+
+// IsDrifted reports whether the destination has the schema with a different version from the archive.
+func (si SchemaImport) IsDrifted() bool {
+	return si.Version != si.ActualVersion
+}
+
+// Drifts returns imported schemas whose versions are different from the archive.
+func (r *ImportReport) Drifts() []SchemaImport {
+	drifts := make([]SchemaImport, 0)
+	for _, s := range r.Subjects {
+		for _, schema := range s.Schemas {
+			if schema.IsDrifted() {
+				drifts = append(drifts, schema)
+			}
+		}
+	}
+	return drifts
+}
+
+// Import creates subjects missing in the destination, updates their descriptions,
+// registers schemas in order of their versions and sets configs.
+// Schemas already present with the same version are skipped, so an import can be resumed after a failure
+// and importing the same registry twice changes nothing.
+// A schema assigned a different version from the archive is reported as a drift instead of an error,
+// and it is not registered again when the import is repeated.
+// Configs are set after schemas are registered since a compatibility restriction may reject older schemas.
+// The report so far is returned with an error if a request fails.
+func (i *Importer) Import(ctx context.Context, registry *Registry) (*ImportReport, error) {
+	registry.sort()
+	report := &ImportReport{Subjects: make([]SubjectImport, 0, len(registry.Subjects))}
+	for _, s := range registry.Subjects {
+		imported, err := i.importSubject(ctx, s)
+		report.Subjects = append(report.Subjects, *imported)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (i *Importer) importSubject(ctx context.Context, s Subject) (*SubjectImport, error) {
+	imported := &SubjectImport{Name: s.Name, Schemas: make([]SchemaImport, 0, len(s.Schemas))}
+
+	existing, err := i.destination.GetSubjectContext(ctx, s.Name)
+	switch {
+	case err.IsNotFound():
+		if _, err := i.destination.CreateSubjectContext(ctx, s.Name, s.Description); err != nil {
+			return imported, err
+		}
+		imported.Created = true
+	case err != nil:
+		return imported, err
+	case existing.Description != s.Description:
+		if _, err := i.destination.UpdateDescriptionContext(ctx, s.Name, s.Description); err != nil {
+			return imported, err
+		}
+		imported.DescriptionUpdated = true
+	}
+
+	var previous *model.SemVer
+	for _, schema := range s.Schemas {
+		si, err := i.importSchema(ctx, s.Name, schema, previous)
+		if err != nil {
+			return imported, err
+		}
+		imported.Schemas = append(imported.Schemas, *si)
+		previous = &si.ActualVersion
+	}
+
+	if s.Config.Compatibility == "" {
+		return imported, nil
+	}
+	config, err := i.destination.GetConfigContext(ctx, s.Name)
+	if err != nil {
+		return imported, err
+	}
+	if *config != s.Config {
+		if _, err := i.destination.SetConfigContext(ctx, s.Name, s.Config); err != nil {
+			return imported, err
+		}
+		imported.ConfigUpdated = true
+	}
+	return imported, nil
+}
+
+// importSchema registers the schema unless it is present in the destination.
+// previous is the actual version of the schema imported just before under the subject, which is nil for the first one.
+// A schema is present if it is found with its version, or with any version after previous if it has drifted.
+// Otherwise a drifted schema would be registered again on every import since the server gives a new version to
+// a definition which is not the latest, while a definition reverted in the archive is still registered.
+func (i *Importer) importSchema(ctx context.Context, subject string, schema model.Schema, previous *model.SemVer) (*SchemaImport, error) {
+	si := &SchemaImport{SourceId: schema.Id, Version: schema.Version}
+
+	// the same definition may be registered with several versions, e.g. when a change is reverted,
+	// so all of them are looked up to find the one with the version
+	present, err := i.destination.LookupAllSchemasContext(ctx, subject, schema.Definition)
+	if err != nil && !err.IsNotFound() {
+		return nil, err
+	}
+	for _, p := range present {
+		if p.Version == schema.Version {
+			si.Id = p.Id
+			si.ActualVersion = p.Version
+			return si, nil
+		}
+	}
+	var drifted *model.Schema
+	for j, p := range present {
+		if (previous == nil || previous.Less(p.Version)) && (drifted == nil || p.Version.Less(drifted.Version)) {
+			drifted = &present[j]
+		}
+	}
+	if drifted != nil {
+		si.Id = drifted.Id
+		si.ActualVersion = drifted.Version
+		return si, nil
+	}
+
+	id, err := i.destination.RegisterSchemaContext(ctx, subject, schema.Definition)
+	if err != nil {
+		return nil, err
+	}
+	// a schema identical to the latest one is not registered again, so the latest matching one is what the schema is
+	registered, err := i.destination.LookupSchemaContext(ctx, subject, schema.Definition)
+	if err != nil {
+		return nil, err
+	}
+	si.Id = registered.Id
+	si.ActualVersion = registered.Version
+	si.Registered = true
+	for _, p := range present {
+		if p.Id == id.Id {
+			si.Registered = false
+		}
+	}
+	return si, nil
+}
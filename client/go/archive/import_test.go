@@ -0,0 +1,231 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// fakeDestination is an in-memory Destination, which bumps the minor version for every new schema.
+// It fails to register the schema with the definition failOn.
+type fakeDestination struct {
+	subjects   map[string]*model.Subject
+	configs    map[string]model.Config
+	schemas    map[string][]model.Schema
+	failOn     string
+	registered int
+}
+
+func newFakeDestination() *fakeDestination {
+	return &fakeDestination{
+		subjects: make(map[string]*model.Subject),
+		configs:  make(map[string]model.Config),
+		schemas:  make(map[string][]model.Schema),
+	}
+}
+
+var errNotFound = model.NewError(&model.ServerError{ErrorCode: 404, Message: "Not Found"}, nil)
+
+func (fd *fakeDestination) GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error) {
+	if s, ok := fd.subjects[name]; ok {
+		return s, nil
+	}
+	return nil, errNotFound
+}
+
+func (fd *fakeDestination) CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	fd.subjects[name] = &model.Subject{Name: name, Description: description}
+	return 0, nil
+}
+
+func (fd *fakeDestination) UpdateDescriptionContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	fd.subjects[name].Description = description
+	return 1, nil
+}
+
+func (fd *fakeDestination) GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error) {
+	config, ok := fd.configs[subject]
+	if !ok {
+		config = model.Config{Compatibility: "NONE"}
+	}
+	return &config, nil
+}
+
+func (fd *fakeDestination) SetConfigContext(ctx context.Context, subject string, config model.Config) (int64, *model.Error) {
+	fd.configs[subject] = config
+	return 1, nil
+}
+
+func (fd *fakeDestination) LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error) {
+	found, _ := fd.LookupAllSchemasContext(ctx, subject, definition)
+	if len(found) == 0 {
+		return nil, errNotFound
+	}
+	return &found[len(found)-1], nil
+}
+
+func (fd *fakeDestination) LookupAllSchemasContext(ctx context.Context, subject, definition string) ([]model.Schema, *model.Error) {
+	found := make([]model.Schema, 0)
+	for _, schema := range fd.schemas[subject] {
+		if schema.Definition == definition {
+			found = append(found, schema)
+		}
+	}
+	return found, nil
+}
+
+func (fd *fakeDestination) RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error) {
+	if definition == fd.failOn {
+		return nil, model.NewError(&model.ServerError{ErrorCode: 503, Message: "Service Unavailable"}, nil)
+	}
+	schemas := fd.schemas[subject]
+	version := model.SemVer{Major: 1}
+	if len(schemas) > 0 {
+		latest := schemas[len(schemas)-1]
+		if latest.Definition == definition {
+			return &model.SchemaId{Id: latest.Id}, nil
+		}
+		version = latest.Version.NextMinor()
+	}
+	fd.registered++
+	schema := model.Schema{Id: int64(100 + fd.registered), Subject: subject, Version: version, Definition: definition}
+	fd.schemas[subject] = append(schemas, schema)
+	return &model.SchemaId{Id: schema.Id}, nil
+}
+
+// registryForImport creates a registry of a subject with schemas of the versions in this order,
+// whose definitions are int, long and int.
+func registryForImport(versions ...model.SemVer) *Registry {
+	s := Subject{Subject: model.Subject{Name: "user-events", Description: "events of users"}, Config: model.Config{Compatibility: "BACKWARD"}}
+	for i, version := range versions {
+		s.Schemas = append(s.Schemas, model.Schema{
+			Id:         int64(i + 1),
+			Subject:    s.Name,
+			Version:    version,
+			Definition: []string{`"int"`, `"long"`, `"int"`}[i],
+		})
+	}
+	return &Registry{Subjects: []Subject{s}}
+}
+
+func TestImport(t *testing.T) {
+	// v1.2.0 has the same definition as v1.0.0, which is reverted after v1.1.0
+	registry := registryForImport(model.SemVer{Major: 1}, model.SemVer{Major: 1, Minor: 1}, model.SemVer{Major: 1, Minor: 2})
+	schemas := registry.Subjects[0].Schemas
+	schemas[0], schemas[2] = schemas[2], schemas[0]
+	destination := newFakeDestination()
+	destination.failOn = `"long"`
+
+	// the first import fails on the way and is resumed
+	if _, err := NewImporter(destination).Import(context.Background(), registry); err == nil {
+		t.Fatalf("Import should fail when registering a schema fails")
+	}
+	if destination.subjects["user-events"] == nil {
+		t.Fatalf("the subject is expected to be created before the failure")
+	}
+	destination.failOn = ""
+
+	report, err := NewImporter(destination).Import(context.Background(), registry)
+	if err != nil {
+		t.Fatalf("Import should not be an error but an error was occurred: %v", err)
+	}
+	imported := report.Subjects[0]
+	if imported.Created || !imported.ConfigUpdated || destination.configs["user-events"].Compatibility != "BACKWARD" {
+		t.Errorf("the existing subject is expected not to be created again and its config is expected to be set but got %+v", imported)
+	}
+	if len(imported.Schemas) != 3 || destination.registered != 3 {
+		t.Fatalf("3 schemas are expected to be registered in order of versions but got %+v", imported.Schemas)
+	}
+	for i, schema := range imported.Schemas {
+		// v1.0.0 has been registered before the failure
+		if schema.Registered != (i > 0) || schema.IsDrifted() {
+			t.Errorf("schema %v is expected to be imported with the same version but got %+v", schema.Version, schema)
+		}
+	}
+	if imported.Schemas[2].SourceId != 3 || imported.Schemas[2].Id != 103 {
+		t.Errorf("v1.2.0 is expected to be registered as a new schema but got %+v", imported.Schemas[2])
+	}
+
+	// importing again changes nothing
+	registry.Subjects[0].Description = "events of users and devices"
+	report, err = NewImporter(destination).Import(context.Background(), registry)
+	if err != nil {
+		t.Fatalf("Import should not be an error but an error was occurred: %v", err)
+	}
+	imported = report.Subjects[0]
+	if imported.Created || imported.ConfigUpdated || !imported.DescriptionUpdated || destination.registered != 3 {
+		t.Errorf("only the description is expected to be updated but got %+v", imported)
+	}
+	for _, schema := range imported.Schemas {
+		if schema.Registered {
+			t.Errorf("schema %v is expected to be skipped but got %+v", schema.Version, schema)
+		}
+	}
+	if drifts := report.Drifts(); len(drifts) != 0 {
+		t.Errorf("no drift is expected but got %+v", drifts)
+	}
+}
+
+func TestImportDrift(t *testing.T) {
+	// the destination bumps the minor version while the archive has v2.0.0
+	registry := registryForImport(model.SemVer{Major: 1}, model.SemVer{Major: 1, Minor: 1}, model.SemVer{Major: 2})
+
+	report, err := NewImporter(newFakeDestination()).Import(context.Background(), registry)
+	if err != nil {
+		t.Fatalf("Import should not be an error but an error was occurred: %v", err)
+	}
+	drifts := report.Drifts()
+	if len(drifts) != 1 || drifts[0].Version != (model.SemVer{Major: 2}) || drifts[0].ActualVersion != (model.SemVer{Major: 1, Minor: 2}) {
+		t.Errorf("v2.0.0 is expected to drift to v1.2.0 but got %+v", drifts)
+	}
+}
+
+func TestImportDriftAgain(t *testing.T) {
+	// the destination already has another schema, so the archived ones drift to v1.1.0 and v1.2.0
+	registry := registryForImport(model.SemVer{Major: 1}, model.SemVer{Major: 2})
+	destination := newFakeDestination()
+	destination.subjects["user-events"] = &model.Subject{Name: "user-events", Description: "events of users"}
+	destination.schemas["user-events"] = []model.Schema{{Id: 50, Subject: "user-events", Version: model.SemVer{Major: 1}, Definition: `"string"`}}
+
+	for run := 1; run <= 3; run++ {
+		report, err := NewImporter(destination).Import(context.Background(), registry)
+		if err != nil {
+			t.Fatalf("Import should not be an error but an error was occurred: %v", err)
+		}
+		if n := len(destination.schemas["user-events"]); n != 3 || destination.registered != 2 {
+			t.Fatalf("import #%d is expected to register the drifted schemas only once but the subject has %d schemas", run, n)
+		}
+		drifts := report.Drifts()
+		if len(drifts) != 2 || drifts[0].ActualVersion != (model.SemVer{Major: 1, Minor: 1}) || drifts[1].ActualVersion != (model.SemVer{Major: 1, Minor: 2}) {
+			t.Errorf("import #%d is expected to report the drifts to v1.1.0 and v1.2.0 but got %+v", run, drifts)
+		}
+		for _, schema := range report.Subjects[0].Schemas {
+			if schema.Registered != (run == 1) {
+				t.Errorf("import #%d is expected to register schema %v only the first time but got %+v", run, schema.Version, schema)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Authenticator adds credentials to every request sent to a typebook server.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type Authenticator interface {
+	Authenticate(ctx context.Context, request *http.Request) error
+}
+
+// RefreshableAuthenticator is an Authenticator which can renew its credentials.
+// When a request is rejected with 401 Unauthorized, Refresh is called and the request is sent once again.
+type RefreshableAuthenticator interface {
+	Authenticator
+	Refresh(ctx context.Context) error
+}
+
+// TokenProvider returns a bearer token to authenticate requests.
+// forceRefresh is true when the token returned previously has been rejected by the server,
+// so that a cached token should not be returned again.
+type TokenProvider func(ctx context.Context, forceRefresh bool) (string, error)
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+// BasicAuth creates an Authenticator which sends static credentials with HTTP basic authentication.
+func BasicAuth(username, password string) Authenticator {
+	return &basicAuth{username, password}
+}
+
+func (ba *basicAuth) Authenticate(ctx context.Context, request *http.Request) error {
+	request.SetBasicAuth(ba.username, ba.password)
+	return nil
+}
+
+type bearerToken string
+
+// BearerToken creates an Authenticator which sends a static token in `Authorization: Bearer` header.
+func BearerToken(token string) Authenticator {
+	return bearerToken(token)
+}
+
+func (bt bearerToken) Authenticate(ctx context.Context, request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+string(bt))
+	return nil
+}
+
+type tokenSource struct {
+	provider TokenProvider
+
+	mu    sync.Mutex
+	token string
+}
+
+// TokenSource creates a RefreshableAuthenticator which sends a token obtained from the given provider
+// in `Authorization: Bearer` header.
+// The token is cached until the server rejects it with 401 Unauthorized.
+func TokenSource(provider TokenProvider) RefreshableAuthenticator {
+	return &tokenSource{provider: provider}
+}
+
+func (ts *tokenSource) Authenticate(ctx context.Context, request *http.Request) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token == "" {
+		token, err := ts.provider(ctx, false)
+		if err != nil {
+			return err
+		}
+		ts.token = token
+	}
+	request.Header.Set("Authorization", "Bearer "+ts.token)
+	return nil
+}
+
+func (ts *tokenSource) Refresh(ctx context.Context) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	token, err := ts.provider(ctx, true)
+	if err != nil {
+		return err
+	}
+	ts.token = token
+	return nil
+}
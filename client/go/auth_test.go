@@ -0,0 +1,122 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func newAuthTestServer(authorized func(r *http.Request) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`["` + subject + `"]`))
+	}))
+}
+
+func TestBasicAuth(t *testing.T) {
+	server := newAuthTestServer(func(r *http.Request) bool {
+		username, password, ok := r.BasicAuth()
+		return ok && username == "user" && password == "secret"
+	})
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).ListSubjects(); err == nil || err.ServerError == nil || err.ErrorCode != http.StatusUnauthorized {
+		t.Errorf("ListSubjects() without credentials should be an error with code 401, but %v", err)
+	}
+	if _, err := NewClient(server.URL, WithAuth(BasicAuth("user", "secret"))).ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	server := newAuthTestServer(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer token"
+	})
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, WithAuth(BearerToken("token"))).ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("token-1")
+	server := newAuthTestServer(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer "+validToken.Load().(string)
+	})
+	defer server.Close()
+
+	var issued, refreshed int32
+	provider := func(ctx context.Context, forceRefresh bool) (string, error) {
+		if forceRefresh {
+			atomic.AddInt32(&refreshed, 1)
+		}
+		return fmt.Sprintf("token-%d", atomic.AddInt32(&issued, 1)), nil
+	}
+	client := NewClient(server.URL, WithAuth(TokenSource(provider)))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListSubjects(); err != nil {
+			t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+		}
+	}
+	if issued != 1 || refreshed != 0 {
+		t.Errorf("a token should be cached, but issued %d times and refreshed %d times", issued, refreshed)
+	}
+
+	// the server rotates a token
+	validToken.Store("token-2")
+	if _, err := client.ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error after refreshing a token. But an error was occurred: %v", err)
+	}
+	if issued != 2 || refreshed != 1 {
+		t.Errorf("a token should be refreshed once, but issued %d times and refreshed %d times", issued, refreshed)
+	}
+
+	// the token provider can not issue a valid token any more
+	validToken.Store("revoked")
+	if _, err := client.ListSubjects(); err == nil || err.ServerError == nil || err.ErrorCode != http.StatusUnauthorized {
+		t.Errorf("ListSubjects() with an invalid token should be an error with code 401, but %v", err)
+	}
+}
+
+func TestWithHeader(t *testing.T) {
+	server := newAuthTestServer(func(r *http.Request) bool {
+		return r.Header.Get("X-Api-Key") == "key" && len(r.Header["X-Tenant"]) == 2
+	})
+	defer server.Close()
+
+	client := NewClient(server.URL, WithHeader("X-Api-Key", "key"), WithHeader("X-Tenant", "a"), WithHeader("X-Tenant", "b"))
+	if _, err := client.ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	}
+}
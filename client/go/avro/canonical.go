@@ -0,0 +1,113 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// CanonicalForm returns the Parsing Canonical Form of the schema defined by the Avro specification.
+// Two schemas have the same canonical form if and only if they are the same in terms of encoding,
+// regardless of attributes such as doc, aliases, defaults, logical types, the order of attributes or whitespaces.
+func CanonicalForm(s Schema) string {
+	buf := new(bytes.Buffer)
+	writeCanonical(buf, s, make(map[string]bool))
+	return buf.String()
+}
+
+func writeCanonical(buf *bytes.Buffer, s Schema, defined map[string]bool) {
+	if named, ok := s.(NamedSchema); ok {
+		if defined[named.FullName()] {
+			buf.WriteString(strconv.Quote(named.FullName()))
+			return
+		}
+		defined[named.FullName()] = true
+	}
+
+	switch s := s.(type) {
+	case *PrimitiveSchema:
+		buf.WriteString(strconv.Quote(string(s.Primitive)))
+	case *RecordSchema:
+		buf.WriteString(`{"name":` + strconv.Quote(s.Name) + `,"type":"record","fields":[`)
+		for i, f := range s.Fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"name":` + strconv.Quote(f.Name) + `,"type":`)
+			writeCanonical(buf, f.Type, defined)
+			buf.WriteByte('}')
+		}
+		buf.WriteString(`]}`)
+	case *EnumSchema:
+		buf.WriteString(`{"name":` + strconv.Quote(s.Name) + `,"type":"enum","symbols":[`)
+		for i, symbol := range s.Symbols {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Quote(symbol))
+		}
+		buf.WriteString(`]}`)
+	case *FixedSchema:
+		buf.WriteString(`{"name":` + strconv.Quote(s.Name) + `,"type":"fixed","size":` + strconv.Itoa(s.Size) + `}`)
+	case *ArraySchema:
+		buf.WriteString(`{"type":"array","items":`)
+		writeCanonical(buf, s.Items, defined)
+		buf.WriteByte('}')
+	case *MapSchema:
+		buf.WriteString(`{"type":"map","values":`)
+		writeCanonical(buf, s.Values, defined)
+		buf.WriteByte('}')
+	case *UnionSchema:
+		buf.WriteByte('[')
+		for i, t := range s.Types {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonical(buf, t, defined)
+		}
+		buf.WriteByte(']')
+	}
+}
+
+const emptyFingerprint uint64 = 0xc15d213aa4d7a795
+
+var fingerprintTable = func() (table [256]uint64) {
+	for i := range table {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (emptyFingerprint & -(fp & 1))
+		}
+		table[i] = fp
+	}
+	return
+}()
+
+// Fingerprint returns the 64-bit Rabin fingerprint (CRC-64-AVRO) of the Parsing Canonical Form of the schema.
+func Fingerprint(s Schema) uint64 {
+	fp := emptyFingerprint
+	for _, b := range []byte(CanonicalForm(s)) {
+		fp = (fp >> 8) ^ fingerprintTable[byte(fp)^b]
+	}
+	return fp
+}
@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"testing"
+)
+
+func TestCanonicalForm(t *testing.T) {
+	cases := []struct {
+		definition string
+		expect     string
+	}{
+		{`{"type": "int", "logicalType": "date"}`, `"int"`},
+		{`["null", {"type": "string"}]`, `["null","string"]`},
+		{
+			`{"type": "record", "namespace": "com.example", "name": "Node", "doc": "a node", "aliases": ["Vertex"], "fields": [
+			  {"name": "value", "type": {"type": "fixed", "name": "Hash", "size": 16}, "doc": "hash"},
+			  {"name": "kind", "type": {"type": "enum", "name": "org.example.Kind", "symbols": ["A", "B"]}, "default": "A"},
+			  {"name": "children", "type": {"type": "array", "items": "Node"}, "default": []},
+			  {"name": "attributes", "type": {"values": "Hash", "type": "map"}}
+			]}`,
+			`{"name":"com.example.Node","type":"record","fields":[` +
+				`{"name":"value","type":{"name":"com.example.Hash","type":"fixed","size":16}},` +
+				`{"name":"kind","type":{"name":"org.example.Kind","type":"enum","symbols":["A","B"]}},` +
+				`{"name":"children","type":{"type":"array","items":"com.example.Node"}},` +
+				`{"name":"attributes","type":{"type":"map","values":"com.example.Hash"}}]}`,
+		},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.definition)
+		if err != nil {
+			t.Errorf("Parse(%s) should not be an error. But an error was occurred: %v", c.definition, err)
+			continue
+		}
+		if actual := CanonicalForm(s); actual != c.expect {
+			t.Errorf("CanonicalForm(%s) = %s, wants %s", c.definition, actual, c.expect)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	// taken from the test suite of the Avro specification
+	cases := []struct {
+		definition string
+		expect     int64
+	}{
+		{`"null"`, 7195948357588979594},
+		{`{"type": "boolean"}`, -6970731678124411036},
+		{`"int"`, 8247732601305521295},
+		{`"long"`, -3434872931120570953},
+		{`"float"`, 5583340709985441680},
+		{`"double"`, -8181574048448539266},
+		{`"bytes"`, 5746618253357095269},
+		{`"string"`, -8142146995180207161},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.definition)
+		if err != nil {
+			t.Errorf("Parse(%s) should not be an error. But an error was occurred: %v", c.definition, err)
+			continue
+		}
+		if actual := int64(Fingerprint(s)); actual != c.expect {
+			t.Errorf("Fingerprint(%s) = %d, wants %d", c.definition, actual, c.expect)
+		}
+	}
+}
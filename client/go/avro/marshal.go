@@ -0,0 +1,277 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Marshal returns the JSON representation of the schema, which Parse parses into the same AST.
+// Unlike CanonicalForm, it keeps attributes such as doc, aliases, defaults, orders and logical types.
+// A named type is defined at its first occurrence and referred to by its full name after that.
+func Marshal(s Schema) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := writeSchema(buf, s, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeSchema(buf *bytes.Buffer, s Schema, defined map[string]bool) error {
+	if named, ok := s.(NamedSchema); ok {
+		if defined[named.FullName()] {
+			buf.WriteString(jsonString(named.FullName()))
+			return nil
+		}
+		defined[named.FullName()] = true
+	}
+
+	switch s := s.(type) {
+	case *PrimitiveSchema:
+		if s.LogicalType == nil {
+			buf.WriteString(jsonString(string(s.Primitive)))
+			return nil
+		}
+		buf.WriteString(`{"type":` + jsonString(string(s.Primitive)))
+		writeLogicalType(buf, s.LogicalType)
+		buf.WriteByte('}')
+	case *RecordSchema:
+		typ := "record"
+		if s.IsError {
+			typ = "error"
+		}
+		buf.WriteString(`{"type":` + jsonString(typ) + `,"name":` + jsonString(s.Name))
+		writeNamedAttributes(buf, s.Aliases, s.Doc)
+		buf.WriteString(`,"fields":[`)
+		for i, f := range s.Fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"name":` + jsonString(f.Name) + `,"type":`)
+			if err := writeSchema(buf, f.Type, defined); err != nil {
+				return err
+			}
+			writeNamedAttributes(buf, f.Aliases, f.Doc)
+			if f.HasDefault {
+				v, err := jsonDefault(f.Type, f.Default)
+				if err != nil {
+					return fmt.Errorf("default value of field `%s` of `%s`: %v", f.Name, s.Name, err)
+				}
+				buf.WriteString(`,"default":` + v)
+			}
+			if f.Order != "" {
+				buf.WriteString(`,"order":` + jsonString(f.Order))
+			}
+			buf.WriteByte('}')
+		}
+		buf.WriteString(`]}`)
+	case *EnumSchema:
+		buf.WriteString(`{"type":"enum","name":` + jsonString(s.Name))
+		writeNamedAttributes(buf, s.Aliases, s.Doc)
+		buf.WriteString(`,"symbols":` + jsonString(s.Symbols))
+		if s.Default != "" {
+			buf.WriteString(`,"default":` + jsonString(s.Default))
+		}
+		buf.WriteByte('}')
+	case *FixedSchema:
+		buf.WriteString(`{"type":"fixed","name":` + jsonString(s.Name))
+		writeNamedAttributes(buf, s.Aliases, "")
+		buf.WriteString(`,"size":` + strconv.Itoa(s.Size))
+		writeLogicalType(buf, s.LogicalType)
+		buf.WriteByte('}')
+	case *ArraySchema:
+		buf.WriteString(`{"type":"array","items":`)
+		if err := writeSchema(buf, s.Items, defined); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+	case *MapSchema:
+		buf.WriteString(`{"type":"map","values":`)
+		if err := writeSchema(buf, s.Values, defined); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+	case *UnionSchema:
+		buf.WriteByte('[')
+		for i, t := range s.Types {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeSchema(buf, t, defined); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	}
+	return nil
+}
+
+func writeNamedAttributes(buf *bytes.Buffer, aliases []string, doc string) {
+	if len(aliases) > 0 {
+		buf.WriteString(`,"aliases":` + jsonString(aliases))
+	}
+	if doc != "" {
+		buf.WriteString(`,"doc":` + jsonString(doc))
+	}
+}
+
+func writeLogicalType(buf *bytes.Buffer, lt *LogicalType) {
+	if lt == nil {
+		return
+	}
+	buf.WriteString(`,"logicalType":` + jsonString(lt.Name))
+	if lt.Name == "decimal" {
+		buf.WriteString(`,"precision":` + strconv.Itoa(lt.Precision) + `,"scale":` + strconv.Itoa(lt.Scale))
+	}
+}
+
+// MarshalDefault returns the JSON of a default value of s, which is a native Go value as Field.Default.
+func MarshalDefault(s Schema, v interface{}) (string, error) {
+	return jsonDefault(s, v)
+}
+
+// jsonDefault converts a native Go value of a default into JSON. This is the reverse of nativeDefault.
+func jsonDefault(s Schema, v interface{}) (string, error) {
+	invalid := fmt.Errorf("%v is not a valid %s", v, TypeName(s))
+	switch s := s.(type) {
+	case *PrimitiveSchema:
+		switch s.Primitive {
+		case Null:
+			if v == nil {
+				return "null", nil
+			}
+		case Boolean:
+			if b, ok := v.(bool); ok {
+				return strconv.FormatBool(b), nil
+			}
+		case Int:
+			if n, ok := v.(int32); ok {
+				return strconv.FormatInt(int64(n), 10), nil
+			}
+		case Long:
+			if n, ok := v.(int64); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+		case Float:
+			if f, ok := v.(float32); ok && !math.IsInf(float64(f), 0) && !math.IsNaN(float64(f)) {
+				return strconv.FormatFloat(float64(f), 'g', -1, 32), nil
+			}
+		case Double:
+			if f, ok := v.(float64); ok && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return strconv.FormatFloat(f, 'g', -1, 64), nil
+			}
+		case String:
+			if str, ok := v.(string); ok {
+				return jsonString(str), nil
+			}
+		case Bytes:
+			if b, ok := v.([]byte); ok {
+				return jsonString(codePoints(b)), nil
+			}
+		}
+	case *FixedSchema:
+		if b, ok := v.([]byte); ok {
+			return jsonString(codePoints(b)), nil
+		}
+	case *EnumSchema:
+		if symbol, ok := v.(string); ok {
+			return jsonString(symbol), nil
+		}
+	case *ArraySchema:
+		if array, ok := v.([]interface{}); ok {
+			buf := new(bytes.Buffer)
+			buf.WriteByte('[')
+			for i, item := range array {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				js, err := jsonDefault(s.Items, item)
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(js)
+			}
+			buf.WriteByte(']')
+			return buf.String(), nil
+		}
+	case *MapSchema:
+		if m, ok := v.(map[string]interface{}); ok {
+			return jsonObject(m, func(string) Schema { return s.Values })
+		}
+	case *RecordSchema:
+		if m, ok := v.(map[string]interface{}); ok {
+			return jsonObject(m, func(name string) Schema {
+				if f := s.Field(name); f != nil {
+					return f.Type
+				}
+				return nil
+			})
+		}
+	case *UnionSchema:
+		if len(s.Types) > 0 {
+			return jsonDefault(s.Types[0], v)
+		}
+	}
+	return "", invalid
+}
+
+// jsonObject converts m into a JSON object with sorted keys, whose values are of the types returned by typeOf.
+func jsonObject(m map[string]interface{}, typeOf func(string) Schema) (string, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, key := range keys {
+		s := typeOf(key)
+		if s == nil {
+			return "", fmt.Errorf("`%s` is not a field", key)
+		}
+		js, err := jsonDefault(s, m[key])
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(jsonString(key) + ":" + js)
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+// codePoints converts bytes into a string whose code points are the bytes, as defaults of bytes and fixed are written in JSON.
+func codePoints(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
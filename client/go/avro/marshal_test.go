@@ -0,0 +1,92 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	definition := `{
+	  "type": "record",
+	  "name": "Person",
+	  "namespace": "com.example",
+	  "doc": "a person",
+	  "aliases": ["Human"],
+	  "fields": [
+	    {"name": "id", "type": "long", "default": 9007199254740993},
+	    {"name": "birthday", "type": {"type": "int", "logicalType": "date"}},
+	    {"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}, "default": "\u0000ÿ"},
+	    {"name": "email", "type": ["null", "string"], "default": null, "aliases": ["mail"], "doc": "an address"},
+	    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "INACTIVE"], "default": "ACTIVE"}, "order": "descending"},
+	    {"name": "tags", "type": {"type": "map", "values": {"type": "array", "items": "string"}}, "default": {"a": ["b"]}},
+	    {"name": "friend", "type": ["null", "Person"], "default": null},
+	    {"name": "address", "type": {"type": "record", "name": "Address", "namespace": "com.example.geo", "fields": [
+	      {"name": "zip", "type": {"type": "fixed", "name": "Zip", "size": 2}, "default": "\u0001\u0002"}
+	    ]}, "default": {"zip": "\u0001\u0002"}},
+	    {"name": "home", "type": "com.example.geo.Address"}
+	  ]
+	}`
+	s, err := Parse(definition)
+	if err != nil {
+		t.Fatalf("Parse should not be an error. But an error was occurred: %v", err)
+	}
+
+	b, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal should not be an error. But an error was occurred: %v", err)
+	}
+	reparsed, err := Parse(string(b))
+	if err != nil {
+		t.Fatalf("Marshal should return a valid schema. But it cannot be parsed: %v\n%s", err, b)
+	}
+	if CanonicalForm(reparsed) != CanonicalForm(s) {
+		t.Errorf("Marshal = %s, wants the same canonical form as %s", CanonicalForm(reparsed), CanonicalForm(s))
+	}
+
+	person := reparsed.(*RecordSchema)
+	if person.Doc != "a person" || len(person.Aliases) != 1 {
+		t.Errorf("doc and aliases of the record = %q %v, wants \"a person\" [com.example.Human]", person.Doc, person.Aliases)
+	}
+	if f := person.Field("id"); f.Default != int64(9007199254740993) {
+		t.Errorf("default of id = %v, wants 9007199254740993", f.Default)
+	}
+	if f := person.Field("balance"); !bytes.Equal(f.Default.([]byte), []byte{0x00, 0xff}) {
+		t.Errorf("default of balance = %v, wants [0 255]", f.Default)
+	}
+	if f := person.Field("email"); f.Doc != "an address" || len(f.Aliases) != 1 || !f.HasDefault || f.Default != nil {
+		t.Errorf("email = %+v, wants a doc, an alias and the default null", f)
+	}
+	if f := person.Field("status"); f.Order != "descending" {
+		t.Errorf("order of status = %q, wants descending", f.Order)
+	}
+	if f := person.Field("birthday"); f.Type.(*PrimitiveSchema).LogicalType == nil {
+		t.Errorf("birthday should keep its logical type")
+	}
+
+	again, err := Marshal(reparsed)
+	if err != nil || !bytes.Equal(again, b) {
+		t.Errorf("Marshal should be stable. But got %s, wants %s", again, b)
+	}
+}
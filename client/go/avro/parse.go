@@ -0,0 +1,495 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parse parses an Avro schema in JSON into its AST.
+// It returns an error if the definition is not a valid Avro schema, e.g. it refers to an undefined type,
+// it has an invalid name, a duplicate field or symbol, or a default value which does not conform to its type.
+func Parse(definition string) (Schema, error) {
+	decoder := json.NewDecoder(strings.NewReader(definition))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid schema: unexpected data after the schema")
+	}
+
+	p := &parser{named: make(map[string]NamedSchema)}
+	s, err := p.parse(v, "")
+	if err != nil {
+		return nil, err
+	}
+	// defaults are validated after all types are defined since a default of a record may refer to the record itself
+	for _, d := range p.defaults {
+		if d.field.Default, err = nativeDefault(d.field.Type, d.value); err != nil {
+			return nil, fmt.Errorf("invalid schema: default value of field `%s` of `%s`: %v", d.field.Name, d.record, err)
+		}
+	}
+	return s, nil
+}
+
+type parser struct {
+	named    map[string]NamedSchema
+	defaults []pendingDefault
+}
+
+type pendingDefault struct {
+	record string
+	field  *Field
+	value  interface{}
+}
+
+func (p *parser) parse(v interface{}, namespace string) (Schema, error) {
+	switch t := v.(type) {
+	case string:
+		return p.parseName(t, namespace)
+	case []interface{}:
+		return p.parseUnion(t, namespace)
+	case map[string]interface{}:
+		return p.parseObject(t, namespace)
+	default:
+		return nil, fmt.Errorf("invalid schema: unexpected %v", v)
+	}
+}
+
+func (p *parser) parseName(name, namespace string) (Schema, error) {
+	if t := Type(name); t.IsPrimitive() {
+		return &PrimitiveSchema{Primitive: t}, nil
+	}
+	if s, ok := p.named[fullName(name, namespace)]; ok {
+		return s, nil
+	}
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("invalid schema: undefined type `%s`", name)
+}
+
+func (p *parser) parseUnion(types []interface{}, namespace string) (Schema, error) {
+	union := new(UnionSchema)
+	seen := make(map[string]bool)
+	for _, t := range types {
+		s, err := p.parse(t, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if s.Type() == Union {
+			return nil, fmt.Errorf("invalid schema: a union may not immediately contain another union")
+		}
+		name := TypeName(s)
+		if seen[name] {
+			return nil, fmt.Errorf("invalid schema: a union contains `%s` more than once", name)
+		}
+		seen[name] = true
+		union.Types = append(union.Types, s)
+	}
+	return union, nil
+}
+
+func (p *parser) parseObject(m map[string]interface{}, namespace string) (Schema, error) {
+	typ, ok := m["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid schema: `type` is not a string in %v", m)
+	}
+
+	switch Type(typ) {
+	case Record, Enum, Fixed:
+		return p.parseNamed(Type(typ), m, namespace)
+	case "error":
+		return p.parseNamed(Record, m, namespace)
+	case Array:
+		items, ok := m["items"]
+		if !ok {
+			return nil, fmt.Errorf("invalid schema: `items` is missing in an array")
+		}
+		s, err := p.parse(items, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &ArraySchema{Items: s}, nil
+	case Map:
+		values, ok := m["values"]
+		if !ok {
+			return nil, fmt.Errorf("invalid schema: `values` is missing in a map")
+		}
+		s, err := p.parse(values, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &MapSchema{Values: s}, nil
+	}
+
+	s, err := p.parseName(typ, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if primitive, ok := s.(*PrimitiveSchema); ok {
+		primitive.LogicalType = logicalTypeOf(m, primitive.Primitive, 0)
+	}
+	return s, nil
+}
+
+func (p *parser) parseNamed(typ Type, m map[string]interface{}, namespace string) (Schema, error) {
+	name, _ := m["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("invalid schema: `name` of a %s is missing", typ)
+	}
+	if ns, ok := m["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	full := fullName(name, namespace)
+	if err := validateFullName(full); err != nil {
+		return nil, err
+	}
+	if _, ok := p.named[full]; ok {
+		return nil, fmt.Errorf("invalid schema: `%s` is defined more than once", full)
+	}
+	aliases, err := p.aliases(m, Namespace(full))
+	if err != nil {
+		return nil, err
+	}
+	doc, _ := m["doc"].(string)
+
+	switch typ {
+	case Record:
+		record := &RecordSchema{Name: full, Aliases: aliases, Doc: doc, IsError: m["type"] == "error"}
+		// defined before its fields are parsed so that they can refer to the record
+		p.named[full] = record
+		return record, p.parseFields(record, m)
+	case Enum:
+		enum := &EnumSchema{Name: full, Aliases: aliases, Doc: doc}
+		symbols, ok := m["symbols"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid schema: `symbols` of `%s` is not an array", full)
+		}
+		for _, s := range symbols {
+			symbol, ok := s.(string)
+			if !ok || !namePattern.MatchString(symbol) {
+				return nil, fmt.Errorf("invalid schema: `%v` is not a valid symbol of `%s`", s, full)
+			}
+			if enum.HasSymbol(symbol) {
+				return nil, fmt.Errorf("invalid schema: `%s` is defined more than once in `%s`", symbol, full)
+			}
+			enum.Symbols = append(enum.Symbols, symbol)
+		}
+		if def, ok := m["default"]; ok {
+			if symbol, ok := def.(string); !ok || !enum.HasSymbol(symbol) {
+				return nil, fmt.Errorf("invalid schema: default `%v` of `%s` is not one of its symbols", def, full)
+			}
+			enum.Default = def.(string)
+		}
+		p.named[full] = enum
+		return enum, nil
+	default:
+		size, err := integerOf(m["size"])
+		if err != nil || size < 0 || size > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid schema: `size` of `%s` is not a non-negative integer", full)
+		}
+		fixed := &FixedSchema{Name: full, Aliases: aliases, Size: int(size)}
+		fixed.LogicalType = logicalTypeOf(m, Fixed, fixed.Size)
+		p.named[full] = fixed
+		return fixed, nil
+	}
+}
+
+func (p *parser) parseFields(record *RecordSchema, m map[string]interface{}) error {
+	fields, ok := m["fields"].([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid schema: `fields` of `%s` is not an array", record.Name)
+	}
+	for _, f := range fields {
+		fm, ok := f.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid schema: a field of `%s` is not an object", record.Name)
+		}
+		name, _ := fm["name"].(string)
+		if !namePattern.MatchString(name) {
+			return fmt.Errorf("invalid schema: `%s` is not a valid field name of `%s`", name, record.Name)
+		}
+		if record.Field(name) != nil {
+			return fmt.Errorf("invalid schema: field `%s` is defined more than once in `%s`", name, record.Name)
+		}
+		typ, ok := fm["type"]
+		if !ok {
+			return fmt.Errorf("invalid schema: `type` of field `%s` of `%s` is missing", name, record.Name)
+		}
+		s, err := p.parse(typ, Namespace(record.Name))
+		if err != nil {
+			return err
+		}
+		aliases, err := p.aliases(fm, "")
+		if err != nil {
+			return err
+		}
+		doc, _ := fm["doc"].(string)
+		order, _ := fm["order"].(string)
+		switch order {
+		case "", "ascending", "descending", "ignore":
+		default:
+			return fmt.Errorf("invalid schema: `%s` is not a valid order of field `%s` of `%s`", order, name, record.Name)
+		}
+
+		field := &Field{Name: name, Aliases: aliases, Doc: doc, Type: s, Order: order}
+		if def, ok := fm["default"]; ok {
+			field.HasDefault = true
+			p.defaults = append(p.defaults, pendingDefault{record: record.Name, field: field, value: def})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return nil
+}
+
+// aliases returns the aliases in m qualified with namespace. Aliases of fields are given an empty namespace.
+func (p *parser) aliases(m map[string]interface{}, namespace string) ([]string, error) {
+	v, ok := m["aliases"]
+	if !ok {
+		return nil, nil
+	}
+	array, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid schema: `aliases` is not an array in %v", m)
+	}
+	aliases := make([]string, 0, len(array))
+	for _, a := range array {
+		alias, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid schema: alias `%v` is not a string", a)
+		}
+		alias = fullName(alias, namespace)
+		if err := validateFullName(alias); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, nil
+}
+
+// logicalTypeOf returns the logical type annotated in m if it is valid for the underlying type, otherwise nil.
+func logicalTypeOf(m map[string]interface{}, typ Type, size int) *LogicalType {
+	name, _ := m["logicalType"].(string)
+	switch name {
+	case "decimal":
+		precision, err := integerOf(m["precision"])
+		if err != nil || precision <= 0 || (typ != Bytes && typ != Fixed) {
+			return nil
+		}
+		scale := int64(0)
+		if v, ok := m["scale"]; ok {
+			if scale, err = integerOf(v); err != nil {
+				return nil
+			}
+		}
+		if scale < 0 || scale > precision || (typ == Fixed && precision > maxDecimalPrecision(size)) {
+			return nil
+		}
+		return &LogicalType{Name: name, Precision: int(precision), Scale: int(scale)}
+	case "uuid":
+		if typ == String {
+			return &LogicalType{Name: name}
+		}
+	case "date", "time-millis":
+		if typ == Int {
+			return &LogicalType{Name: name}
+		}
+	case "time-micros", "timestamp-millis", "timestamp-micros", "local-timestamp-millis", "local-timestamp-micros":
+		if typ == Long {
+			return &LogicalType{Name: name}
+		}
+	case "duration":
+		if typ == Fixed && size == 12 {
+			return &LogicalType{Name: name}
+		}
+	}
+	return nil
+}
+
+// maxDecimalPrecision returns the number of digits that a signed integer of size bytes can hold.
+func maxDecimalPrecision(size int) int64 {
+	if size <= 0 {
+		return 0
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), uint(8*size-1))
+	max.Sub(max, big.NewInt(1))
+	return int64(len(max.String()) - 1)
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func validateFullName(full string) error {
+	for _, part := range strings.Split(full, ".") {
+		if !namePattern.MatchString(part) {
+			return fmt.Errorf("invalid schema: `%s` is not a valid name", full)
+		}
+	}
+	if Type(ShortName(full)).IsPrimitive() {
+		return fmt.Errorf("invalid schema: a primitive type name `%s` cannot be defined", full)
+	}
+	return nil
+}
+
+func integerOf(v interface{}) (int64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+	return n.Int64()
+}
+
+// nativeDefault validates a default value in JSON against s and converts it into a native Go value.
+func nativeDefault(s Schema, v interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%s is not a valid %s", jsonString(v), TypeName(s))
+	switch s := s.(type) {
+	case *PrimitiveSchema:
+		switch s.Primitive {
+		case Null:
+			if v == nil {
+				return nil, nil
+			}
+		case Boolean:
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+		case Int:
+			if n, err := integerOf(v); err == nil && n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int32(n), nil
+			}
+		case Long:
+			if n, err := integerOf(v); err == nil {
+				return n, nil
+			}
+		case Float, Double:
+			if n, ok := v.(json.Number); ok {
+				if f, err := n.Float64(); err == nil {
+					if s.Primitive == Float {
+						return float32(f), nil
+					}
+					return f, nil
+				}
+			}
+		case String:
+			if str, ok := v.(string); ok {
+				return str, nil
+			}
+		case Bytes:
+			return bytesOf(v, -1, invalid)
+		}
+	case *FixedSchema:
+		return bytesOf(v, s.Size, invalid)
+	case *EnumSchema:
+		if symbol, ok := v.(string); ok && s.HasSymbol(symbol) {
+			return symbol, nil
+		}
+	case *ArraySchema:
+		if array, ok := v.([]interface{}); ok {
+			items := make([]interface{}, len(array))
+			for i, item := range array {
+				var err error
+				if items[i], err = nativeDefault(s.Items, item); err != nil {
+					return nil, err
+				}
+			}
+			return items, nil
+		}
+	case *MapSchema:
+		if m, ok := v.(map[string]interface{}); ok {
+			values := make(map[string]interface{}, len(m))
+			for key, value := range m {
+				var err error
+				if values[key], err = nativeDefault(s.Values, value); err != nil {
+					return nil, err
+				}
+			}
+			return values, nil
+		}
+	case *RecordSchema:
+		if m, ok := v.(map[string]interface{}); ok {
+			record := make(map[string]interface{}, len(s.Fields))
+			for _, f := range s.Fields {
+				value, ok := m[f.Name]
+				if !ok {
+					if !f.HasDefault {
+						return nil, fmt.Errorf("field `%s` is missing in %s", f.Name, jsonString(v))
+					}
+					record[f.Name] = f.Default
+					continue
+				}
+				var err error
+				if record[f.Name], err = nativeDefault(f.Type, value); err != nil {
+					return nil, err
+				}
+			}
+			return record, nil
+		}
+	case *UnionSchema:
+		if len(s.Types) > 0 {
+			return nativeDefault(s.Types[0], v)
+		}
+	}
+	return nil, invalid
+}
+
+// bytesOf converts a string whose code points are 0-255 into bytes. If size is not negative, the length should be size.
+func bytesOf(v interface{}, size int, invalid error) ([]byte, error) {
+	str, ok := v.(string)
+	if !ok {
+		return nil, invalid
+	}
+	b := make([]byte, 0, len(str))
+	for _, c := range str {
+		if c > 255 {
+			return nil, invalid
+		}
+		b = append(b, byte(c))
+	}
+	if size >= 0 && len(b) != size {
+		return nil, invalid
+	}
+	return b, nil
+}
+
+func jsonString(v interface{}) string {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,136 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	definition := `{
+	  "type": "record",
+	  "name": "Person",
+	  "namespace": "com.example",
+	  "doc": "a person",
+	  "fields": [
+	    {"name": "id", "type": "long", "default": 9007199254740993},
+	    {"name": "birthday", "type": {"type": "int", "logicalType": "date"}},
+	    {"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+	    {"name": "email", "type": ["null", "string"], "default": null, "aliases": ["mail"]},
+	    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "INACTIVE"], "default": "ACTIVE"}},
+	    {"name": "friend", "type": ["null", "Person"], "default": null},
+	    {"name": "address", "type": {"type": "record", "name": "Address", "namespace": "com.example.geo", "fields": [
+	      {"name": "zip", "type": {"type": "fixed", "name": "Zip", "size": 7}, "default": "1500000"}
+	    ]}, "default": {}},
+	    {"name": "home", "type": "com.example.geo.Address", "default": {"zip": "1000000"}}
+	  ]
+	}`
+	s, err := Parse(definition)
+	if err != nil {
+		t.Fatalf("Parse should not be an error. But an error was occurred: %v", err)
+	}
+
+	person, ok := s.(*RecordSchema)
+	if !ok || person.Name != "com.example.Person" || person.Doc != "a person" || len(person.Fields) != 8 {
+		t.Fatalf("Parse = %v, wants a record com.example.Person with 8 fields", s)
+	}
+	if f := person.Field("id"); f.Default != int64(9007199254740993) {
+		t.Errorf("default of id = %v, wants 9007199254740993", f.Default)
+	}
+	if f := person.Field("birthday"); !reflect.DeepEqual(f.Type, &PrimitiveSchema{Primitive: Int, LogicalType: &LogicalType{Name: "date"}}) {
+		t.Errorf("type of birthday = %v, wants int with date", f.Type)
+	}
+	if f := person.Field("balance"); f.Type.(*PrimitiveSchema).LogicalType.Precision != 10 {
+		t.Errorf("type of balance = %v, wants decimal(10, 2)", f.Type)
+	}
+	if f := person.Field("email"); !f.HasDefault || f.Default != nil || !reflect.DeepEqual(f.Aliases, []string{"mail"}) {
+		t.Errorf("email = %v, wants a nullable field with aliases", f)
+	}
+	if f := person.Field("status"); TypeName(f.Type) != "com.example.Status" {
+		t.Errorf("type of status = %s, wants com.example.Status", TypeName(f.Type))
+	}
+	if f := person.Field("friend"); f.Type.(*UnionSchema).Types[1] != person {
+		t.Errorf("friend should refer to Person itself")
+	}
+	address := person.Field("address").Type
+	if TypeName(address) != "com.example.geo.Address" || person.Field("home").Type != address {
+		t.Errorf("home should refer to com.example.geo.Address, but %s", TypeName(person.Field("home").Type))
+	}
+	if f := person.Field("address"); !reflect.DeepEqual(f.Default, map[string]interface{}{"zip": []byte("1500000")}) {
+		t.Errorf("default of address = %v, wants it filled with the default of zip", f.Default)
+	}
+}
+
+func TestParseInvalidSchemas(t *testing.T) {
+	cases := []string{
+		`{"type": "record", "name": "R"`,
+		`"Unknown"`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "Unknown"}]}`,
+		`{"type": "record", "name": "1R", "fields": []}`,
+		`{"type": "record", "name": "int", "fields": []}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "int"}, {"name": "f", "type": "long"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "int", "default": "zero"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": ["null", "int"], "default": 1}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "int", "order": "random"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": {"type": "enum", "name": "E", "symbols": []}}, {"name": "b", "type": {"type": "fixed", "name": "E", "size": 1}}]}`,
+		`{"type": "enum", "name": "E", "symbols": ["A", "A"]}`,
+		`{"type": "enum", "name": "E", "symbols": ["A"], "default": "B"}`,
+		`{"type": "fixed", "name": "F", "size": -1}`,
+		`{"type": "fixed", "name": "F", "size": 1.5}`,
+		`{"type": "array"}`,
+		`["int", "int"]`,
+		`["null", ["int"]]`,
+	}
+	for _, definition := range cases {
+		if _, err := Parse(definition); err == nil {
+			t.Errorf("Parse(%s) should be an error. But no error was occurred", definition)
+		}
+	}
+}
+
+func TestParseIgnoresInvalidLogicalTypes(t *testing.T) {
+	cases := []string{
+		`{"type": "string", "logicalType": "date"}`,
+		`{"type": "bytes", "logicalType": "decimal", "precision": 2, "scale": 3}`,
+		`{"type": "fixed", "name": "F", "size": 2, "logicalType": "decimal", "precision": 5}`,
+		`{"type": "long", "logicalType": "unknown"}`,
+	}
+	for _, definition := range cases {
+		s, err := Parse(definition)
+		if err != nil {
+			t.Errorf("Parse(%s) should not be an error. But an error was occurred: %v", definition, err)
+			continue
+		}
+		var logicalType *LogicalType
+		switch s := s.(type) {
+		case *PrimitiveSchema:
+			logicalType = s.LogicalType
+		case *FixedSchema:
+			logicalType = s.LogicalType
+		}
+		if logicalType != nil {
+			t.Errorf("logical type of %s should be ignored, but %v", definition, logicalType)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	ratType      = reflect.TypeOf(big.Rat{})
+)
+
+// SchemaOf derives an Avro schema from the Go type of v, which is typically a struct.
+// It is the reverse of what package serde does to encode Go values, so that values of the type can be serialized with the schema.
+//
+// A struct is derived as a record named after the Go type, or after the full name returned by its AvroRecordName method if it has one.
+// Its exported fields become fields of the record in the same order, which are configured with the following tags.
+// Unlike encoding/json, an embedded struct is not flattened but becomes a field named after its type,
+// while an AvroRecordName method promoted from it names the outer record.
+//
+//	avro:"name,options" the name of the field, or `-` to skip the field. The Go field name is used if it is omitted.
+//	avrodoc:"text"      the doc of the field.
+//	avrodefault:"value" the default value of the field. It is written as is for strings, bytes and enums, otherwise as JSON.
+//
+// Pointers are derived as unions of null and the type pointed, which default to null
+// unless another default is given, in which case null comes last. Slices and arrays become arrays except []byte and [N]byte,
+// which become bytes and fixed, and maps keyed by strings become maps.
+// uint and uint64 are rejected since Avro has no unsigned integer and a long cannot hold all of their values.
+// Structs that have an AvroUnion method, i.e. serde.Union, become unions of null and the types of their pointer fields.
+// time.Time is derived as timestamp-millis, time.Duration as time-micros and big.Rat or *big.Rat as decimal like the code generated by codegen,
+// which can be changed by the options `date`, `timestamp-micros`, `time-millis` and `precision=N`, `scale=N` of the avro tag.
+// A decimal requires its precision.
+func SchemaOf(v interface{}) (Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("schema of nil cannot be derived")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	d := &deriver{records: make(map[reflect.Type]*RecordSchema), named: make(map[string]reflect.Type)}
+	return d.schemaOf(t, t.Name(), tagOptions{})
+}
+
+type deriver struct {
+	records map[reflect.Type]*RecordSchema
+	named   map[string]reflect.Type // Go types of named types by their full names
+}
+
+type tagOptions struct {
+	logicalType string
+	precision   int
+	scale       int
+}
+
+// parseTag returns the name and the options in an avro tag.
+func parseTag(tag string) (string, tagOptions, error) {
+	parts := strings.Split(tag, ",")
+	opts := tagOptions{}
+	for _, opt := range parts[1:] {
+		var err error
+		switch {
+		case opt == "date" || opt == "timestamp-millis" || opt == "timestamp-micros" || opt == "time-millis" || opt == "time-micros":
+			opts.logicalType = opt
+		case strings.HasPrefix(opt, "precision="):
+			opts.precision, err = strconv.Atoi(strings.TrimPrefix(opt, "precision="))
+		case strings.HasPrefix(opt, "scale="):
+			opts.scale, err = strconv.Atoi(strings.TrimPrefix(opt, "scale="))
+		case opt != "":
+			err = fmt.Errorf("unknown option")
+		}
+		if err != nil {
+			return "", opts, fmt.Errorf("invalid option `%s` in tag `%s`", opt, tag)
+		}
+	}
+	return parts[0], opts, nil
+}
+
+// schemaOf derives a schema from t. hint names a type that has no name in Go, such as an anonymous struct or [N]byte.
+func (d *deriver) schemaOf(t reflect.Type, hint string, opts tagOptions) (Schema, error) {
+	switch t {
+	case timeType:
+		switch opts.logicalType {
+		case "", "timestamp-millis":
+			return &PrimitiveSchema{Primitive: Long, LogicalType: &LogicalType{Name: "timestamp-millis"}}, nil
+		case "timestamp-micros":
+			return &PrimitiveSchema{Primitive: Long, LogicalType: &LogicalType{Name: "timestamp-micros"}}, nil
+		case "date":
+			return &PrimitiveSchema{Primitive: Int, LogicalType: &LogicalType{Name: "date"}}, nil
+		}
+		return nil, fmt.Errorf("time.Time cannot be %s", opts.logicalType)
+	case durationType:
+		switch opts.logicalType {
+		case "", "time-micros":
+			return &PrimitiveSchema{Primitive: Long, LogicalType: &LogicalType{Name: "time-micros"}}, nil
+		case "time-millis":
+			return &PrimitiveSchema{Primitive: Int, LogicalType: &LogicalType{Name: "time-millis"}}, nil
+		}
+		return nil, fmt.Errorf("time.Duration cannot be %s", opts.logicalType)
+	case ratType, reflect.PtrTo(ratType):
+		if opts.precision <= 0 || opts.scale < 0 || opts.scale > opts.precision {
+			return nil, fmt.Errorf("big.Rat requires a valid precision and scale, e.g. `avro:\"amount,precision=10,scale=2\"`")
+		}
+		return &PrimitiveSchema{Primitive: Bytes, LogicalType: &LogicalType{Name: "decimal", Precision: opts.precision, Scale: opts.scale}}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &PrimitiveSchema{Primitive: Boolean}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &PrimitiveSchema{Primitive: Int}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return &PrimitiveSchema{Primitive: Long}, nil
+	case reflect.Uint, reflect.Uint64:
+		return nil, fmt.Errorf("%s cannot be derived since a long cannot hold values above %d", t, uint64(math.MaxInt64))
+	case reflect.Float32:
+		return &PrimitiveSchema{Primitive: Float}, nil
+	case reflect.Float64:
+		return &PrimitiveSchema{Primitive: Double}, nil
+	case reflect.String:
+		return &PrimitiveSchema{Primitive: String}, nil
+	case reflect.Ptr:
+		elem, err := d.schemaOf(t.Elem(), hint, opts)
+		if err != nil {
+			return nil, err
+		}
+		if union, ok := elem.(*UnionSchema); ok { // a pointer to a union struct
+			return union, nil
+		}
+		return &UnionSchema{Types: []Schema{&PrimitiveSchema{Primitive: Null}, elem}}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &PrimitiveSchema{Primitive: Bytes}, nil
+		}
+		items, err := d.schemaOf(t.Elem(), hint+"Item", tagOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ArraySchema{Items: items}, nil
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return d.fixedOf(t, hint)
+		}
+		items, err := d.schemaOf(t.Elem(), hint+"Item", tagOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ArraySchema{Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s cannot be a map since its key is not a string", t)
+		}
+		values, err := d.schemaOf(t.Elem(), hint+"Value", tagOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &MapSchema{Values: values}, nil
+	case reflect.Struct:
+		if _, ok := t.MethodByName("AvroUnion"); ok {
+			return d.unionOf(t)
+		}
+		return d.recordOf(t, hint)
+	}
+	return nil, fmt.Errorf("schema of %s cannot be derived", t)
+}
+
+func (d *deriver) fixedOf(t reflect.Type, hint string) (Schema, error) {
+	name := t.Name()
+	if name == "" {
+		name = hint
+	}
+	if err := d.define(name, t); err != nil {
+		return nil, err
+	}
+	return &FixedSchema{Name: name, Size: t.Len()}, nil
+}
+
+// define registers that the named type is derived from t. It fails if the name is taken by another type.
+func (d *deriver) define(name string, t reflect.Type) error {
+	if name == "" {
+		return fmt.Errorf("%s has no name, which should be given by AvroRecordName", t)
+	}
+	if err := validateFullName(name); err != nil {
+		return err
+	}
+	if other, ok := d.named[name]; ok && other != t {
+		return fmt.Errorf("name `%s` is used by both %s and %s", name, other, t)
+	}
+	d.named[name] = t
+	return nil
+}
+
+func (d *deriver) recordOf(t reflect.Type, hint string) (Schema, error) {
+	if record, ok := d.records[t]; ok {
+		return record, nil
+	}
+
+	name := t.Name()
+	if name == "" {
+		name = hint
+	}
+	if named, ok := reflect.New(t).Interface().(interface{ AvroRecordName() string }); ok {
+		name = named.AvroRecordName()
+	}
+	if err := d.define(name, t); err != nil {
+		return nil, err
+	}
+	record := &RecordSchema{Name: name}
+	d.records[t] = record // registered before fields for recursive types
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("avro") == "-" {
+			continue
+		}
+		fieldName, opts, err := parseTag(sf.Tag.Get("avro"))
+		if err != nil {
+			return nil, err
+		}
+		if fieldName == "" {
+			fieldName = sf.Name
+		}
+		if !namePattern.MatchString(fieldName) {
+			return nil, fmt.Errorf("`%s` is not a valid field name of `%s`", fieldName, name)
+		}
+		if record.Field(fieldName) != nil {
+			return nil, fmt.Errorf("field `%s` is defined more than once in `%s`", fieldName, name)
+		}
+
+		s, err := d.schemaOf(sf.Type, ShortName(name)+sf.Name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("field `%s` of `%s`: %v", fieldName, name, err)
+		}
+		field := &Field{Name: fieldName, Type: s, Doc: sf.Tag.Get("avrodoc")}
+		if raw, ok := sf.Tag.Lookup("avrodefault"); ok {
+			if err := setDefault(field, raw); err != nil {
+				return nil, fmt.Errorf("default value of field `%s` of `%s`: %v", fieldName, name, err)
+			}
+		} else if union, ok := s.(*UnionSchema); ok && union.Types[0].Type() == Null {
+			field.HasDefault = true // optional fields can be added or removed compatibly
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// unionOf derives a union of null and the types of the pointer fields of t, which implements serde.Union.
+func (d *deriver) unionOf(t reflect.Type) (Schema, error) {
+	union := &UnionSchema{Types: []Schema{&PrimitiveSchema{Primitive: Null}}}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Type.Kind() != reflect.Ptr {
+			continue
+		}
+		name, opts, err := parseTag(sf.Tag.Get("avro"))
+		if err != nil {
+			return nil, err
+		}
+		branch, err := d.schemaOf(sf.Type.Elem(), t.Name()+sf.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		if TypeName(branch) != name {
+			return nil, fmt.Errorf("field %s of %s is tagged with `%s` but its type is `%s`", sf.Name, t, name, TypeName(branch))
+		}
+		union.Types = append(union.Types, branch)
+	}
+	return union, nil
+}
+
+// setDefault sets the default written in a tag to the field.
+// A union derived from a pointer is reordered so that the type of the default comes first.
+func setDefault(field *Field, raw string) error {
+	if union, ok := field.Type.(*UnionSchema); ok && raw != "null" && len(union.Types) == 2 && union.Types[0].Type() == Null {
+		field.Type = &UnionSchema{Types: []Schema{union.Types[1], union.Types[0]}}
+	}
+	first := field.Type
+	if union, ok := first.(*UnionSchema); ok && len(union.Types) > 0 {
+		first = union.Types[0]
+	}
+
+	var v interface{} = raw
+	switch first.Type() {
+	case String, Bytes, Fixed, Enum:
+	default:
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return fmt.Errorf("%s is not JSON: %v", raw, err)
+		}
+	}
+	def, err := nativeDefault(field.Type, v)
+	if err != nil {
+		return err
+	}
+	field.Default = def
+	field.HasDefault = true
+	return nil
+}
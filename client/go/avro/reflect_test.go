@@ -0,0 +1,194 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package avro
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reflectedStatus string
+
+type reflectedKey [4]byte
+
+type reflectedAddress struct {
+	Zip string `avro:"zip"`
+}
+
+type reflectedContact struct {
+	Email *string           `avro:"string"`
+	Home  *reflectedAddress `avro:"com.example.Address"`
+}
+
+func (reflectedContact) AvroUnion() {}
+
+type reflectedPerson struct {
+	Id        int64              `avro:"id" avrodoc:"an identifier"`
+	FirstName string             `avro:"first_name" avrodefault:"unknown"`
+	Age       *int32             `avro:"age"`
+	Nickname  *string            `avro:"nickname" avrodefault:"none"`
+	Tags      []string           `avro:"tags" avrodefault:"[]"`
+	Scores    map[string]float64 `avro:"scores"`
+	Photo     []byte             `avro:"photo"`
+	Key       reflectedKey       `avro:"key"`
+	Birthday  time.Time          `avro:"birthday,date"`
+	CreatedAt time.Time          `avro:"created_at"`
+	Elapsed   time.Duration      `avro:"elapsed,time-millis"`
+	Balance   *big.Rat           `avro:"balance,precision=10,scale=2"`
+	Address   reflectedAddress   `avro:"address"`
+	Contact   reflectedContact   `avro:"contact"`
+	Friend    *reflectedPerson   `avro:"friend"`
+	Ignored   string             `avro:"-"`
+	internal  string
+}
+
+func (*reflectedPerson) AvroRecordName() string {
+	return "com.example.Person"
+}
+
+func (*reflectedAddress) AvroRecordName() string {
+	return "com.example.Address"
+}
+
+func TestSchemaOf(t *testing.T) {
+	s, err := SchemaOf(&reflectedPerson{})
+	if err != nil {
+		t.Fatalf("SchemaOf should not be an error. But an error was occurred: %v", err)
+	}
+
+	expected := `{"name":"com.example.Person","type":"record","fields":[` +
+		`{"name":"id","type":"long"},` +
+		`{"name":"first_name","type":"string"},` +
+		`{"name":"age","type":["null","int"]},` +
+		`{"name":"nickname","type":["string","null"]},` +
+		`{"name":"tags","type":{"type":"array","items":"string"}},` +
+		`{"name":"scores","type":{"type":"map","values":"double"}},` +
+		`{"name":"photo","type":"bytes"},` +
+		`{"name":"key","type":{"name":"reflectedKey","type":"fixed","size":4}},` +
+		`{"name":"birthday","type":"int"},` +
+		`{"name":"created_at","type":"long"},` +
+		`{"name":"elapsed","type":"int"},` +
+		`{"name":"balance","type":"bytes"},` +
+		`{"name":"address","type":{"name":"com.example.Address","type":"record","fields":[{"name":"zip","type":"string"}]}},` +
+		`{"name":"contact","type":["null","string","com.example.Address"]},` +
+		`{"name":"friend","type":["null","com.example.Person"]}]}`
+	if actual := CanonicalForm(s); actual != expected {
+		t.Errorf("SchemaOf = %s, wants %s", actual, expected)
+	}
+
+	person := s.(*RecordSchema)
+	if f := person.Field("id"); f.Doc != "an identifier" || f.HasDefault {
+		t.Errorf("id = %+v, wants a doc and no default", f)
+	}
+	if f := person.Field("age"); !f.HasDefault || f.Default != nil {
+		t.Errorf("age = %+v, wants the default null", f)
+	}
+	if f := person.Field("first_name"); f.Default != "unknown" {
+		t.Errorf("default of first_name = %v, wants unknown", f.Default)
+	}
+	if f := person.Field("nickname"); f.Default != "none" {
+		t.Errorf("default of nickname = %v, wants none", f.Default)
+	}
+	if f := person.Field("tags"); !f.HasDefault || len(f.Default.([]interface{})) != 0 {
+		t.Errorf("default of tags = %v, wants []", f.Default)
+	}
+	if lt := person.Field("balance").Type.(*PrimitiveSchema).LogicalType; lt == nil || lt.Name != "decimal" || lt.Precision != 10 || lt.Scale != 2 {
+		t.Errorf("logical type of balance = %+v, wants decimal(10, 2)", lt)
+	}
+	if lt := person.Field("created_at").Type.(*PrimitiveSchema).LogicalType; lt == nil || lt.Name != "timestamp-millis" {
+		t.Errorf("logical type of created_at = %+v, wants timestamp-millis", lt)
+	}
+
+	b, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal should not be an error. But an error was occurred: %v", err)
+	}
+	if _, err := Parse(string(b)); err != nil {
+		t.Errorf("SchemaOf should derive a valid schema. But it cannot be parsed: %v\n%s", err, b)
+	}
+}
+
+func TestSchemaOfEmbedded(t *testing.T) {
+	type Location struct {
+		Zip string `avro:"zip"`
+	}
+	type withEmbedded struct {
+		Location
+		Id int64 `avro:"id"`
+	}
+	s, err := SchemaOf(withEmbedded{})
+	if err != nil {
+		t.Fatalf("SchemaOf should not be an error. But an error was occurred: %v", err)
+	}
+	expected := `{"name":"withEmbedded","type":"record","fields":[` +
+		`{"name":"Location","type":{"name":"Location","type":"record","fields":[{"name":"zip","type":"string"}]}},` +
+		`{"name":"id","type":"long"}]}`
+	if actual := CanonicalForm(s); actual != expected {
+		t.Errorf("an embedded struct is expected to be a field, but SchemaOf = %s", actual)
+	}
+}
+
+func TestSchemaOfInvalid(t *testing.T) {
+	type withInterface struct{ Any interface{} }
+	type withIntKeys struct{ M map[int]string }
+	type withoutPrecision struct{ Amount big.Rat }
+	type withTimeMillis struct {
+		At time.Time `avro:"at,time-millis"`
+	}
+	type withDuplicates struct {
+		A string `avro:"x"`
+		B string `avro:"x"`
+	}
+	type withInvalidDefault struct {
+		Count int `avrodefault:"many"`
+	}
+	type withUnknownOption struct {
+		Name string `avro:"name,unknown"`
+	}
+	type withUint64 struct {
+		Count uint64 `avro:"count"`
+	}
+
+	cases := []struct {
+		v     interface{}
+		error string
+	}{
+		{nil, "nil"},
+		{struct{ Id int }{}, "has no name"},
+		{withInterface{}, "cannot be derived"},
+		{withIntKeys{}, "not a string"},
+		{withoutPrecision{}, "precision"},
+		{withTimeMillis{}, "cannot be time-millis"},
+		{withDuplicates{}, "more than once"},
+		{withInvalidDefault{}, "not JSON"},
+		{withUnknownOption{}, "invalid option"},
+		{withUint64{}, "cannot hold"},
+	}
+	for _, c := range cases {
+		if _, err := SchemaOf(c.v); err == nil || !strings.Contains(err.Error(), c.error) {
+			t.Errorf("SchemaOf(%T) = %v, wants an error containing %q", c.v, err, c.error)
+		}
+	}
+}
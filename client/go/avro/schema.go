@@ -0,0 +1,248 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package avro parses Avro schemas into a typed AST and computes their Parsing Canonical Form.
+package avro
+
+import (
+	"strings"
+)
+
+// Type is the name of an Avro type.
+type Type string
+
+const (
+	Null    Type = "null"
+	Boolean Type = "boolean"
+	Int     Type = "int"
+	Long    Type = "long"
+	Float   Type = "float"
+	Double  Type = "double"
+	Bytes   Type = "bytes"
+	String  Type = "string"
+	Record  Type = "record"
+	Enum    Type = "enum"
+	Array   Type = "array"
+	Map     Type = "map"
+	Fixed   Type = "fixed"
+	Union   Type = "union"
+)
+
+// IsPrimitive reports whether t is a primitive type.
+func (t Type) IsPrimitive() bool {
+	switch t {
+	case Null, Boolean, Int, Long, Float, Double, Bytes, String:
+		return true
+	}
+	return false
+}
+
+// Schema is a node of a parsed Avro schema.
+// It is one of *PrimitiveSchema, *RecordSchema, *EnumSchema, *ArraySchema, *MapSchema, *FixedSchema and *UnionSchema.
+// A named type referred to by its name is the same node as its definition, so that a schema may be recursive.
+type Schema interface {
+	Type() Type
+}
+
+// NamedSchema is a schema of a named type, i.e. record, enum or fixed.
+type NamedSchema interface {
+	Schema
+	// FullName returns the name of the type qualified with its namespace.
+	FullName() string
+	// AliasNames returns the aliases of the type qualified with its namespace.
+	AliasNames() []string
+}
+
+// LogicalType annotates a primitive or fixed type. Invalid logical types are ignored on parsing as the specification says.
+type LogicalType struct {
+	Name string
+	// Precision and Scale are used only by `decimal`.
+	Precision int
+	Scale     int
+}
+
+// PrimitiveSchema is a schema of a primitive type.
+type PrimitiveSchema struct {
+	Primitive   Type
+	LogicalType *LogicalType
+}
+
+// Type returns the primitive type.
+func (s *PrimitiveSchema) Type() Type {
+	return s.Primitive
+}
+
+// RecordSchema is a schema of a record or an error.
+type RecordSchema struct {
+	Name    string // full name
+	Aliases []string
+	Doc     string
+	Fields  []*Field
+	IsError bool
+}
+
+// Type returns Record.
+func (s *RecordSchema) Type() Type {
+	return Record
+}
+
+// FullName returns the full name of the record.
+func (s *RecordSchema) FullName() string {
+	return s.Name
+}
+
+// AliasNames returns the full names of aliases of the record.
+func (s *RecordSchema) AliasNames() []string {
+	return s.Aliases
+}
+
+// Field returns the field of the given name or nil if it does not exist.
+func (s *RecordSchema) Field(name string) *Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Field is a field of a record.
+type Field struct {
+	Name    string
+	Aliases []string
+	Doc     string
+	Type    Schema
+	// Default is the default value converted into a native Go value of Type:
+	// nil, bool, int32, int64, float32, float64, []byte (bytes and fixed), string (string and enum),
+	// []interface{} (array) or map[string]interface{} (map and record). A default of a union is a value of its first type.
+	Default    interface{}
+	HasDefault bool
+	Order      string // `ascending`, `descending`, `ignore` or empty
+}
+
+// EnumSchema is a schema of an enum.
+type EnumSchema struct {
+	Name    string // full name
+	Aliases []string
+	Doc     string
+	Symbols []string
+	Default string // empty if absent
+}
+
+// Type returns Enum.
+func (s *EnumSchema) Type() Type {
+	return Enum
+}
+
+// FullName returns the full name of the enum.
+func (s *EnumSchema) FullName() string {
+	return s.Name
+}
+
+// AliasNames returns the full names of aliases of the enum.
+func (s *EnumSchema) AliasNames() []string {
+	return s.Aliases
+}
+
+// HasSymbol reports whether the enum has the given symbol.
+func (s *EnumSchema) HasSymbol(symbol string) bool {
+	for _, e := range s.Symbols {
+		if e == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// FixedSchema is a schema of a fixed.
+type FixedSchema struct {
+	Name        string // full name
+	Aliases     []string
+	Size        int
+	LogicalType *LogicalType
+}
+
+// Type returns Fixed.
+func (s *FixedSchema) Type() Type {
+	return Fixed
+}
+
+// FullName returns the full name of the fixed.
+func (s *FixedSchema) FullName() string {
+	return s.Name
+}
+
+// AliasNames returns the full names of aliases of the fixed.
+func (s *FixedSchema) AliasNames() []string {
+	return s.Aliases
+}
+
+// ArraySchema is a schema of an array.
+type ArraySchema struct {
+	Items Schema
+}
+
+// Type returns Array.
+func (s *ArraySchema) Type() Type {
+	return Array
+}
+
+// MapSchema is a schema of a map.
+type MapSchema struct {
+	Values Schema
+}
+
+// Type returns Map.
+func (s *MapSchema) Type() Type {
+	return Map
+}
+
+// UnionSchema is a schema of a union.
+type UnionSchema struct {
+	Types []Schema
+}
+
+// Type returns Union.
+func (s *UnionSchema) Type() Type {
+	return Union
+}
+
+// TypeName returns the full name of a named type, otherwise the name of its type.
+func TypeName(s Schema) string {
+	if named, ok := s.(NamedSchema); ok {
+		return named.FullName()
+	}
+	return string(s.Type())
+}
+
+// Namespace returns the namespace part of the given full name.
+func Namespace(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
+
+// ShortName returns the name without its namespace.
+func ShortName(fullName string) string {
+	return fullName[strings.LastIndex(fullName, ".")+1:]
+}
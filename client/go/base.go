@@ -23,63 +23,225 @@
 package _go
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-
-	"github.com/parnurzeal/gorequest"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cyberagent/typebook/client/go/model"
 )
 
+const (
+	contentTypeJSON = "application/json"
+	contentTypeText = "text/plain"
+)
+
+// baseClient holds nothing but immutable settings and the endpoint pool which is safe for concurrent use
+// after it is created, so that it can be shared among goroutines.
 type baseClient struct {
-	host string
-	*gorequest.SuperAgent
+	endpoints           *endpointPool
+	balancing           Balancing
+	healthCheckInterval time.Duration
+
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	tlsConfig   *tls.Config
+	auth        Authenticator
+	header      http.Header
+	retryPolicy *RetryPolicy
+
+	diskCache *DiskCache
+	offline   bool
 }
 
-// Get constructs a HTTP GET request as a `gorequest.SuperAgent`.
-func (bc *baseClient) Get(path string) *gorequest.SuperAgent {
-	return bc.SuperAgent.Get(fmt.Sprintf("http://%s%s", bc.host, path))
+func newBaseClient(endpoints string, opts ...Option) *baseClient {
+	bc := &baseClient{healthCheckInterval: DefaultHealthCheckInterval, header: make(http.Header)}
+	for _, opt := range opts {
+		opt(bc)
+	}
+	if bc.httpClient == nil {
+		bc.httpClient = new(http.Client)
+	}
+	if bc.transport == nil && bc.tlsConfig != nil {
+		bc.transport = newTLSTransport(bc.tlsConfig)
+	}
+	if bc.transport != nil {
+		httpClient := *bc.httpClient // not to modify the http.Client owned by the caller
+		httpClient.Transport = bc.transport
+		bc.httpClient = &httpClient
+	}
+
+	bc.endpoints = newEndpointPool(parseEndpoints(endpoints), bc.balancing)
+	if len(bc.endpoints.endpoints) > 1 && !bc.offline {
+		go bc.endpoints.healthCheck(bc.healthCheckInterval, bc.probe)
+	}
+	return bc
 }
 
-// Post constructs a HTTP POST request as a `gorequest.SuperAgent`.
-func (bc *baseClient) Post(path string) *gorequest.SuperAgent {
-	return bc.SuperAgent.Post(fmt.Sprintf("http://%s%s", bc.host, path))
+// probe issues a GET /health request to the given endpoint and reports whether it is healthy.
+func (bc *baseClient) probe(ctx context.Context, e *endpoint) bool {
+	response, _, err := bc.send(ctx, e.url, http.MethodGet, "/health", "", nil)
+	return err == nil && response.StatusCode == http.StatusOK
 }
 
-// Put constructs a HTTP PUT request as a `gorequest.SuperAgent`.
-func (bc *baseClient) Put(path string) *gorequest.SuperAgent {
-	return bc.SuperAgent.Put(fmt.Sprintf("http://%s%s", bc.host, path))
+// normalizeEndpoint completes the scheme of the given endpoint with `http` if it is omitted (e.g. `host:port`)
+// and trims a trailing slash so that a request path can be appended to it.
+func normalizeEndpoint(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+// Get issues a HTTP GET request to the given path and returns the response body.
+// It is retried according to the retry policy of the client.
+func (bc *baseClient) Get(ctx context.Context, path string) ([]byte, *model.Error) {
+	return bc.do(ctx, http.MethodGet, path, "", nil, true)
+}
+
+// Post issues a HTTP POST request with the given body to the given path and returns the response body.
+// It is retried according to the retry policy of the client only if retryable is true,
+// which means the request does not change any state of a typebook server.
+func (bc *baseClient) Post(ctx context.Context, path, contentType string, body []byte, retryable bool) ([]byte, *model.Error) {
+	return bc.do(ctx, http.MethodPost, path, contentType, body, retryable)
+}
+
+// Put issues a HTTP PUT request with the given body to the given path and returns the response body.
+func (bc *baseClient) Put(ctx context.Context, path, contentType string, body []byte) ([]byte, *model.Error) {
+	return bc.do(ctx, http.MethodPut, path, contentType, body, false)
+}
+
+// Delete issues a HTTP DELETE request to the given path and returns the response body.
+func (bc *baseClient) Delete(ctx context.Context, path string) ([]byte, *model.Error) {
+	return bc.do(ctx, http.MethodDelete, path, "", nil, false)
+}
+
+// do sends a HTTP request bound to ctx and reads the whole response body.
+// The request is aborted as soon as ctx is cancelled or its deadline is exceeded.
+// If retryable is true and the request fails transiently, it is sent again according to the retry policy.
+// It returns *model.Error if either client or server have caused errors.
+func (bc *baseClient) do(ctx context.Context, method, path, contentType string, body []byte, retryable bool) ([]byte, *model.Error) {
+	if bc.offline {
+		return nil, model.NewError(nil, []error{ErrOffline})
+	}
+
+	maxAttempts := 1
+	if retryable && bc.retryPolicy != nil && bc.retryPolicy.MaxAttempts > 1 {
+		maxAttempts = bc.retryPolicy.MaxAttempts
+	}
+
+	var (
+		response     *http.Response
+		responseBody []byte
+		err          error
+	)
+	for attempt := 1; ; attempt++ {
+		endpoint := bc.endpoints.pick()
+		response, responseBody, err = bc.sendWithAuth(ctx, endpoint.url, method, path, contentType, body)
+		bc.endpoints.report(ctx, endpoint, response, err)
+		if attempt >= maxAttempts || !bc.retryPolicy.shouldRetry(ctx, response, err) {
+			break
+		}
+		if err := sleep(ctx, bc.retryPolicy.backoff(attempt)); err != nil {
+			return nil, model.NewError(nil, []error{err})
+		}
+	}
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+
+	if err := checkServerError(response, responseBody); err != nil {
+		return nil, model.NewError(err, nil)
+	}
+	return responseBody, nil
 }
 
-// Delete constructs a HTTP DELETE request as a `gorequest.SuperAgent`
-func (bc *baseClient) Delete(path string) *gorequest.SuperAgent {
-	return bc.SuperAgent.Delete(fmt.Sprintf("http://%s%s", bc.host, path))
+// sendWithAuth sends a HTTP request by send.
+// If the request is rejected with 401 Unauthorized and the authenticator can refresh its credentials,
+// it is sent once again with the new credentials.
+func (bc *baseClient) sendWithAuth(ctx context.Context, endpoint, method, path, contentType string, body []byte) (*http.Response, []byte, error) {
+	response, responseBody, err := bc.send(ctx, endpoint, method, path, contentType, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if refresher, ok := bc.auth.(RefreshableAuthenticator); ok && response.StatusCode == http.StatusUnauthorized {
+		if err := refresher.Refresh(ctx); err != nil {
+			return nil, nil, err
+		}
+		return bc.send(ctx, endpoint, method, path, contentType, body)
+	}
+	return response, responseBody, nil
 }
 
-// checkError checks the response of `gorequest.EndBytes()` and returns *model.Error
-// if either client or server have caused errors.
-// Iff no error is occurred to both client and server, this will return nil.
-func checkError(response gorequest.Response, body []byte, errs []error) *model.Error {
-	if len(errs) != 0 {
-		return model.NewError(nil, errs)
+// send sends a HTTP request to the given endpoint with the configured headers and credentials,
+// and returns the response with its whole body.
+func (bc *baseClient) send(ctx context.Context, endpoint, method, path, contentType string, body []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
 	}
-	if err := checkServerError(response, body); err != nil {
-		return model.NewError(err, nil)
+	request, err := http.NewRequest(method, endpoint+path, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	request = request.WithContext(ctx)
+	for key, values := range bc.header {
+		request.Header[key] = append([]string(nil), values...)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if bc.auth != nil {
+		if err := bc.auth.Authenticate(ctx, request); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	response, err := bc.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, responseBody, nil
+}
+
+// parseInt parses a response body which consists of an integer such as the number of affected rows.
+func parseInt(body []byte) (int64, *model.Error) {
+	n, err := strconv.ParseInt(string(body), 10, 64)
+	if err != nil {
+		return -1, model.NewError(nil, []error{err})
 	}
-	return nil
+	return n, nil
 }
 
 // checkServerError checks the status code of response from typebook server.
 // if it is 200 or 201, it will return nil, otherwise model.ServerError is created from the response body.
-func checkServerError(response gorequest.Response, body []byte) *model.ServerError {
+// If the response body is not an error response of typebook (e.g. returned by a proxy),
+// the status code and the raw body are used instead.
+func checkServerError(response *http.Response, body []byte) *model.ServerError {
 	switch response.StatusCode {
-	case 200, 201:
+	case http.StatusOK, http.StatusCreated:
 		return nil
 	default:
 		serverErr := new(model.ServerError)
-		err := json.Unmarshal(body, serverErr)
-		if err != nil {
-			panic(err)
+		if err := json.Unmarshal(body, serverErr); err != nil || serverErr.Message == "" {
+			message := response.Status
+			if len(bytes.TrimSpace(body)) != 0 {
+				message = fmt.Sprintf("%s: %s", message, bytes.TrimSpace(body))
+			}
+			return &model.ServerError{ErrorCode: response.StatusCode, Message: message}
 		}
 		return serverErr
 	}
@@ -0,0 +1,215 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// CachedClient is a Client which caches schemas in memory.
+// A schema identified by an ID or a pair of a subject and a semver never changes once it is registered,
+// so it is cached forever. The latest schema of a subject or of a major version changes
+// when a new schema is registered, so it is cached only for the TTL.
+// Concurrent requests for the same schema are deduplicated into one request.
+// Methods other than schema lookups are not cached and delegated to the underlying Client as they are.
+type CachedClient struct {
+	*Client
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	byId     map[int64]model.Schema
+	bySemVer map[string]model.Schema
+	latest   map[string]cachedSchema // keyed by subject and `latest` or a major version
+
+	flights flightGroup
+}
+
+type cachedSchema struct {
+	schema  model.Schema
+	expires time.Time
+}
+
+// NewCachedClient wraps the given client with an in-memory schema cache.
+// ttl is how long the latest schema of a subject or of a major version is cached.
+// If it is not positive, such schemas are always retrieved from a typebook server.
+func NewCachedClient(client *Client, ttl time.Duration) *CachedClient {
+	return &CachedClient{
+		Client:   client,
+		ttl:      ttl,
+		byId:     make(map[int64]model.Schema),
+		bySemVer: make(map[string]model.Schema),
+		latest:   make(map[string]cachedSchema),
+	}
+}
+
+func semVerKey(subject string, semver model.SemVer) string {
+	return fmt.Sprintf("%s/%s", subject, semver.String())
+}
+
+func latestKey(subject, version string) string {
+	return fmt.Sprintf("%s/%s", subject, version)
+}
+
+// GetSchemaById is the same as Client.GetSchemaById except that the schema is cached.
+func (cc *CachedClient) GetSchemaById(id int64) (*model.Schema, *model.Error) {
+	return cc.GetSchemaByIdContext(context.Background(), id)
+}
+
+// GetSchemaByIdContext is the same as Client.GetSchemaByIdContext except that the schema is cached.
+func (cc *CachedClient) GetSchemaByIdContext(ctx context.Context, id int64) (*model.Schema, *model.Error) {
+	cc.mu.RLock()
+	schema, ok := cc.byId[id]
+	cc.mu.RUnlock()
+	if ok {
+		return &schema, nil
+	}
+
+	return cc.fetch(ctx, fmt.Sprintf("id:%d", id), "", func() (*model.Schema, *model.Error) {
+		return cc.Client.GetSchemaByIdContext(ctx, id)
+	})
+}
+
+// GetSchemaBySemVer is the same as Client.GetSchemaBySemVer except that the schema is cached.
+func (cc *CachedClient) GetSchemaBySemVer(subject string, semver model.SemVer) (*model.Schema, *model.Error) {
+	return cc.GetSchemaBySemVerContext(context.Background(), subject, semver)
+}
+
+// GetSchemaBySemVerContext is the same as Client.GetSchemaBySemVerContext except that the schema is cached.
+func (cc *CachedClient) GetSchemaBySemVerContext(ctx context.Context, subject string, semver model.SemVer) (*model.Schema, *model.Error) {
+	key := semVerKey(subject, semver)
+	cc.mu.RLock()
+	schema, ok := cc.bySemVer[key]
+	cc.mu.RUnlock()
+	if ok {
+		return &schema, nil
+	}
+
+	return cc.fetch(ctx, "semver:"+key, "", func() (*model.Schema, *model.Error) {
+		return cc.Client.GetSchemaBySemVerContext(ctx, subject, semver)
+	})
+}
+
+// GetLatestSchema is the same as Client.GetLatestSchema except that the schema is cached for the TTL.
+func (cc *CachedClient) GetLatestSchema(subject string) (*model.Schema, *model.Error) {
+	return cc.GetLatestSchemaContext(context.Background(), subject)
+}
+
+// GetLatestSchemaContext is the same as Client.GetLatestSchemaContext except that the schema is cached for the TTL.
+func (cc *CachedClient) GetLatestSchemaContext(ctx context.Context, subject string) (*model.Schema, *model.Error) {
+	return cc.getLatest(ctx, latestKey(subject, "latest"), func() (*model.Schema, *model.Error) {
+		return cc.Client.GetLatestSchemaContext(ctx, subject)
+	})
+}
+
+// GetSchemaByMajorVersion is the same as Client.GetSchemaByMajorVersion except that the schema is cached for the TTL.
+func (cc *CachedClient) GetSchemaByMajorVersion(subject string, majorVersion int) (*model.Schema, *model.Error) {
+	return cc.GetSchemaByMajorVersionContext(context.Background(), subject, majorVersion)
+}
+
+// GetSchemaByMajorVersionContext is the same as Client.GetSchemaByMajorVersionContext except that the schema is cached for the TTL.
+func (cc *CachedClient) GetSchemaByMajorVersionContext(ctx context.Context, subject string, majorVersion int) (*model.Schema, *model.Error) {
+	return cc.getLatest(ctx, latestKey(subject, fmt.Sprintf("v%d", majorVersion)), func() (*model.Schema, *model.Error) {
+		return cc.Client.GetSchemaByMajorVersionContext(ctx, subject, majorVersion)
+	})
+}
+
+// RegisterSchema is the same as Client.RegisterSchema except that it invalidates the cached latest schemas of the subject.
+func (cc *CachedClient) RegisterSchema(subject, definition string) (*model.SchemaId, *model.Error) {
+	return cc.RegisterSchemaContext(context.Background(), subject, definition)
+}
+
+// RegisterSchemaContext is the same as Client.RegisterSchemaContext except that it invalidates the cached latest schemas of the subject.
+func (cc *CachedClient) RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error) {
+	id, err := cc.Client.RegisterSchemaContext(ctx, subject, definition)
+	if err == nil {
+		cc.invalidate(subject)
+	}
+	return id, err
+}
+
+// RegisterType is the same as Client.RegisterType except that it invalidates the cached latest schemas of the subject.
+func (cc *CachedClient) RegisterType(subject string, v interface{}) (*model.SchemaId, *model.Error) {
+	return cc.RegisterTypeContext(context.Background(), subject, v)
+}
+
+// RegisterTypeContext is the same as Client.RegisterTypeContext except that it invalidates the cached latest schemas of the subject.
+func (cc *CachedClient) RegisterTypeContext(ctx context.Context, subject string, v interface{}) (*model.SchemaId, *model.Error) {
+	definition, err := definitionOf(v)
+	if err != nil {
+		return nil, err
+	}
+	return cc.RegisterSchemaContext(ctx, subject, definition)
+}
+
+func (cc *CachedClient) getLatest(ctx context.Context, key string, get func() (*model.Schema, *model.Error)) (*model.Schema, *model.Error) {
+	cc.mu.RLock()
+	cached, ok := cc.latest[key]
+	cc.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return &cached.schema, nil
+	}
+
+	return cc.fetch(ctx, "latest:"+key, key, get)
+}
+
+// fetch retrieves a schema by get deduplicating concurrent calls with the same flightKey, then caches it.
+// If ttlKey is not empty, the schema is also cached as the latest one under the key for the TTL.
+func (cc *CachedClient) fetch(ctx context.Context, flightKey, ttlKey string, get func() (*model.Schema, *model.Error)) (*model.Schema, *model.Error) {
+	schema, err := cc.flights.do(ctx, flightKey, func() (*model.Schema, *model.Error) {
+		schema, err := get()
+		if err != nil {
+			return nil, err
+		}
+
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		cc.byId[schema.Id] = *schema
+		cc.bySemVer[semVerKey(schema.Subject, schema.Version)] = *schema
+		if ttlKey != "" && cc.ttl > 0 {
+			cc.latest[ttlKey] = cachedSchema{schema: *schema, expires: time.Now().Add(cc.ttl)}
+		}
+		return schema, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *schema // not to share a schema among callers
+	return &copied, nil
+}
+
+// invalidate removes the cached latest schemas of the given subject.
+func (cc *CachedClient) invalidate(subject string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for key, cached := range cc.latest {
+		if cached.schema.Subject == subject {
+			delete(cc.latest, key)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// countingServer replies testSchema() for any schema lookup after the given delay and counts requests.
+type countingServer struct {
+	*httptest.Server
+	requests int32
+}
+
+func newCountingServer(delay time.Duration) *countingServer {
+	cs := new(countingServer)
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&cs.requests, 1)
+		time.Sleep(delay)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":2}`))
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/subjects/notfound/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":404,"message":"not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(testSchema())
+	}))
+	return cs
+}
+
+func (cs *countingServer) received() int32 {
+	return atomic.LoadInt32(&cs.requests)
+}
+
+func TestCachedClientImmutableLookups(t *testing.T) {
+	server := newCountingServer(0)
+	defer server.Close()
+
+	client := NewCachedClient(NewClient(server.URL), time.Minute)
+	expect := testSchema()
+	for i := 0; i < 3; i++ {
+		if actual, err := client.GetSchemaById(expect.Id); err != nil {
+			t.Errorf("GetSchemaById(%d) should not be an error. But an error was occurred: %v", expect.Id, err)
+		} else if *actual != expect {
+			t.Errorf("GetSchemaById(%d) = %v, wants %v", expect.Id, *actual, expect)
+		}
+	}
+	// already cached by the lookup by ID
+	if actual, err := client.GetSchemaBySemVer(expect.Subject, expect.Version); err != nil {
+		t.Errorf("GetSchemaBySemVer(%s, %s) should not be an error. But an error was occurred: %v", expect.Subject, expect.Version.String(), err)
+	} else if *actual != expect {
+		t.Errorf("GetSchemaBySemVer(%s, %s) = %v, wants %v", expect.Subject, expect.Version.String(), *actual, expect)
+	}
+	if server.received() != 1 {
+		t.Errorf("the server received %d requests, wants 1", server.received())
+	}
+}
+
+func TestCachedClientDoesNotCacheErrors(t *testing.T) {
+	server := newCountingServer(0)
+	defer server.Close()
+
+	client := NewCachedClient(NewClient(server.URL), time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetSchemaBySemVer("notfound", model.SemVer{Major: 1}); err == nil {
+			t.Errorf("GetSchemaBySemVer(notfound, v1.0.0) should be an error. But no error was occurred")
+		}
+	}
+	if server.received() != 2 {
+		t.Errorf("the server received %d requests, wants 2", server.received())
+	}
+}
+
+func TestCachedClientTTL(t *testing.T) {
+	server := newCountingServer(0)
+	defer server.Close()
+
+	client := NewCachedClient(NewClient(server.URL), 50*time.Millisecond)
+	client.GetLatestSchema(subject)
+	client.GetLatestSchema(subject)
+	client.GetSchemaByMajorVersion(subject, 1)
+	client.GetSchemaByMajorVersion(subject, 1)
+	if server.received() != 2 {
+		t.Errorf("the server received %d requests, wants 2", server.received())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	client.GetLatestSchema(subject)
+	if server.received() != 3 {
+		t.Errorf("the latest schema should expire after the TTL, but the server received %d requests", server.received())
+	}
+
+	// registering a new schema invalidates the latest schemas
+	if _, err := client.RegisterSchema(subject, schemaDef); err != nil {
+		t.Errorf("RegisterSchema(%s, ...) should not be an error. But an error was occurred: %v", subject, err)
+	}
+	client.GetLatestSchema(subject)
+	if server.received() != 5 {
+		t.Errorf("the latest schema should be invalidated by registration, but the server received %d requests", server.received())
+	}
+}
+
+func TestCachedClientDeduplicatesRequests(t *testing.T) {
+	server := newCountingServer(100 * time.Millisecond)
+	defer server.Close()
+
+	client := NewCachedClient(NewClient(server.URL), time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetSchemaById(1); err != nil {
+				t.Errorf("GetSchemaById(1) should not be an error. But an error was occurred: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if server.received() != 1 {
+		t.Errorf("concurrent requests should be deduplicated, but the server received %d requests", server.received())
+	}
+}
+
+func TestCachedClientWaitersHonorTheirContexts(t *testing.T) {
+	server := newCountingServer(200 * time.Millisecond)
+	defer server.Close()
+
+	client := NewCachedClient(NewClient(server.URL), time.Minute)
+	leaderCtx, cancelLeader := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelLeader()
+	go client.GetSchemaByIdContext(leaderCtx, 1)
+	time.Sleep(10 * time.Millisecond)
+
+	// a waiter gives up as soon as its own context is done
+	waiterCtx, cancelWaiter := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelWaiter()
+	start := time.Now()
+	if _, err := client.GetSchemaByIdContext(waiterCtx, 1); err == nil {
+		t.Error("GetSchemaByIdContext should be an error when its context is done")
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("a waiter should not wait for the request in progress after its context is done, but it took %v", elapsed)
+	}
+
+	// a waiter sends the request again when the request it waited for is canceled by another caller
+	if _, err := client.GetSchemaById(1); err != nil {
+		t.Errorf("GetSchemaById(1) should not be an error even if another caller is canceled. But an error was occurred: %v", err)
+	}
+	if server.received() != 2 {
+		t.Errorf("the canceled request should be sent again, but the server received %d requests", server.received())
+	}
+}
@@ -23,32 +23,131 @@
 package _go
 
 import (
-	"github.com/parnurzeal/gorequest"
-)
-
-var (
-	DisableTransportSwap = false
+	"crypto/tls"
+	"net/http"
+	"time"
 )
 
 type Client struct {
 	*subjectClient
 	*configClient
 	*schemaClient
-	*gorequest.SuperAgent
+	base *baseClient
+}
+
+// Option configures optional behavior of a Client created by NewClient.
+type Option func(*baseClient)
+
+// WithHTTPClient makes a Client send requests through the given `http.Client`.
+// It is useful to configure timeout, redirect policy and so on.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(bc *baseClient) {
+		bc.httpClient = httpClient
+	}
+}
+
+// WithTransport makes a Client send requests through the given `http.RoundTripper`.
+// If it is combined with WithHTTPClient, the transport of the given `http.Client` is overwritten.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(bc *baseClient) {
+		bc.transport = transport
+	}
+}
+
+// WithTLSConfig makes a Client use the given `tls.Config` for HTTPS connections.
+// It replaces the transport of the `http.Client` given by WithHTTPClient,
+// while it is ignored if a transport is given by WithTransport.
+// See LoadTLSConfig to build it from certificate files.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(bc *baseClient) {
+		bc.tlsConfig = config
+	}
+}
+
+// WithAuth makes a Client authenticate every request with the given Authenticator.
+// See BasicAuth, BearerToken and TokenSource for built-in ones.
+func WithAuth(auth Authenticator) Option {
+	return func(bc *baseClient) {
+		bc.auth = auth
+	}
+}
+
+// WithHeader makes a Client send the given header in every request.
+// It can be applied multiple times to send multiple headers.
+func WithHeader(key, value string) Option {
+	return func(bc *baseClient) {
+		bc.header.Add(key, value)
+	}
+}
+
+// WithRetryPolicy makes a Client retry requests which have failed transiently according to the given policy.
+// Without this option, no request is retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(bc *baseClient) {
+		bc.retryPolicy = &policy
+	}
+}
+
+// WithBalancing sets the strategy to choose an endpoint when a Client has multiple endpoints.
+// The default is RoundRobin.
+func WithBalancing(balancing Balancing) Option {
+	return func(bc *baseClient) {
+		bc.balancing = balancing
+	}
+}
+
+// WithHealthCheckInterval sets the interval to probe GET /health of ejected endpoints
+// when a Client has multiple endpoints. Non-positive values are ignored.
+// The default is DefaultHealthCheckInterval.
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(bc *baseClient) {
+		if interval > 0 {
+			bc.healthCheckInterval = interval
+		}
+	}
+}
+
+// WithDiskCache sets a DiskCache which is consulted before a schema is retrieved by its id or semver
+// and populated after any schema is retrieved from a typebook server.
+func WithDiskCache(cache *DiskCache) Option {
+	return func(bc *baseClient) {
+		bc.diskCache = cache
+	}
+}
+
+// WithOffline makes a Client serve schemas only from its DiskCache without issuing any request.
+// The latest schema of a subject (or of a major version) is the one with the highest version among cached ones.
+// Methods that cannot be served from the cache return model.Error wrapping ErrOffline.
+func WithOffline() Option {
+	return func(bc *baseClient) {
+		bc.offline = true
+	}
 }
 
 // NewClient create and instantiate a new Client object which can interact with
 // typebook server at the designated endpoint.
-// endpoint should be in the form of `host:port`.
-// Client instances should create for each goroutine to send multiple requests concurrently.
-func NewClient(endpoint string) *Client {
-	gorequest.DisableTransportSwap = DisableTransportSwap
-
-	baseClient := &baseClient{endpoint, gorequest.New()}
+// endpoint should be in the form of `host:port` or a URL with scheme and optional base path
+// (e.g. `https://example.com/typebook`).
+// A Client instance is safe for concurrent use by multiple goroutines,
+// so it should be created once and reused rather than created for each request.
+//
+// To interact with multiple replicas of typebook server, endpoint may be a comma separated list
+// (e.g. `host1:8888,host2:8888`). An endpoint is ejected when a request to it fails with a connection error
+// or 502, 503 or 504, and it is brought back after it passes a periodic health check.
+// Combine it with WithRetryPolicy to fail over to another endpoint within a single method call.
+// Call Close to stop the health check when the Client is no longer used.
+func NewClient(endpoint string, opts ...Option) *Client {
+	baseClient := newBaseClient(endpoint, opts...)
 	return &Client{
-		&subjectClient{baseClient},
-		&configClient{baseClient},
-		&schemaClient{baseClient},
-		baseClient.SuperAgent,
+		subjectClient: &subjectClient{baseClient},
+		configClient:  &configClient{baseClient},
+		schemaClient:  &schemaClient{baseClient},
+		base:          baseClient,
 	}
 }
+
+// Close stops the background health check of endpoints.
+// The Client should not be used after it is closed.
+func (c *Client) Close() {
+	c.base.endpoints.close()
+}
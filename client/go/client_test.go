@@ -0,0 +1,126 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// TestConcurrentRequests shares a single Client among many goroutines.
+// Run it with `go test -race` to detect data races in the client.
+func TestConcurrentRequests(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/schemas/ids/"):
+			var id int64
+			fmt.Sscanf(r.URL.Path, "/schemas/ids/%d", &id)
+			json.NewEncoder(w).Encode(model.Schema{Id: id, Subject: subject, Version: model.SemVer{Major: 1}, Definition: schemaDef})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/versions"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/subjects/"):
+			w.Write([]byte("1"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":404,"message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"))
+
+	const goroutines = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*4)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			if schema, err := client.GetSchemaById(id); err != nil {
+				errs <- err
+			} else if schema.Id != id {
+				errs <- fmt.Errorf("GetSchemaById(%d) returned a schema with ID %d", id, schema.Id)
+			}
+			if _, err := client.RegisterSchema(subject, schemaDef); err != nil {
+				errs <- err
+			}
+			if _, err := client.UpdateDescription(subject, description); err != nil {
+				errs <- err
+			}
+			if _, err := client.GetConfig(subject); err == nil || err.ServerError == nil || err.ErrorCode != 404 {
+				errs <- fmt.Errorf("GetConfig(%s) should be a server error with code 404, but %v", subject, err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if requests := atomic.LoadInt64(&requests); requests != goroutines*4 {
+		t.Errorf("the server received %d requests, wants %d", requests, goroutines*4)
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["` + r.Header.Get("X-Test") + `"]`))
+	}))
+	defer server.Close()
+
+	httpClient := new(http.Client)
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"),
+		WithHTTPClient(httpClient),
+		WithTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r.Header.Set("X-Test", subject)
+			return http.DefaultTransport.RoundTrip(r)
+		})),
+	)
+
+	if actual, err := client.ListSubjects(); err != nil {
+		t.Errorf(`ListSubjects() should not be an error. But an error was occurred: %v`, err)
+	} else if len(actual) != 1 || actual[0] != subject {
+		t.Errorf(`ListSubjects() = %v, wants [%s]`, actual, subject)
+	}
+	if httpClient.Transport != nil {
+		t.Errorf("WithTransport should not modify the http.Client given by WithHTTPClient")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
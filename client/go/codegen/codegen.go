@@ -0,0 +1,416 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package codegen generates Go types from Avro schemas registered in a typebook server.
+// Generated types can be serialized and deserialized with package serde.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Go generates the source code of Go types in the package named packageName from a registered schema.
+//
+// Records are generated as structs whose fields are tagged with `avro`, enums as string types with a constant for each symbol
+// and fixed as byte arrays. A union of null and another type is generated as a pointer, and other unions as structs
+// that implement serde.Union, which hold one of their branches in pointer fields.
+// Logical types date, timestamp-millis and timestamp-micros are mapped to time.Time,
+// time-millis and time-micros to time.Duration and decimal to *big.Rat.
+//
+// The type generated from the schema itself is named after the schema, or after the subject if the schema is not named.
+// The subject, version, ID and definition of the schema are embedded as constants prefixed with the name,
+// so that a serializer can be pinned to the schema, e.g. serde.NewSerializer(PersonSchemaId, PersonSchema).
+// Types and constants whose names collide with those declared before are suffixed with a number, e.g. KindAdmin2.
+func Go(schema *model.Schema, packageName string) ([]byte, error) {
+	root, err := avro.Parse(schema.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		names:     make(map[string]string),
+		used:      make(map[string]bool),
+		generated: make(map[string]bool),
+		imports:   make(map[string]bool),
+	}
+	var rootName string
+	if named, ok := root.(avro.NamedSchema); ok {
+		// the root type is named first, so it always gets its short name
+		rootName = camelCase(avro.ShortName(named.FullName()))
+		g.reserveConstants(rootName)
+		g.nameTypes(root, make(map[string]bool))
+		g.goType(root, rootName, nil)
+	} else {
+		g.nameTypes(root, make(map[string]bool))
+		rootName = camelCase(schema.Subject)
+		for g.used[rootName] || g.constantsTaken(rootName) {
+			rootName += "Root"
+		}
+		g.reserveConstants(rootName)
+		g.decls = append(g.decls, "")
+		index := len(g.decls) - 1
+		// a union is generated as a struct named rootName itself
+		if t := g.goType(root, rootName, nil); t != rootName {
+			g.used[rootName] = true
+			g.decls[index] = fmt.Sprintf("// %s is generated from the schema of subject `%s`.\ntype %s %s\n", rootName, schema.Subject, rootName, t)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by typebook codegen from %s of subject `%s`. DO NOT EDIT.\n\n", schema.Version.String(), schema.Subject)
+	fmt.Fprintf(buf, "package %s\n\n", packageName)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			imports = append(imports, strconv.Quote(path))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(buf, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+
+	fmt.Fprintf(buf, "// Subject, version and ID of the schema that %s is generated from.\n", rootName)
+	fmt.Fprintf(buf, "const (\n%sSubject = %s\n%sVersion = %s\n%sSchemaId = %d\n)\n\n",
+		rootName, strconv.Quote(schema.Subject), rootName, strconv.Quote(schema.Version.String()), rootName, schema.Id)
+	fmt.Fprintf(buf, "// %sSchema is the definition of the schema that %s is generated from.\n", rootName, rootName)
+	fmt.Fprintf(buf, "const %sSchema = %s\n\n", rootName, quote(schema.Definition))
+
+	for _, decl := range g.decls {
+		if decl != "" {
+			buf.WriteString(decl)
+			buf.WriteString("\n")
+		}
+	}
+	return format.Source(buf.Bytes())
+}
+
+// constantSuffixes are the suffixes of the constants embedded with the name of the root type.
+var constantSuffixes = []string{"Subject", "Version", "SchemaId", "Schema"}
+
+type generator struct {
+	names     map[string]string // Go type names of named types by their full names
+	used      map[string]bool   // Go identifiers already taken by types and constants
+	generated map[string]bool   // full names of named types whose declarations are generated
+	imports   map[string]bool
+	decls     []string
+}
+
+// reserveConstants takes the names of the constants embedded with rootName.
+func (g *generator) reserveConstants(rootName string) {
+	for _, suffix := range constantSuffixes {
+		g.used[rootName+suffix] = true
+	}
+}
+
+// constantsTaken reports whether any of the constants embedded with rootName is already taken.
+func (g *generator) constantsTaken(rootName string) bool {
+	for _, suffix := range constantSuffixes {
+		if g.used[rootName+suffix] {
+			return true
+		}
+	}
+	return false
+}
+
+// nameTypes gives a Go type name to every named type in s. A short name is used unless it collides with another.
+func (g *generator) nameTypes(s avro.Schema, visited map[string]bool) {
+	switch s := s.(type) {
+	case avro.NamedSchema:
+		if visited[s.FullName()] {
+			return
+		}
+		visited[s.FullName()] = true
+		name := camelCase(avro.ShortName(s.FullName()))
+		if g.used[name] {
+			name = camelCase(s.FullName())
+		}
+		g.names[s.FullName()] = g.uniqueName(name)
+		if record, ok := s.(*avro.RecordSchema); ok {
+			for _, f := range record.Fields {
+				g.nameTypes(f.Type, visited)
+			}
+		}
+	case *avro.ArraySchema:
+		g.nameTypes(s.Items, visited)
+	case *avro.MapSchema:
+		g.nameTypes(s.Values, visited)
+	case *avro.UnionSchema:
+		for _, t := range s.Types {
+			g.nameTypes(t, visited)
+		}
+	}
+}
+
+// decl appends a declaration.
+func (g *generator) decl(format string, args ...interface{}) {
+	g.decls = append(g.decls, fmt.Sprintf(format, args...))
+}
+
+// uniqueName returns name or name suffixed with a number if it is already taken.
+func (g *generator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+// goType returns the Go type for s, generating declarations of the types it needs.
+// hint names a union struct, and ancestors are records that contain s by value, which must be referred to by pointers.
+func (g *generator) goType(s avro.Schema, hint string, ancestors map[string]bool) string {
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		return g.primitiveType(s)
+	case *avro.RecordSchema:
+		name := g.names[s.Name]
+		if ancestors[s.Name] {
+			return "*" + name
+		}
+		g.record(s, name, ancestors)
+		return name
+	case *avro.EnumSchema:
+		name := g.names[s.Name]
+		g.enum(s, name)
+		return name
+	case *avro.FixedSchema:
+		if s.LogicalType != nil && s.LogicalType.Name == "decimal" {
+			g.imports["math/big"] = true
+			return "*big.Rat"
+		}
+		name := g.names[s.Name]
+		if !g.generated[s.Name] {
+			g.generated[s.Name] = true
+			g.decl("// %s is generated from fixed `%s`.\ntype %s [%d]byte\n", name, s.Name, name, s.Size)
+		}
+		return name
+	case *avro.ArraySchema:
+		return "[]" + g.goType(s.Items, hint+"Item", nil)
+	case *avro.MapSchema:
+		return "map[string]" + g.goType(s.Values, hint+"Value", nil)
+	case *avro.UnionSchema:
+		return g.union(s, hint, ancestors)
+	}
+	return "interface{}"
+}
+
+func (g *generator) primitiveType(s *avro.PrimitiveSchema) string {
+	if s.LogicalType != nil {
+		switch s.LogicalType.Name {
+		case "date", "timestamp-millis", "timestamp-micros", "local-timestamp-millis", "local-timestamp-micros":
+			g.imports["time"] = true
+			return "time.Time"
+		case "time-millis", "time-micros":
+			g.imports["time"] = true
+			return "time.Duration"
+		case "decimal":
+			g.imports["math/big"] = true
+			return "*big.Rat"
+		}
+	}
+	switch s.Primitive {
+	case avro.Boolean:
+		return "bool"
+	case avro.Int:
+		return "int32"
+	case avro.Long:
+		return "int64"
+	case avro.Float:
+		return "float32"
+	case avro.Double:
+		return "float64"
+	case avro.Bytes:
+		return "[]byte"
+	case avro.String:
+		return "string"
+	}
+	return "interface{}" // null
+}
+
+func (g *generator) record(s *avro.RecordSchema, name string, ancestors map[string]bool) {
+	if g.generated[s.Name] {
+		return
+	}
+	g.generated[s.Name] = true
+
+	inner := map[string]bool{s.Name: true}
+	for a := range ancestors {
+		inner[a] = true
+	}
+
+	// reserve the position so that the record precedes the types of its fields
+	g.decls = append(g.decls, "")
+	index := len(g.decls) - 1
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// %s is generated from record `%s`.\n", name, s.Name)
+	writeDoc(buf, s.Doc)
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	fieldNames := make(map[string]bool)
+	for _, f := range s.Fields {
+		fieldName := camelCase(f.Name)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", camelCase(f.Name), i)
+		}
+		fieldNames[fieldName] = true
+
+		writeDoc(buf, f.Doc)
+		fmt.Fprintf(buf, "%s %s `avro:%s`\n", fieldName, g.goType(f.Type, name+fieldName, inner), strconv.Quote(f.Name))
+	}
+	buf.WriteString("}\n")
+	g.decls[index] = buf.String()
+}
+
+func (g *generator) enum(s *avro.EnumSchema, name string) {
+	if g.generated[s.Name] {
+		return
+	}
+	g.generated[s.Name] = true
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// %s is generated from enum `%s`.\n", name, s.Name)
+	writeDoc(buf, s.Doc)
+	fmt.Fprintf(buf, "type %s string\n\n", name)
+	fmt.Fprintf(buf, "// Symbols of %s\nconst (\n", name)
+	for _, symbol := range s.Symbols {
+		// symbols such as `a` and `A` are converted into the same name
+		fmt.Fprintf(buf, "%s %s = %s\n", g.uniqueName(name+symbolName(symbol)), name, strconv.Quote(symbol))
+	}
+	buf.WriteString(")\n")
+	g.decl("%s", buf.String())
+}
+
+// union returns a pointer type for a union of null and another type, otherwise generates a struct that implements serde.Union.
+func (g *generator) union(s *avro.UnionSchema, hint string, ancestors map[string]bool) string {
+	branches := make([]avro.Schema, 0, len(s.Types))
+	nullable := false
+	for _, t := range s.Types {
+		if t.Type() == avro.Null {
+			nullable = true
+		} else {
+			branches = append(branches, t)
+		}
+	}
+
+	switch {
+	case len(branches) == 0:
+		return "interface{}"
+	case len(branches) == 1 && !nullable:
+		return g.goType(branches[0], hint, ancestors)
+	case len(branches) == 1:
+		return pointerTo(g.goType(branches[0], hint, ancestors))
+	}
+
+	name := g.uniqueName(hint)
+	g.decls = append(g.decls, "")
+	index := len(g.decls) - 1
+
+	typeNames := make([]string, 0, len(s.Types))
+	for _, t := range s.Types {
+		typeNames = append(typeNames, avro.TypeName(t))
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// %s is a union of %s. At most one of its fields is set", name, strings.Join(typeNames, ", "))
+	if nullable {
+		buf.WriteString(" and it is null if none is set")
+	}
+	fmt.Fprintf(buf, ".\ntype %s struct {\n", name)
+	fieldNames := make(map[string]bool)
+	for _, t := range branches {
+		fieldName := branchName(t, g.names)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", branchName(t, g.names), i)
+		}
+		fieldNames[fieldName] = true
+		// branches are held by pointers, so records need not be
+		fmt.Fprintf(buf, "%s %s `avro:%s`\n", fieldName, pointerTo(g.goType(t, name+fieldName, nil)), strconv.Quote(avro.TypeName(t)))
+	}
+	fmt.Fprintf(buf, "}\n\n// AvroUnion implements serde.Union.\nfunc (%s) AvroUnion() {}\n", name)
+	g.decls[index] = buf.String()
+	return name
+}
+
+// branchName returns a field name of a union struct for the branch s.
+func branchName(s avro.Schema, names map[string]string) string {
+	if named, ok := s.(avro.NamedSchema); ok {
+		return names[named.FullName()]
+	}
+	return camelCase(string(s.Type()))
+}
+
+func pointerTo(t string) string {
+	if strings.HasPrefix(t, "*") || t == "interface{}" {
+		return t
+	}
+	return "*" + t
+}
+
+// camelCase converts an Avro name such as `first_name` or `userId` into an exported Go name.
+func camelCase(name string) string {
+	return joinWords(name, false)
+}
+
+// symbolName converts an enum symbol such as `GUEST_USER` into a part of an exported Go name, e.g. `GuestUser`.
+func symbolName(symbol string) string {
+	return joinWords(symbol, true)
+}
+
+func joinWords(name string, lowerUpperCase bool) string {
+	buf := new(bytes.Buffer)
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if lowerUpperCase && strings.ToUpper(word) == word {
+			word = strings.ToLower(word)
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		buf.WriteString(string(runes))
+	}
+	if buf.Len() == 0 || unicode.IsDigit([]rune(buf.String())[0]) {
+		return "X" + buf.String()
+	}
+	return buf.String()
+}
+
+func writeDoc(buf *bytes.Buffer, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		fmt.Fprintf(buf, "// %s\n", strings.TrimSpace(line))
+	}
+}
+
+// quote returns a raw string literal of s if possible, otherwise an interpreted one.
+func quote(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,172 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+const personDef = `{"type": "record", "name": "Person", "namespace": "com.example", "doc": "A person.",
+ "fields": [
+  {"name": "id", "type": "long", "doc": "unique ID"},
+  {"name": "first_name", "type": "string"},
+  {"name": "nickname", "type": ["null", "string"], "default": null},
+  {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "GUEST_USER"]}},
+  {"name": "address", "type": {"type": "record", "name": "Address", "fields": [{"name": "owner", "type": ["null", "Person"]}]}},
+  {"name": "contact", "type": ["null", "string", {"type": "record", "name": "Phone", "fields": [{"name": "number", "type": "string"}]}]},
+  {"name": "birthday", "type": {"type": "int", "logicalType": "date"}},
+  {"name": "updated_at", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+  {"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+  {"name": "hash", "type": {"type": "fixed", "name": "MD5", "size": 16}},
+  {"name": "friends", "type": {"type": "array", "items": "Person"}}
+ ]}`
+
+// typeCheck parses and type-checks the generated source and returns the declared package.
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code cannot be parsed: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("events", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, src)
+	}
+	return pkg
+}
+
+func TestGo(t *testing.T) {
+	schema := &model.Schema{Id: 12, Subject: "user-events", Version: model.SemVer{Major: 1, Minor: 2, Patch: 0}, Definition: personDef}
+	src, err := Go(schema, "events")
+	if err != nil {
+		t.Fatalf("Go should not be an error, but an error was occurred: %v", err)
+	}
+	pkg := typeCheck(t, src)
+
+	expectTypes := map[string]string{
+		"Person":        "struct{Id int64 \"avro:\\\"id\\\"\"; FirstName string \"avro:\\\"first_name\\\"\"; Nickname *string \"avro:\\\"nickname\\\"\"; Kind events.Kind \"avro:\\\"kind\\\"\"; Address events.Address \"avro:\\\"address\\\"\"; Contact events.PersonContact \"avro:\\\"contact\\\"\"; Birthday time.Time \"avro:\\\"birthday\\\"\"; UpdatedAt *time.Time \"avro:\\\"updated_at\\\"\"; Balance *math/big.Rat \"avro:\\\"balance\\\"\"; Hash events.MD5 \"avro:\\\"hash\\\"\"; Friends []events.Person \"avro:\\\"friends\\\"\"}",
+		"Address":       "struct{Owner *events.Person \"avro:\\\"owner\\\"\"}",
+		"PersonContact": "struct{String *string \"avro:\\\"string\\\"\"; Phone *events.Phone \"avro:\\\"com.example.Phone\\\"\"}",
+		"Kind":          "string",
+		"MD5":           "[16]byte",
+	}
+	for name, expect := range expectTypes {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			t.Errorf("type %s is not generated", name)
+			continue
+		}
+		if actual := obj.Type().Underlying().String(); actual != expect {
+			t.Errorf("type %s = %s, wants %s", name, actual, expect)
+		}
+	}
+
+	expectConsts := map[string]string{
+		"PersonSubject":  `"user-events"`,
+		"PersonVersion":  `"v1.2.0"`,
+		"PersonSchemaId": "12",
+		"KindGuestUser":  `"GUEST_USER"`,
+	}
+	for name, expect := range expectConsts {
+		c, ok := pkg.Scope().Lookup(name).(*types.Const)
+		if !ok || c.Val().String() != expect {
+			t.Errorf("constant %s = %v, wants %s", name, pkg.Scope().Lookup(name), expect)
+		}
+	}
+	if !strings.Contains(string(src), "func (PersonContact) AvroUnion() {}") {
+		t.Errorf("union struct is expected to implement serde.Union\n%s", src)
+	}
+}
+
+func TestGoUnnamed(t *testing.T) {
+	cases := map[string]string{
+		`{"type": "array", "items": "string"}`: "[]string",
+		`["null", "int", "string"]`:            "struct{Int *int32 \"avro:\\\"int\\\"\"; String *string \"avro:\\\"string\\\"\"}",
+	}
+	for definition, expect := range cases {
+		src, err := Go(&model.Schema{Id: 1, Subject: "user-events", Version: model.SemVer{Major: 1}, Definition: definition}, "events")
+		if err != nil {
+			t.Fatalf("Go should not be an error, but an error was occurred: %v", err)
+		}
+		obj := typeCheck(t, src).Scope().Lookup("UserEvents")
+		if obj == nil || obj.Type().Underlying().String() != expect {
+			t.Errorf("type UserEvents generated from %s = %v, wants %s", definition, obj, expect)
+		}
+	}
+}
+
+func TestGoNameCollisions(t *testing.T) {
+	cases := map[string][]string{
+		`{"type": "enum", "name": "E", "symbols": ["a", "A", "b_c", "B_C"]}`:                                                      {"EA", "EA2", "EBC", "EBC2"},
+		`{"type": "enum", "name": "E", "symbols": ["Subject", "Version", "Schema"]}`:                                              {"ESubject", "ESubject2", "EVersion2", "ESchema2"},
+		`{"type": "record", "name": "R", "fields": [{"name": "s", "type": {"type": "record", "name": "RSchema", "fields": []}}]}`: {"R", "RSchema", "RSchema2"},
+	}
+	for definition, names := range cases {
+		src, err := Go(&model.Schema{Id: 1, Subject: "user-events", Version: model.SemVer{Major: 1}, Definition: definition}, "events")
+		if err != nil {
+			t.Fatalf("Go should not be an error, but an error was occurred: %v", err)
+		}
+		pkg := typeCheck(t, src)
+		for _, name := range names {
+			if pkg.Scope().Lookup(name) == nil {
+				t.Errorf("%s is expected to be declared from %s\n%s", name, definition, src)
+			}
+		}
+	}
+
+	// an unnamed root is not named after the subject if the constants collide with a named type
+	src, err := Go(&model.Schema{Id: 1, Subject: "user", Version: model.SemVer{Major: 1}, Definition: `{"type": "array", "items": {"type": "enum", "name": "UserVersion", "symbols": ["V1"]}}`}, "events")
+	if err != nil {
+		t.Fatalf("Go should not be an error, but an error was occurred: %v", err)
+	}
+	if pkg := typeCheck(t, src); pkg.Scope().Lookup("UserRootVersion") == nil {
+		t.Errorf("the constants are expected to be prefixed with UserRoot\n%s", src)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	cases := map[string]string{
+		"first_name":  "FirstName",
+		"userId":      "UserId",
+		"user-events": "UserEvents",
+		"MD5":         "MD5",
+		"_1st":        "X1st",
+	}
+	for name, expect := range cases {
+		if actual := camelCase(name); actual != expect {
+			t.Errorf("camelCase(%s) = %s, wants %s", name, actual, expect)
+		}
+	}
+	if actual := symbolName("GUEST_USER"); actual != "GuestUser" {
+		t.Errorf("symbolName(GUEST_USER) = %s, wants GuestUser", actual)
+	}
+}
@@ -0,0 +1,208 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compatibility
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// IsCompatible reports whether data encoded with writer can be decoded with reader.
+// It follows SchemaCompatibility.checkReaderWriterCompatibility of Avro 1.8 that a typebook server uses,
+// so string and bytes are not promoted to each other unlike the Avro specification.
+func IsCompatible(reader, writer avro.Schema) bool {
+	return len(Incompatibilities(reader, writer)) == 0
+}
+
+// Incompatibilities returns every reason why data encoded with writer cannot be decoded with reader.
+// It is empty if they are compatible. Direction of the returned values is left empty.
+func Incompatibilities(reader, writer avro.Schema) []model.Incompatibility {
+	c := &checker{inProgress: make(map[schemaPair]bool), incompatibilities: make([]model.Incompatibility, 0)}
+	c.check(reader, writer, "$")
+	return c.incompatibilities
+}
+
+type schemaPair struct {
+	reader, writer avro.Schema
+}
+
+type checker struct {
+	inProgress        map[schemaPair]bool
+	incompatibilities []model.Incompatibility
+}
+
+// report records an incompatibility. writer is nil if the reader's field is missing in the writer.
+func (c *checker) report(kind, path string, reader, writer avro.Schema, format string, args ...interface{}) {
+	incompatibility := model.Incompatibility{
+		Path:    path,
+		Kind:    kind,
+		Reader:  avro.TypeName(reader),
+		Message: fmt.Sprintf(format, args...),
+	}
+	if writer != nil {
+		incompatibility.Writer = avro.TypeName(writer)
+	}
+	c.incompatibilities = append(c.incompatibilities, incompatibility)
+}
+
+// check records incompatibilities between reader and writer at path and reports whether they are compatible.
+func (c *checker) check(reader, writer avro.Schema, path string) bool {
+	pair := schemaPair{reader, writer}
+	if c.inProgress[pair] {
+		// assumed to be compatible while recursive types are being checked
+		return true
+	}
+	c.inProgress[pair] = true
+	defer delete(c.inProgress, pair)
+
+	before := len(c.incompatibilities)
+	c.calculate(reader, writer, path)
+	return len(c.incompatibilities) == before
+}
+
+// compatible reports whether reader and writer are compatible without recording incompatibilities.
+func (c *checker) compatible(reader, writer avro.Schema, path string) bool {
+	before := len(c.incompatibilities)
+	result := c.check(reader, writer, path)
+	c.incompatibilities = c.incompatibilities[:before]
+	return result
+}
+
+func (c *checker) calculate(reader, writer avro.Schema, path string) {
+	if reader.Type() == writer.Type() {
+		switch r := reader.(type) {
+		case *avro.ArraySchema:
+			c.check(r.Items, writer.(*avro.ArraySchema).Items, path+"[]")
+		case *avro.MapSchema:
+			c.check(r.Values, writer.(*avro.MapSchema).Values, path+"{}")
+		case *avro.FixedSchema:
+			w := writer.(*avro.FixedSchema)
+			if !nameEquals(r, w) {
+				c.report(model.NameMismatch, path, r, w, "expected name `%s` but found `%s`", w.Name, r.Name)
+			} else if r.Size != w.Size {
+				c.report(model.FixedSizeMismatch, path, r, w, "expected size %d but found %d", w.Size, r.Size)
+			}
+		case *avro.EnumSchema:
+			w := writer.(*avro.EnumSchema)
+			if !nameEquals(r, w) {
+				c.report(model.NameMismatch, path, r, w, "expected name `%s` but found `%s`", w.Name, r.Name)
+				return
+			}
+			for _, symbol := range w.Symbols {
+				if !r.HasSymbol(symbol) {
+					c.report(model.EnumSymbolRemoved, path, r, w, "symbol `%s` of the writer is missing in the reader", symbol)
+				}
+			}
+		case *avro.RecordSchema:
+			c.calculateRecord(r, writer.(*avro.RecordSchema), path)
+		case *avro.UnionSchema:
+			for _, branch := range writer.(*avro.UnionSchema).Types {
+				c.check(reader, branch, path)
+			}
+		}
+		return
+	}
+
+	if w, ok := writer.(*avro.UnionSchema); ok {
+		for _, branch := range w.Types {
+			c.check(reader, branch, path)
+		}
+		return
+	}
+
+	switch reader.Type() {
+	case avro.Long:
+		if writer.Type() == avro.Int {
+			return
+		}
+	case avro.Float:
+		if writer.Type() == avro.Int || writer.Type() == avro.Long {
+			return
+		}
+	case avro.Double:
+		if writer.Type() == avro.Int || writer.Type() == avro.Long || writer.Type() == avro.Float {
+			return
+		}
+	case avro.Union:
+		for _, branch := range reader.(*avro.UnionSchema).Types {
+			if c.compatible(branch, writer, path) {
+				return
+			}
+		}
+		c.report(model.MissingUnionBranch, path, reader, writer, "the reader's union has no type that can read `%s`", avro.TypeName(writer))
+		return
+	}
+	c.report(model.TypeMismatch, path, reader, writer, "`%s` cannot be read as `%s`", avro.TypeName(writer), avro.TypeName(reader))
+}
+
+func (c *checker) calculateRecord(r, w *avro.RecordSchema, path string) {
+	if !nameEquals(r, w) {
+		c.report(model.NameMismatch, path, r, w, "expected name `%s` but found `%s`", w.Name, r.Name)
+		return
+	}
+	for _, rf := range r.Fields {
+		fieldPath := path + "." + rf.Name
+		found := writerFields(w, rf)
+		switch len(found) {
+		case 0:
+			if !rf.HasDefault {
+				c.report(model.MissingDefault, fieldPath, rf.Type, nil, "field `%s` is missing in the writer and has no default", rf.Name)
+			}
+		case 1:
+			c.check(rf.Type, found[0].Type, fieldPath)
+		default:
+			names := make([]string, len(found))
+			for i, f := range found {
+				names[i] = f.Name
+			}
+			c.report(model.AmbiguousField, fieldPath, rf.Type, nil, "field `%s` matches multiple fields of the writer: %s", rf.Name, strings.Join(names, ", "))
+		}
+	}
+}
+
+// nameEquals compares names of named types without their namespaces, or checks aliases of the reader.
+func nameEquals(reader, writer avro.NamedSchema) bool {
+	if avro.ShortName(reader.FullName()) == avro.ShortName(writer.FullName()) {
+		return true
+	}
+	for _, alias := range reader.AliasNames() {
+		if alias == writer.FullName() {
+			return true
+		}
+	}
+	return false
+}
+
+// writerFields looks up fields of writer which correspond to rf by its name and aliases.
+func writerFields(writer *avro.RecordSchema, rf *avro.Field) []*avro.Field {
+	var found []*avro.Field
+	for _, name := range append([]string{rf.Name}, rf.Aliases...) {
+		if wf := writer.Field(name); wf != nil {
+			found = append(found, wf)
+		}
+	}
+	return found
+}
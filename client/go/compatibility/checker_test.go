@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compatibility
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func mustParse(t *testing.T, definition string) avro.Schema {
+	s, err := avro.Parse(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestIncompatibilities(t *testing.T) {
+	reader := mustParse(t, `{"type": "record", "name": "User", "fields": [
+	  {"name": "id", "type": "int"},
+	  {"name": "email", "type": "string"},
+	  {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN"]}},
+	  {"name": "tags", "type": {"type": "array", "items": "int"}},
+	  {"name": "address", "type": {"type": "record", "name": "Location", "fields": []}},
+	  {"name": "nickname", "type": ["null", "string"], "default": null}
+	]}`)
+	writer := mustParse(t, `{"type": "record", "name": "User", "fields": [
+	  {"name": "id", "type": "long"},
+	  {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "GUEST", "MEMBER"]}},
+	  {"name": "tags", "type": {"type": "array", "items": "string"}},
+	  {"name": "address", "type": {"type": "record", "name": "Address", "fields": []}},
+	  {"name": "nickname", "type": "boolean"}
+	]}`)
+
+	expect := []model.Incompatibility{
+		{Path: "$.id", Kind: model.TypeMismatch, Reader: "int", Writer: "long", Message: "`long` cannot be read as `int`"},
+		{Path: "$.email", Kind: model.MissingDefault, Reader: "string", Message: "field `email` is missing in the writer and has no default"},
+		{Path: "$.kind", Kind: model.EnumSymbolRemoved, Reader: "Kind", Writer: "Kind", Message: "symbol `GUEST` of the writer is missing in the reader"},
+		{Path: "$.kind", Kind: model.EnumSymbolRemoved, Reader: "Kind", Writer: "Kind", Message: "symbol `MEMBER` of the writer is missing in the reader"},
+		{Path: "$.tags[]", Kind: model.TypeMismatch, Reader: "int", Writer: "string", Message: "`string` cannot be read as `int`"},
+		{Path: "$.address", Kind: model.NameMismatch, Reader: "Location", Writer: "Address", Message: "expected name `Address` but found `Location`"},
+		{Path: "$.nickname", Kind: model.MissingUnionBranch, Reader: "union", Writer: "boolean", Message: "the reader's union has no type that can read `boolean`"},
+	}
+	if actual := Incompatibilities(reader, writer); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Incompatibilities =\n%v\nwants\n%v", actual, expect)
+	}
+	if IsCompatible(reader, writer) {
+		t.Errorf("IsCompatible should be false")
+	}
+}
+
+func TestReport(t *testing.T) {
+	target := mustParse(t, `{"type": "record", "name": "User", "fields": [
+	  {"name": "name", "type": "string"},
+	  {"name": "color", "type": "string"}
+	]}`)
+	comparison := mustParse(t, `{"type": "record", "name": "User", "fields": [
+	  {"name": "name", "type": "string"}
+	]}`)
+
+	report := Report(target, comparison)
+	if report.IsCompatible || report.Compatibility != string(Forward) {
+		t.Errorf("Report = %v, wants an incompatible report with FORWARD", report)
+	}
+	if len(report.Incompatibilities) != 1 || report.Incompatibilities[0].Direction != string(Backward) || report.Incompatibilities[0].Path != "$.color" {
+		t.Errorf("Incompatibilities = %v, wants a missing default of $.color breaking backward compatibility", report.Incompatibilities)
+	}
+
+	report = Report(comparison, target)
+	if !report.IsCompatible || report.Compatibility != string(Backward) || len(report.Incompatibilities) != 1 || report.Incompatibilities[0].Direction != string(Forward) {
+		t.Errorf("Report = %v, wants a compatible report with BACKWARD", report)
+	}
+}
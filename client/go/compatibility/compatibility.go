@@ -0,0 +1,132 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package compatibility computes compatibility between Avro schemas locally
+// in the same way as CompatibilityUtil of a typebook server.
+package compatibility
+
+import (
+	"fmt"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Level is one of 4 types of Avro schema compatibility. It is also used as a compatibility restriction of a subject.
+type Level string
+
+const (
+	// None means that data encoded by either schema cannot be decoded with another one.
+	None Level = "NONE"
+	// Forward means that data encoded by a target schema can be decoded with a comparison but not vice versa.
+	Forward Level = "FORWARD"
+	// Backward means that data encoded by a comparison can be decoded with a target schema but not vice versa.
+	Backward Level = "BACKWARD"
+	// Full means that data encoded by either schema can be decoded with another one.
+	Full Level = "FULL"
+)
+
+// ParseLevel returns the Level of the given name.
+func ParseLevel(name string) (Level, error) {
+	switch l := Level(name); l {
+	case None, Forward, Backward, Full:
+		return l, nil
+	}
+	return "", fmt.Errorf("`%s` is not a compatibility. Valid values are NONE, FORWARD, BACKWARD or FULL", name)
+}
+
+// IsStrongerThanOrEqualTo reports whether l is a stronger restriction than other or the both are equal.
+func (l Level) IsStrongerThanOrEqualTo(other Level) bool {
+	switch other {
+	case None:
+		return true
+	case Forward:
+		return l == Forward || l == Full
+	case Backward:
+		return l == Backward || l == Full
+	case Full:
+		return l == Full
+	}
+	return false
+}
+
+// IsWeakerThanOrEqualTo reports whether l is a weaker restriction than other or the both are equal.
+func (l Level) IsWeakerThanOrEqualTo(other Level) bool {
+	switch other {
+	case None:
+		return l == None
+	case Forward:
+		return l == Forward || l == None
+	case Backward:
+		return l == Backward || l == None
+	case Full:
+		return true
+	}
+	return false
+}
+
+// Calculate returns the compatibility of target with comparison.
+func Calculate(target, comparison avro.Schema) Level {
+	return levelOf(IsCompatible(target, comparison), IsCompatible(comparison, target))
+}
+
+func levelOf(backward, forward bool) Level {
+	switch {
+	case backward && forward:
+		return Full
+	case backward:
+		return Backward
+	case forward:
+		return Forward
+	default:
+		return None
+	}
+}
+
+// Report checks target against comparison in both directions and returns every incompatibility found.
+// Incompatibilities that break backward compatibility, i.e. target cannot read data written with comparison,
+// come first with the direction BACKWARD, followed by ones with FORWARD.
+func Report(target, comparison avro.Schema) *model.CompatibilityReport {
+	backward := Incompatibilities(target, comparison)
+	forward := Incompatibilities(comparison, target)
+	for i := range backward {
+		backward[i].Direction = string(Backward)
+	}
+	for i := range forward {
+		forward[i].Direction = string(Forward)
+	}
+	return &model.CompatibilityReport{
+		IsCompatible:      len(backward) == 0,
+		Compatibility:     string(levelOf(len(backward) == 0, len(forward) == 0)),
+		Incompatibilities: append(backward, forward...),
+	}
+}
+
+// Check reports whether target obeys restriction comparing with all of existing schemas.
+func Check(target avro.Schema, existing []avro.Schema, restriction Level) bool {
+	for _, comparison := range existing {
+		if !Calculate(target, comparison).IsStrongerThanOrEqualTo(restriction) {
+			return false
+		}
+	}
+	return true
+}
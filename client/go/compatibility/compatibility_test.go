@@ -0,0 +1,126 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compatibility
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+)
+
+// fixturesPath is shared with the test of CompatibilityUtil of a typebook server
+// to confirm that the both agree with each other.
+const fixturesPath = "../../../core/src/test/resources/compatibility/fixtures.json"
+
+type fixture struct {
+	Name          string          `json:"name"`
+	Target        json.RawMessage `json:"target"`
+	Comparison    json.RawMessage `json:"comparison"`
+	Compatibility Level           `json:"compatibility"`
+}
+
+func TestCalculateWithSharedFixtures(t *testing.T) {
+	content, err := ioutil.ReadFile(fixturesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixtures []fixture
+	if err := json.Unmarshal(content, &fixtures); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range fixtures {
+		target, err := avro.Parse(string(f.Target))
+		if err != nil {
+			t.Errorf("%s: target should be a valid schema, but %v", f.Name, err)
+			continue
+		}
+		comparison, err := avro.Parse(string(f.Comparison))
+		if err != nil {
+			t.Errorf("%s: comparison should be a valid schema, but %v", f.Name, err)
+			continue
+		}
+		if actual := Calculate(target, comparison); actual != f.Compatibility {
+			t.Errorf("%s: Calculate = %s, wants %s", f.Name, actual, f.Compatibility)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	parse := func(fields string) avro.Schema {
+		s, err := avro.Parse(`{"type": "record", "name": "user", "fields": [` + fields + `]}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	original := parse(`{"name": "name", "type": "string"}`)
+	compatiblyEvolved := parse(`{"name": "name", "type": "string"}, {"name": "color", "type": "string", "default": "green"}`)
+	incompatiblyEvolved := parse(`{"name": "name", "type": "string"}, {"name": "color", "type": "string"}`)
+	existing := []avro.Schema{compatiblyEvolved, original}
+
+	cases := []struct {
+		target      avro.Schema
+		existing    []avro.Schema
+		restriction Level
+		expect      bool
+	}{
+		{incompatiblyEvolved, existing, Forward, true},
+		{incompatiblyEvolved, existing, None, true},
+		{incompatiblyEvolved, nil, Full, true},
+		{incompatiblyEvolved, existing, Full, false},
+		{incompatiblyEvolved, existing, Backward, false},
+	}
+	for _, c := range cases {
+		if actual := Check(c.target, c.existing, c.restriction); actual != c.expect {
+			t.Errorf("Check(%s, %d schemas, %s) = %v, wants %v", avro.CanonicalForm(c.target), len(c.existing), c.restriction, actual, c.expect)
+		}
+	}
+}
+
+func TestLevelStrength(t *testing.T) {
+	levels := []Level{None, Backward, Forward, Full}
+	// stronger[i][j] is whether levels[i] is stronger than or equal to levels[j]
+	stronger := [][]bool{
+		{true, false, false, false},
+		{true, true, false, false},
+		{true, false, true, false},
+		{true, true, true, true},
+	}
+	for i, l := range levels {
+		for j, other := range levels {
+			if actual := l.IsStrongerThanOrEqualTo(other); actual != stronger[i][j] {
+				t.Errorf("%s.IsStrongerThanOrEqualTo(%s) = %v, wants %v", l, other, actual, stronger[i][j])
+			}
+			if actual := other.IsWeakerThanOrEqualTo(l); actual != stronger[i][j] {
+				t.Errorf("%s.IsWeakerThanOrEqualTo(%s) = %v, wants %v", other, l, actual, stronger[i][j])
+			}
+		}
+	}
+
+	if _, err := ParseLevel("PARTIAL"); err == nil {
+		t.Errorf("ParseLevel(PARTIAL) should be an error")
+	}
+}
@@ -23,9 +23,9 @@
 package _go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
 
 	"github.com/cyberagent/typebook/client/go/model"
 )
@@ -38,48 +38,49 @@ type configClient struct {
 // It will create or update a whole config of the subject with the given name.
 // This method returns the number of updated rows in the backend database otherwise non-nil model.Error is returned on failure.
 func (cc *configClient) SetConfig(subject string, config model.Config) (int64, *model.Error) {
-	response, body, errs := cc.baseClient.
-		Put(fmt.Sprintf("/config/%s", subject)).
-		Type("json").
-		Send(config).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
+	return cc.SetConfigContext(context.Background(), subject, config)
+}
 
-	affectedRows, err := strconv.ParseInt(string(body), 10, 64)
+// SetConfigContext is the same as SetConfig except that the request is bound to the given context.
+func (cc *configClient) SetConfigContext(ctx context.Context, subject string, config model.Config) (int64, *model.Error) {
+	content, jsonErr := json.Marshal(config)
+	if jsonErr != nil {
+		return -1, model.NewError(nil, []error{jsonErr})
+	}
+	body, err := cc.baseClient.Put(ctx, fmt.Sprintf("/config/%s", subject), contentTypeJSON, content)
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return affectedRows, nil
+	return parseInt(body)
 }
 
 // SetConfig issues a PUT /config/(subject string)/properties/(property string) request with its value in its body to a typebook server.
 // It will create or update a specific property of config of a subject which has the given name.
 // This method returns the number of updated rows so normally it is 1 for success, otherwise non-nil model.Error is returned.
 func (cc *configClient) SetProperty(subject, name, value string) (int64, *model.Error) {
-	response, body, errs := cc.baseClient.
-		Put(fmt.Sprintf("/config/%s/properties/%s", subject, name)).
-		Type("text").
-		Send(value).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
+	return cc.SetPropertyContext(context.Background(), subject, name, value)
+}
 
-	affectedRows, err := strconv.ParseInt(string(body), 10, 64)
+// SetPropertyContext is the same as SetProperty except that the request is bound to the given context.
+func (cc *configClient) SetPropertyContext(ctx context.Context, subject, name, value string) (int64, *model.Error) {
+	body, err := cc.baseClient.Put(ctx, fmt.Sprintf("/config/%s/properties/%s", subject, name), contentTypeText, []byte(value))
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return affectedRows, nil
+	return parseInt(body)
 }
 
 // GetConfig issues a GET /config/(subject string) request to a typebook server.
 // It will retrieve a whole config belongs to a subject with the given name.
 // If it fails, non-nil model.Error is returned.
 func (cc *configClient) GetConfig(subject string) (*model.Config, *model.Error) {
-	response, body, errs := cc.baseClient.Get(fmt.Sprintf("/config/%s", subject)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return cc.GetConfigContext(context.Background(), subject)
+}
+
+// GetConfigContext is the same as GetConfig except that the request is bound to the given context.
+func (cc *configClient) GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error) {
+	body, err := cc.baseClient.Get(ctx, fmt.Sprintf("/config/%s", subject))
+	if err != nil {
 		return nil, err
 	}
 
@@ -94,8 +95,13 @@ func (cc *configClient) GetConfig(subject string) (*model.Config, *model.Error)
 // It will retrieve a specific property of config belongs to a subject with the given name.
 // If it fails, non-nil model.Error is returned.
 func (cc *configClient) GetProperty(subject, property string) (string, *model.Error) {
-	response, body, errs := cc.baseClient.Get(fmt.Sprintf("/config/%s/properties/%s", subject, property)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return cc.GetPropertyContext(context.Background(), subject, property)
+}
+
+// GetPropertyContext is the same as GetProperty except that the request is bound to the given context.
+func (cc *configClient) GetPropertyContext(ctx context.Context, subject, property string) (string, *model.Error) {
+	body, err := cc.baseClient.Get(ctx, fmt.Sprintf("/config/%s/properties/%s", subject, property))
+	if err != nil {
 		return "", err
 	}
 	return string(body), nil
@@ -105,16 +111,16 @@ func (cc *configClient) GetProperty(subject, property string) (string, *model.Er
 // It will delete a whole config belongs to a subject with the given name.
 // This method returns the number of deleted rows in the backend database or returns non-nil model.Error on failure.
 func (cc *configClient) DeleteConfig(subject string) (int64, *model.Error) {
-	response, body, errs := cc.baseClient.Delete(fmt.Sprintf("/config/%s", subject)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
+	return cc.DeleteConfigContext(context.Background(), subject)
+}
 
-	deletedRows, err := strconv.ParseInt(string(body), 10, 64)
+// DeleteConfigContext is the same as DeleteConfig except that the request is bound to the given context.
+func (cc *configClient) DeleteConfigContext(ctx context.Context, subject string) (int64, *model.Error) {
+	body, err := cc.baseClient.Delete(ctx, fmt.Sprintf("/config/%s", subject))
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return deletedRows, nil
+	return parseInt(body)
 }
 
 // DeleteProperty issues a DELETE /config/(subject string)/properties/(property string) request to a typebook server.
@@ -122,14 +128,14 @@ func (cc *configClient) DeleteConfig(subject string) (int64, *model.Error) {
 // This method returns the number of deleted rows in the backend database so normally it is 1.
 // Otherwise non-nil model.Error is returned.
 func (cc *configClient) DeleteProperty(subject, property string) (int64, *model.Error) {
-	response, body, errs := cc.baseClient.Delete(fmt.Sprintf("/config/%s/properties/%s", subject, property)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
+	return cc.DeletePropertyContext(context.Background(), subject, property)
+}
 
-	deletedRows, err := strconv.ParseInt(string(body), 10, 64)
+// DeletePropertyContext is the same as DeleteProperty except that the request is bound to the given context.
+func (cc *configClient) DeletePropertyContext(ctx context.Context, subject, property string) (int64, *model.Error) {
+	body, err := cc.baseClient.Delete(ctx, fmt.Sprintf("/config/%s/properties/%s", subject, property))
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return deletedRows, nil
+	return parseInt(body)
 }
@@ -0,0 +1,382 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package diff compares Avro schemas semantically, reporting added, removed and changed fields,
+// types, defaults and docs rather than differences of their texts.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Diff returns the changes from the schema from to the schema to.
+// It is empty if they are semantically the same, e.g. if only their fields are reordered.
+// Fields are matched by their names or the aliases of the fields of to, and branches of unions by their type names.
+// A type is promoted if data of the old type can be read as the new one, where string and bytes are not promoted to each other
+// as a typebook server checks compatibility.
+func Diff(from, to avro.Schema) []model.Change {
+	d := &differ{visited: make(map[schemaPair]bool), changes: make([]model.Change, 0)}
+	d.diff(from, to, "$")
+	return d.changes
+}
+
+type schemaPair struct {
+	from, to avro.Schema
+}
+
+type differ struct {
+	visited map[schemaPair]bool
+	changes []model.Change
+}
+
+func (d *differ) report(kind, path, from, to, format string, args ...interface{}) {
+	d.changes = append(d.changes, model.Change{
+		Path:    path,
+		Kind:    kind,
+		From:    from,
+		To:      to,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) diff(from, to avro.Schema, path string) {
+	if from.Type() != to.Type() {
+		d.diffTypes(from, to, path)
+		return
+	}
+
+	switch f := from.(type) {
+	case *avro.PrimitiveSchema:
+		d.diffLogicalTypes(f.LogicalType, to.(*avro.PrimitiveSchema).LogicalType, path)
+	case *avro.ArraySchema:
+		d.diff(f.Items, to.(*avro.ArraySchema).Items, path+"[]")
+	case *avro.MapSchema:
+		d.diff(f.Values, to.(*avro.MapSchema).Values, path+"{}")
+	case *avro.UnionSchema:
+		d.diffUnions(f, to.(*avro.UnionSchema), path)
+	case avro.NamedSchema:
+		pair := schemaPair{from, to}
+		if d.visited[pair] {
+			// a named type is compared only where it appears first, which also stops recursion
+			return
+		}
+		d.visited[pair] = true
+
+		t := to.(avro.NamedSchema)
+		if f.FullName() != t.FullName() {
+			d.report(model.NameChanged, path, f.FullName(), t.FullName(), "name is changed from `%s` to `%s`", f.FullName(), t.FullName())
+		}
+		d.diffAliases(f.AliasNames(), t.AliasNames(), path)
+
+		switch f := from.(type) {
+		case *avro.RecordSchema:
+			d.diffRecords(f, to.(*avro.RecordSchema), path)
+		case *avro.EnumSchema:
+			d.diffEnums(f, to.(*avro.EnumSchema), path)
+		case *avro.FixedSchema:
+			t := to.(*avro.FixedSchema)
+			if f.Size != t.Size {
+				d.report(model.SizeChanged, path, strconv.Itoa(f.Size), strconv.Itoa(t.Size), "size is changed from %d to %d", f.Size, t.Size)
+			}
+			d.diffLogicalTypes(f.LogicalType, t.LogicalType, path)
+		}
+	}
+}
+
+// diffTypes compares schemas of different types. A type which becomes a union with it, or vice versa, is compared with the branch.
+func (d *differ) diffTypes(from, to avro.Schema, path string) {
+	if union, ok := to.(*avro.UnionSchema); ok {
+		if i := branchOf(union, from); i >= 0 {
+			for j, branch := range union.Types {
+				if j != i {
+					d.report(model.UnionBranchAdded, path, "", typeString(branch), "`%s` is added to the union", typeString(branch))
+				}
+			}
+			d.diff(from, union.Types[i], path)
+			return
+		}
+	}
+	if union, ok := from.(*avro.UnionSchema); ok {
+		if i := branchOf(union, to); i >= 0 {
+			for j, branch := range union.Types {
+				if j != i {
+					d.report(model.UnionBranchRemoved, path, typeString(branch), "", "`%s` is removed from the union", typeString(branch))
+				}
+			}
+			d.diff(union.Types[i], to, path)
+			return
+		}
+	}
+
+	if isPromotable(from, to) {
+		d.report(model.TypePromoted, path, typeString(from), typeString(to), "`%s` is promoted to `%s`", typeString(from), typeString(to))
+	} else {
+		d.report(model.TypeChanged, path, typeString(from), typeString(to), "type is changed from `%s` to `%s`", typeString(from), typeString(to))
+	}
+}
+
+// diffUnions compares branches with the same type names, and reports the others as promoted, added or removed.
+func (d *differ) diffUnions(from, to *avro.UnionSchema, path string) {
+	var removed, added []avro.Schema
+	for _, f := range from.Types {
+		if t := branchByName(to, avro.TypeName(f)); t != nil {
+			d.diff(f, t, path)
+		} else {
+			removed = append(removed, f)
+		}
+	}
+	for _, t := range to.Types {
+		if branchByName(from, avro.TypeName(t)) == nil {
+			added = append(added, t)
+		}
+	}
+
+	for _, f := range removed {
+		promoted := -1
+		for i, t := range added {
+			if isPromotable(f, t) {
+				promoted = i
+				break
+			}
+		}
+		if promoted < 0 {
+			d.report(model.UnionBranchRemoved, path, typeString(f), "", "`%s` is removed from the union", typeString(f))
+			continue
+		}
+		t := added[promoted]
+		added = append(added[:promoted], added[promoted+1:]...)
+		d.report(model.TypePromoted, path, typeString(f), typeString(t), "`%s` in the union is promoted to `%s`", typeString(f), typeString(t))
+	}
+	for _, t := range added {
+		d.report(model.UnionBranchAdded, path, "", typeString(t), "`%s` is added to the union", typeString(t))
+	}
+}
+
+func (d *differ) diffRecords(from, to *avro.RecordSchema, path string) {
+	d.diffDocs(from.Doc, to.Doc, path)
+
+	matched := make(map[*avro.Field]bool)
+	for _, tf := range to.Fields {
+		fieldPath := path + "." + tf.Name
+		ff := from.Field(tf.Name)
+		if ff == nil {
+			for _, alias := range tf.Aliases {
+				if ff = from.Field(alias); ff != nil && !matched[ff] {
+					d.report(model.FieldRenamed, fieldPath, ff.Name, tf.Name, "field `%s` is renamed to `%s`", ff.Name, tf.Name)
+					break
+				}
+			}
+		}
+		if ff == nil || matched[ff] {
+			if tf.HasDefault {
+				d.report(model.FieldAdded, fieldPath, "", typeString(tf.Type), "field `%s` is added with default %s", tf.Name, defaultString(tf))
+			} else {
+				d.report(model.FieldAdded, fieldPath, "", typeString(tf.Type), "field `%s` is added without default", tf.Name)
+			}
+			continue
+		}
+		matched[ff] = true
+		d.diffFields(ff, tf, fieldPath)
+	}
+
+	for _, ff := range from.Fields {
+		if !matched[ff] {
+			d.report(model.FieldRemoved, path+"."+ff.Name, typeString(ff.Type), "", "field `%s` is removed", ff.Name)
+		}
+	}
+}
+
+func (d *differ) diffFields(from, to *avro.Field, path string) {
+	d.diff(from.Type, to.Type, path)
+
+	switch {
+	case !from.HasDefault && to.HasDefault:
+		d.report(model.DefaultAdded, path, "", defaultString(to), "default %s is added", defaultString(to))
+	case from.HasDefault && !to.HasDefault:
+		d.report(model.DefaultRemoved, path, defaultString(from), "", "default %s is removed", defaultString(from))
+	case from.HasDefault && to.HasDefault && defaultString(from) != defaultString(to):
+		d.report(model.DefaultChanged, path, defaultString(from), defaultString(to), "default is changed from %s to %s", defaultString(from), defaultString(to))
+	}
+
+	d.diffDocs(from.Doc, to.Doc, path)
+	d.diffAliases(from.Aliases, to.Aliases, path)
+	if orderOf(from) != orderOf(to) {
+		d.report(model.OrderChanged, path, orderOf(from), orderOf(to), "order is changed from %s to %s", orderOf(from), orderOf(to))
+	}
+}
+
+func (d *differ) diffEnums(from, to *avro.EnumSchema, path string) {
+	d.diffDocs(from.Doc, to.Doc, path)
+
+	for _, symbol := range to.Symbols {
+		if !from.HasSymbol(symbol) {
+			d.report(model.SymbolAdded, path, "", symbol, "symbol `%s` is added", symbol)
+		}
+	}
+	for _, symbol := range from.Symbols {
+		if !to.HasSymbol(symbol) {
+			d.report(model.SymbolRemoved, path, symbol, "", "symbol `%s` is removed", symbol)
+		}
+	}
+
+	f, t := quote(from.Default), quote(to.Default)
+	switch {
+	case from.Default == "" && to.Default != "":
+		d.report(model.DefaultAdded, path, "", t, "default %s is added", t)
+	case from.Default != "" && to.Default == "":
+		d.report(model.DefaultRemoved, path, f, "", "default %s is removed", f)
+	case from.Default != to.Default:
+		d.report(model.DefaultChanged, path, f, t, "default is changed from %s to %s", f, t)
+	}
+}
+
+func (d *differ) diffLogicalTypes(from, to *avro.LogicalType, path string) {
+	f, t := logicalTypeString(from), logicalTypeString(to)
+	switch {
+	case f == t:
+	case f == "":
+		d.report(model.LogicalTypeChanged, path, f, t, "logical type `%s` is added", t)
+	case t == "":
+		d.report(model.LogicalTypeChanged, path, f, t, "logical type `%s` is removed", f)
+	default:
+		d.report(model.LogicalTypeChanged, path, f, t, "logical type is changed from `%s` to `%s`", f, t)
+	}
+}
+
+func (d *differ) diffDocs(from, to, path string) {
+	if from != to {
+		d.report(model.DocChanged, path, from, to, "doc is changed")
+	}
+}
+
+func (d *differ) diffAliases(from, to []string, path string) {
+	f, t := sortedString(from), sortedString(to)
+	if f != t {
+		d.report(model.AliasesChanged, path, f, t, "aliases are changed from [%s] to [%s]", f, t)
+	}
+}
+
+// branchOf returns the index of the branch of union that s is compared with, or -1 if none.
+func branchOf(union *avro.UnionSchema, s avro.Schema) int {
+	for i, branch := range union.Types {
+		if avro.TypeName(branch) == avro.TypeName(s) {
+			return i
+		}
+	}
+	for i, branch := range union.Types {
+		if isPromotable(s, branch) {
+			return i
+		}
+	}
+	return -1
+}
+
+func branchByName(union *avro.UnionSchema, name string) avro.Schema {
+	for _, branch := range union.Types {
+		if avro.TypeName(branch) == name {
+			return branch
+		}
+	}
+	return nil
+}
+
+// isPromotable reports whether data of from can be read as to, which is a different primitive type.
+func isPromotable(from, to avro.Schema) bool {
+	switch from.Type() {
+	case avro.Int:
+		return to.Type() == avro.Long || to.Type() == avro.Float || to.Type() == avro.Double
+	case avro.Long:
+		return to.Type() == avro.Float || to.Type() == avro.Double
+	case avro.Float:
+		return to.Type() == avro.Double
+	}
+	return false
+}
+
+// typeString describes a type with its logical type, e.g. `int (date)`.
+func typeString(s avro.Schema) string {
+	var lt *avro.LogicalType
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		lt = s.LogicalType
+	case *avro.FixedSchema:
+		lt = s.LogicalType
+	case *avro.ArraySchema:
+		return "array<" + typeString(s.Items) + ">"
+	case *avro.MapSchema:
+		return "map<" + typeString(s.Values) + ">"
+	case *avro.UnionSchema:
+		branches := make([]string, len(s.Types))
+		for i, branch := range s.Types {
+			branches[i] = typeString(branch)
+		}
+		return "union<" + strings.Join(branches, ", ") + ">"
+	}
+	if lt != nil {
+		return fmt.Sprintf("%s (%s)", avro.TypeName(s), logicalTypeString(lt))
+	}
+	return avro.TypeName(s)
+}
+
+func logicalTypeString(lt *avro.LogicalType) string {
+	if lt == nil {
+		return ""
+	}
+	if lt.Name == "decimal" {
+		return fmt.Sprintf("decimal(%d, %d)", lt.Precision, lt.Scale)
+	}
+	return lt.Name
+}
+
+// defaultString returns the default of a field in JSON.
+func defaultString(f *avro.Field) string {
+	js, err := avro.MarshalDefault(f.Type, f.Default)
+	if err != nil {
+		return fmt.Sprint(f.Default)
+	}
+	return js
+}
+
+func orderOf(f *avro.Field) string {
+	if f.Order == "" {
+		return "ascending"
+	}
+	return f.Order
+}
+
+func quote(s string) string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+func sortedString(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
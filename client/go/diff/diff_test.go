@@ -0,0 +1,134 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func TestDiff(t *testing.T) {
+	from := mustParse(t, `{
+	  "type": "record",
+	  "name": "Person",
+	  "namespace": "com.example",
+	  "fields": [
+	    {"name": "id", "type": "int"},
+	    {"name": "name", "type": "string", "doc": "a name"},
+	    {"name": "age", "type": ["null", "int"], "default": null},
+	    {"name": "email", "type": "string", "default": "none"},
+	    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "INACTIVE"]}},
+	    {"name": "birthday", "type": {"type": "int", "logicalType": "date"}},
+	    {"name": "tags", "type": {"type": "array", "items": "string"}},
+	    {"name": "friend", "type": ["null", "Person"], "default": null},
+	    {"name": "nickname", "type": "string"}
+	  ]
+	}`)
+	to := mustParse(t, `{
+	  "type": "record",
+	  "name": "Person",
+	  "namespace": "com.example",
+	  "fields": [
+	    {"name": "id", "type": "long"},
+	    {"name": "full_name", "type": "string", "aliases": ["name"], "doc": "a full name"},
+	    {"name": "age", "type": ["null", "long", "string"], "default": null},
+	    {"name": "email", "type": ["null", "string"], "default": null},
+	    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "SUSPENDED"], "default": "ACTIVE"}},
+	    {"name": "birthday", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+	    {"name": "tags", "type": {"type": "array", "items": "bytes"}, "order": "ignore"},
+	    {"name": "friend", "type": ["null", "Person"], "default": null},
+	    {"name": "country", "type": "string", "default": "JP"}
+	  ]
+	}`)
+
+	expected := []model.Change{
+		{Path: "$.id", Kind: model.TypePromoted, From: "int", To: "long"},
+		{Path: "$.full_name", Kind: model.FieldRenamed, From: "name", To: "full_name"},
+		{Path: "$.full_name", Kind: model.DocChanged, From: "a name", To: "a full name"},
+		{Path: "$.full_name", Kind: model.AliasesChanged, From: "", To: "name"},
+		{Path: "$.age", Kind: model.TypePromoted, From: "int", To: "long"},
+		{Path: "$.age", Kind: model.UnionBranchAdded, To: "string"},
+		{Path: "$.email", Kind: model.UnionBranchAdded, To: "null"},
+		{Path: "$.email", Kind: model.DefaultChanged, From: `"none"`, To: "null"},
+		{Path: "$.status", Kind: model.SymbolAdded, To: "SUSPENDED"},
+		{Path: "$.status", Kind: model.SymbolRemoved, From: "INACTIVE"},
+		{Path: "$.status", Kind: model.DefaultAdded, To: `"ACTIVE"`},
+		{Path: "$.birthday", Kind: model.TypePromoted, From: "int (date)", To: "long (timestamp-millis)"},
+		{Path: "$.tags[]", Kind: model.TypeChanged, From: "string", To: "bytes"},
+		{Path: "$.tags", Kind: model.OrderChanged, From: "ascending", To: "ignore"},
+		{Path: "$.country", Kind: model.FieldAdded, To: "string"},
+		{Path: "$.nickname", Kind: model.FieldRemoved, From: "string"},
+	}
+
+	actual := Diff(from, to)
+	if len(actual) != len(expected) {
+		t.Fatalf("Diff returns %d changes, wants %d: %+v", len(actual), len(expected), actual)
+	}
+	for i, e := range expected {
+		a := actual[i]
+		if a.Path != e.Path || a.Kind != e.Kind || a.From != e.From || a.To != e.To || a.Message == "" {
+			t.Errorf("Diff()[%d] = %+v, wants %+v", i, a, e)
+		}
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	from := mustParse(t, `{"type": "record", "name": "Node", "fields": [
+	  {"name": "value", "type": "int"},
+	  {"name": "children", "type": {"type": "array", "items": "Node"}}
+	]}`)
+	to := mustParse(t, `{"type": "record", "name": "Node", "fields": [
+	  {"name": "children", "type": {"type": "array", "items": "Node"}},
+	  {"name": "value", "type": "int", "order": "ascending"}
+	]}`)
+
+	if changes := Diff(from, to); len(changes) != 0 {
+		t.Errorf("Diff of reordered fields is expected to be empty but got %+v", changes)
+	}
+}
+
+func TestDiffNamedTypes(t *testing.T) {
+	from := mustParse(t, `{"type": "fixed", "name": "com.example.Hash", "size": 16}`)
+	to := mustParse(t, `{"type": "fixed", "name": "com.example.Digest", "aliases": ["com.example.Hash"], "size": 32}`)
+
+	expected := []string{model.NameChanged, model.AliasesChanged, model.SizeChanged}
+	changes := Diff(from, to)
+	if len(changes) != len(expected) {
+		t.Fatalf("Diff returns %+v, wants changes of %v", changes, expected)
+	}
+	for i, kind := range expected {
+		if changes[i].Kind != kind || changes[i].Path != "$" {
+			t.Errorf("Diff()[%d] = %+v, wants %s at $", i, changes[i], kind)
+		}
+	}
+}
+
+func mustParse(t *testing.T, definition string) avro.Schema {
+	s, err := avro.Parse(definition)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", definition, err)
+	}
+	return s
+}
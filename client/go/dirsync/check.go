@@ -0,0 +1,165 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dirsync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Checker is a typebook server to check schemas against, such as *typebook.Client.
+type Checker interface {
+	GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error)
+	ReportCompatibilityWithLatestContext(ctx context.Context, subject, definition string) (*model.CompatibilityReport, *model.Error)
+	PredictNextVersionContext(ctx context.Context, subject, definition string) (*model.VersionPrediction, *model.Error)
+}
+
+// Kinds of Violation other than those of model.Incompatibility
+const (
+	// InvalidSchema means that the file is not a valid Avro schema.
+	InvalidSchema = "INVALID_SCHEMA"
+	// RestrictionViolated means that the schema is not compatible enough with an existing schema other than the latest one.
+	RestrictionViolated = "RESTRICTION_VIOLATED"
+)
+
+// Violation is a reason why a schema file would be rejected by a typebook server.
+// Kind is InvalidSchema, RestrictionViolated or a kind of model.Incompatibility, in which case Path points to the offending node.
+type Violation struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// CheckedSchema is the result of a check of a schema file.
+// Restriction is the compatibility configured to the subject and Version is the version that the schema would be given,
+// which is v1.0.0 if the subject has no schema yet. Report is the compatibility against the latest schema, which is nil without it.
+type CheckedSchema struct {
+	File        string                     `json:"file"`
+	Subject     string                     `json:"subject"`
+	Restriction string                     `json:"restriction"`
+	Version     string                     `json:"version"`
+	IsNew       bool                       `json:"is_new"`
+	Report      *model.CompatibilityReport `json:"report,omitempty"`
+	Violations  []Violation                `json:"violations"`
+}
+
+// IsAllowed reports whether the schema would be registered without violating the compatibility restriction.
+func (cs *CheckedSchema) IsAllowed() bool {
+	return len(cs.Violations) == 0
+}
+
+// Check checks the given schema files under dir, such as ones listed by ChangedFiles, without registering them.
+// Each file is checked against the latest schema under its subject with the compatibility configured to the subject,
+// and the version it would be given is predicted. Every file is checked independently of the others.
+// A file which is not a valid schema or violates the compatibility is reported with its violations rather than as an error,
+// so that an error is returned only when the server fails.
+func Check(ctx context.Context, checker Checker, dir string, files []string) ([]CheckedSchema, error) {
+	checked := make([]CheckedSchema, 0, len(files))
+	for _, file := range files {
+		c, err := checkFile(ctx, checker, dir, file)
+		if err != nil {
+			return checked, err
+		}
+		checked = append(checked, *c)
+	}
+	return checked, nil
+}
+
+func checkFile(ctx context.Context, checker Checker, dir, file string) (*CheckedSchema, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	first := model.SemVer{Major: 1}
+	c := &CheckedSchema{File: file, Subject: path.Dir(file), Version: first.String(), IsNew: true, Violations: make([]Violation, 0)}
+
+	definition := string(content)
+	if _, err := avro.Parse(definition); err != nil {
+		c.Violations = append(c.Violations, Violation{Kind: InvalidSchema, Message: err.Error()})
+		return c, nil
+	}
+
+	config, merr := checker.GetConfigContext(ctx, c.Subject)
+	if merr.IsNotFound() {
+		// the subject would be created by Sync
+		return c, nil
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	c.Restriction = config.Compatibility
+
+	report, merr := checker.ReportCompatibilityWithLatestContext(ctx, c.Subject, definition)
+	if merr.IsNotFound() {
+		// the subject has no schema yet
+		return c, nil
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	c.Report = report
+
+	prediction, merr := checker.PredictNextVersionContext(ctx, c.Subject, definition)
+	if merr != nil {
+		return nil, merr
+	}
+	c.Version = prediction.Version.String()
+	c.IsNew = prediction.IsNew
+
+	restriction := compatibility.None
+	if c.Restriction != "" {
+		if restriction, err = compatibility.ParseLevel(c.Restriction); err != nil {
+			return nil, err
+		}
+	}
+	if !compatibility.Level(report.Compatibility).IsStrongerThanOrEqualTo(restriction) {
+		for _, i := range report.Incompatibilities {
+			if isRestricted(restriction, i.Direction) {
+				c.Violations = append(c.Violations, Violation{Kind: i.Kind, Path: i.Path, Message: i.Message})
+			}
+		}
+	}
+	// the other schemas with the latest major version are compared by the prediction
+	for _, comparison := range prediction.Comparisons {
+		if comparison.Id == report.ComparisonId || compatibility.Level(comparison.Compatibility).IsStrongerThanOrEqualTo(restriction) {
+			continue
+		}
+		c.Violations = append(c.Violations, Violation{
+			Kind:    RestrictionViolated,
+			Message: fmt.Sprintf("compatibility with %s is %s, which violates %s", comparison.Version.String(), comparison.Compatibility, restriction),
+		})
+	}
+	return c, nil
+}
+
+// isRestricted reports whether an incompatibility in the direction violates the restriction.
+func isRestricted(restriction compatibility.Level, direction string) bool {
+	return restriction == compatibility.Full || string(restriction) == direction
+}
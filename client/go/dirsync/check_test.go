@@ -0,0 +1,199 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dirsync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+	"github.com/cyberagent/typebook/client/go/model"
+	"github.com/cyberagent/typebook/client/go/version"
+)
+
+// fakeChecker is an in-memory Checker on top of fakeServer, which calculates compatibility and versions locally.
+type fakeChecker struct {
+	*fakeServer
+	configs map[string]string
+}
+
+func (fc *fakeChecker) GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error) {
+	if !fc.subjects[subject] {
+		return nil, errNotFound
+	}
+	config := &model.Config{Compatibility: string(compatibility.None)}
+	if c, ok := fc.configs[subject]; ok {
+		config.Compatibility = c
+	}
+	return config, nil
+}
+
+func (fc *fakeChecker) ReportCompatibilityWithLatestContext(ctx context.Context, subject, definition string) (*model.CompatibilityReport, *model.Error) {
+	schemas := fc.schemas[subject]
+	if len(schemas) == 0 {
+		return nil, errNotFound
+	}
+	latest := schemas[len(schemas)-1]
+	target, err := avro.Parse(definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	comparison, err := avro.Parse(latest.Definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	report := compatibility.Report(target, comparison)
+	report.ComparisonId = latest.Id
+	report.ComparisonVersion = latest.Version.String()
+	return report, nil
+}
+
+func (fc *fakeChecker) PredictNextVersionContext(ctx context.Context, subject, definition string) (*model.VersionPrediction, *model.Error) {
+	target, err := avro.Parse(definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	schemas := fc.schemas[subject]
+	next, comparisons, err := version.Next(target, schemas)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	prediction := &model.VersionPrediction{Subject: subject, Version: next, IsNew: true, Comparisons: comparisons}
+	if latest := schemas[len(schemas)-1]; latest.Definition == definition {
+		prediction.Version = latest.Version
+		prediction.IsNew = false
+	}
+	return prediction, nil
+}
+
+const (
+	withName        = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string"}]}`
+	withDefaultName = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string", "default": ""}]}`
+)
+
+func TestCheck(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"user-events/v1.avsc":   v1,
+		"user-events/v2.avsc":   v2,
+		"user-events/v3.avsc":   v3,
+		"page-views/v1.avsc":    v1,
+		"clicks/v2.avsc":        withName,
+		"broken/broken.avsc":    `{"type": "record"`,
+		"empty-subject/v1.avsc": v1,
+	})
+	defer os.RemoveAll(dir)
+	server := newFakeServer()
+	for _, subject := range []string{"user-events", "clicks", "empty-subject"} {
+		server.subjects[subject] = true
+	}
+	server.schemas["user-events"] = []model.Schema{{Id: 1, Subject: "user-events", Version: model.SemVer{Major: 1}, Definition: v1}}
+	server.schemas["clicks"] = []model.Schema{
+		{Id: 2, Subject: "clicks", Version: model.SemVer{Major: 1}, Definition: v1},
+		{Id: 3, Subject: "clicks", Version: model.SemVer{Major: 1, Minor: 1}, Definition: withDefaultName},
+	}
+	checker := &fakeChecker{fakeServer: server, configs: map[string]string{"user-events": "BACKWARD", "clicks": "BACKWARD"}}
+
+	files, err := SchemaFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked, err := Check(context.Background(), checker, dir, files)
+	if err != nil {
+		t.Fatalf("Check should not be an error but an error was occurred: %v", err)
+	}
+	if len(checked) != len(files) {
+		t.Fatalf("every file is expected to be checked but got %+v", checked)
+	}
+
+	results := make(map[string]CheckedSchema)
+	for _, c := range checked {
+		results[c.File] = c
+	}
+	kindsOf := func(c CheckedSchema) []string {
+		kinds := make([]string, 0)
+		for _, v := range c.Violations {
+			kinds = append(kinds, v.Kind)
+		}
+		return kinds
+	}
+	cases := []struct {
+		file    string
+		version string
+		isNew   bool
+		kinds   []string
+	}{
+		{"broken/broken.avsc", "v1.0.0", true, []string{InvalidSchema}},
+		{"clicks/v2.avsc", "v2.0.0", true, []string{RestrictionViolated}},
+		{"empty-subject/v1.avsc", "v1.0.0", true, []string{}},
+		{"page-views/v1.avsc", "v1.0.0", true, []string{}},
+		{"user-events/v1.avsc", "v1.0.0", false, []string{}},
+		{"user-events/v2.avsc", "v1.1.0", true, []string{}},
+		{"user-events/v3.avsc", "v2.0.0", true, []string{model.TypeMismatch}},
+	}
+	for _, c := range cases {
+		result := results[c.file]
+		if result.Version != c.version || result.IsNew != c.isNew || !reflect.DeepEqual(kindsOf(result), c.kinds) {
+			t.Errorf("%s is expected to be %s (new: %v) with violations %v but got %+v", c.file, c.version, c.isNew, c.kinds, result)
+		}
+		if result.IsAllowed() != (len(c.kinds) == 0) {
+			t.Errorf("IsAllowed of %s is expected to be %v", c.file, len(c.kinds) == 0)
+		}
+	}
+	if v := results["user-events/v3.avsc"].Violations[0]; v.Path != "$.id" {
+		t.Errorf("the violation is expected to point to $.id but got %+v", v)
+	}
+	if r := results["user-events/v2.avsc"]; r.Restriction != "BACKWARD" || r.Report == nil || r.Report.Compatibility != "BACKWARD" {
+		t.Errorf("the restriction and the report are expected to be set but got %+v", r)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{"user-events/v1.avsc": v1, "user-events/v2.avsc": v2})
+	defer os.RemoveAll(dir)
+
+	changed, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"user-events/v1.avsc", "user-events/v2.avsc"}; !reflect.DeepEqual(changed, expect) {
+		t.Errorf("every file is expected to be changed without a lockfile but got %v", changed)
+	}
+
+	if _, err := Sync(context.Background(), newFakeServer(), dir); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "user-events", "v2.avsc"), []byte(v3), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "user-events", "v3.avsc"), []byte(v3), 0644)
+	changed, err = ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"user-events/v2.avsc", "user-events/v3.avsc"}; !reflect.DeepEqual(changed, expect) {
+		t.Errorf("ChangedFiles = %v, wants %v", changed, expect)
+	}
+}
@@ -0,0 +1,326 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dirsync registers schemas laid out in a directory as (dir)/(subject)/*.avsc
+// and records the ID and semver assigned to each file in a lockfile.
+package dirsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// LockfileName is the name of the lockfile written in the top of a directory.
+const LockfileName = "typebook.lock"
+
+// Server is a typebook server to register schemas with. A *typebook.Client can be passed as it is.
+type Server interface {
+	GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error)
+	CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error)
+	LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error)
+	RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error)
+}
+
+// Lock is the content of a lockfile.
+type Lock struct {
+	Schemas []LockedSchema `json:"schemas"`
+}
+
+// LockedSchema is a schema file with the ID and semver assigned to it.
+// File is the slash separated path relative to the directory, and Digest is the SHA-256 digest of the file.
+type LockedSchema struct {
+	File    string `json:"file"`
+	Subject string `json:"subject"`
+	Id      int64  `json:"id"`
+	Version string `json:"version"`
+	Digest  string `json:"digest"`
+}
+
+// Status tells what Sync did for a schema file.
+type Status string
+
+const (
+	// Unchanged means that the file is not changed since the lockfile was written, so no request is issued.
+	Unchanged Status = "UNCHANGED"
+	// Present means that the schema has already been registered under the subject.
+	Present Status = "PRESENT"
+	// Registered means that the schema is newly registered.
+	Registered Status = "REGISTERED"
+)
+
+// SyncedSchema is a schema file synced by Sync.
+type SyncedSchema struct {
+	LockedSchema
+	Status Status
+}
+
+// Sync registers schema files under dir which are new or changed since the lockfile was written, and updates the lockfile.
+// Each directory under dir is a subject, which is created if it does not exist, and files in it with the extension .avsc are its schemas.
+// Subjects and files are processed in natural order, so schemas under a subject are registered in order of their file names
+// with numbers compared by their values, e.g. v2.avsc before v10.avsc.
+// Files which are not changed are skipped without any request, and the lockfile is not rewritten if nothing is changed,
+// so syncing an unchanged directory is a no-op.
+// If a request fails, the lockfile is updated with the schemas synced so far and the error is returned.
+func Sync(ctx context.Context, server Server, dir string) ([]SyncedSchema, error) {
+	lockPath := filepath.Join(dir, LockfileName)
+	lock, err := ReadLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[string]LockedSchema)
+	for _, s := range lock.Schemas {
+		locked[s.File] = s
+	}
+
+	files, err := SchemaFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	synced := make([]SyncedSchema, 0, len(files))
+	entries := make([]LockedSchema, 0, len(files))
+	ensured := make(map[string]bool)
+	for i, file := range files {
+		s, err := syncFile(ctx, server, dir, file, locked, ensured)
+		if err != nil {
+			// files not synced yet keep their entries to be compared with next time
+			for _, rest := range files[i:] {
+				if l, ok := locked[rest]; ok {
+					entries = append(entries, l)
+				}
+			}
+			if werr := writeLock(lockPath, lock, entries); werr != nil {
+				return synced, werr
+			}
+			return synced, err
+		}
+		synced = append(synced, *s)
+		entries = append(entries, s.LockedSchema)
+	}
+	return synced, writeLock(lockPath, lock, entries)
+}
+
+func syncFile(ctx context.Context, server Server, dir, file string, locked map[string]LockedSchema, ensured map[string]bool) (*SyncedSchema, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	digest := digestOf(content)
+	subject := path.Dir(file)
+	if l, ok := locked[file]; ok && l.Digest == digest {
+		return &SyncedSchema{LockedSchema: l, Status: Unchanged}, nil
+	}
+
+	definition := string(content)
+	if _, err := avro.Parse(definition); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", file, err)
+	}
+	s := &SyncedSchema{LockedSchema: LockedSchema{File: file, Subject: subject, Digest: digest}, Status: Present}
+
+	schema, merr := server.LookupSchemaContext(ctx, subject, definition)
+	if merr.IsNotFound() {
+		if err := ensureSubject(ctx, server, subject, ensured); err != nil {
+			return nil, err
+		}
+		if _, err := server.RegisterSchemaContext(ctx, subject, definition); err != nil {
+			return nil, err
+		}
+		schema, merr = server.LookupSchemaContext(ctx, subject, definition)
+		s.Status = Registered
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	s.Id = schema.Id
+	s.Version = schema.Version.String()
+	return s, nil
+}
+
+// ensureSubject creates the subject if it does not exist. ensured caches subjects known to exist.
+func ensureSubject(ctx context.Context, server Server, subject string, ensured map[string]bool) *model.Error {
+	if ensured[subject] {
+		return nil
+	}
+	_, err := server.GetSubjectContext(ctx, subject)
+	if err.IsNotFound() {
+		_, err = server.CreateSubjectContext(ctx, subject, "")
+	}
+	if err != nil {
+		return err
+	}
+	ensured[subject] = true
+	return nil
+}
+
+// SchemaFiles lists schema files under dir as slash separated paths relative to it in natural order,
+// in which runs of digits are compared as numbers.
+func SchemaFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		children, err := ioutil.ReadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if !child.IsDir() && filepath.Ext(child.Name()) == ".avsc" {
+				files = append(files, path.Join(entry.Name(), child.Name()))
+			}
+		}
+	}
+	// ReadDir sorts entries by name lexically, e.g. v10.avsc before v2.avsc,
+	// and the paths are sorted as a whole for subjects which are prefixes of others
+	sort.Slice(files, func(i, j int) bool {
+		return naturalLess(files[i], files[j])
+	})
+	return files, nil
+}
+
+// naturalLess reports whether a is less than b comparing runs of digits by their numeric values and the others byte by byte.
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return a[i] < b[j]
+			}
+			i++
+			j++
+			continue
+		}
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		x, y := strings.TrimLeft(a[si:i], "0"), strings.TrimLeft(b[sj:j], "0")
+		if len(x) != len(y) {
+			return len(x) < len(y)
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	// the same except for leading zeros
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// ChangedFiles lists schema files under dir which are new or changed since the lockfile was written,
+// i.e. the files which Sync would register, as slash separated paths relative to dir in the same order as SchemaFiles.
+func ChangedFiles(dir string) ([]string, error) {
+	lock, err := ReadLock(filepath.Join(dir, LockfileName))
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]string)
+	for _, s := range lock.Schemas {
+		digests[s.File] = s.Digest
+	}
+
+	files, err := SchemaFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		if digest, ok := digests[file]; !ok || digest != digestOf(content) {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+func digestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// ReadLock reads a lockfile. An empty Lock is returned if the file does not exist.
+func ReadLock(path string) (*Lock, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Lock{Schemas: make([]LockedSchema, 0)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := new(Lock)
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %v", path, err)
+	}
+	return lock, nil
+}
+
+// writeLock writes the entries to the lockfile unless its content is the same as before.
+func writeLock(path string, before *Lock, entries []LockedSchema) error {
+	after := &Lock{Schemas: entries}
+	oldContent, err := marshalLock(before)
+	if err != nil {
+		return err
+	}
+	newContent, err := marshalLock(after)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(oldContent, newContent) {
+		return nil
+	}
+	return ioutil.WriteFile(path, newContent, 0644)
+}
+
+func marshalLock(lock *Lock) ([]byte, error) {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,242 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dirsync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// fakeServer is an in-memory Server, which bumps the minor version for every new schema and counts requests.
+// It fails to register the schema with the definition failOn.
+type fakeServer struct {
+	subjects map[string]bool
+	schemas  map[string][]model.Schema
+	requests int
+	failOn   string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{subjects: make(map[string]bool), schemas: make(map[string][]model.Schema)}
+}
+
+var errNotFound = model.NewError(&model.ServerError{ErrorCode: 404, Message: "Not Found"}, nil)
+
+func (fs *fakeServer) GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error) {
+	fs.requests++
+	if fs.subjects[name] {
+		return &model.Subject{Name: name}, nil
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeServer) CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	fs.requests++
+	fs.subjects[name] = true
+	return 0, nil
+}
+
+func (fs *fakeServer) LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error) {
+	fs.requests++
+	schemas := fs.schemas[subject]
+	for i := len(schemas) - 1; i >= 0; i-- {
+		if schemas[i].Definition == definition {
+			return &schemas[i], nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeServer) RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error) {
+	fs.requests++
+	if definition == fs.failOn {
+		return nil, model.NewError(&model.ServerError{ErrorCode: 503, Message: "Service Unavailable"}, nil)
+	}
+	if !fs.subjects[subject] {
+		return nil, model.NewError(&model.ServerError{ErrorCode: 404, Message: "Subject Not Found"}, nil)
+	}
+	version := model.SemVer{Major: 1}
+	if schemas := fs.schemas[subject]; len(schemas) > 0 {
+		version = schemas[len(schemas)-1].Version.NextMinor()
+	}
+	id := int64(0)
+	for _, schemas := range fs.schemas {
+		id += int64(len(schemas))
+	}
+	schema := model.Schema{Id: id + 1, Subject: subject, Version: version, Definition: definition}
+	fs.schemas[subject] = append(fs.schemas[subject], schema)
+	return &model.SchemaId{Id: schema.Id}, nil
+}
+
+const (
+	v1 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "int"}]}`
+	v2 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "long"}]}`
+	v3 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "string"}]}`
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "typebook-dirsync-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func statusesOf(synced []SyncedSchema) map[string]Status {
+	statuses := make(map[string]Status)
+	for _, s := range synced {
+		statuses[s.File] = s.Status
+	}
+	return statuses
+}
+
+func TestSync(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"README.md":                "schemas of events",
+		"user-events/v1.avsc":      v1,
+		"user-events/v2.avsc":      v2,
+		"user-events/notes.txt":    "not a schema",
+		"page-views/v1.avsc":       v1,
+		".git/user-events/v3.avsc": v3,
+	})
+	defer os.RemoveAll(dir)
+	server := newFakeServer()
+	server.subjects["page-views"] = true
+	server.schemas["page-views"] = []model.Schema{{Id: 10, Subject: "page-views", Version: model.SemVer{Major: 1}, Definition: v1}}
+
+	synced, err := Sync(context.Background(), server, dir)
+	if err != nil {
+		t.Fatalf("Sync should not be an error but an error was occurred: %v", err)
+	}
+	expect := map[string]Status{"page-views/v1.avsc": Present, "user-events/v1.avsc": Registered, "user-events/v2.avsc": Registered}
+	if statuses := statusesOf(synced); !reflect.DeepEqual(statuses, expect) {
+		t.Errorf("Sync = %v, wants %v", statuses, expect)
+	}
+	if synced[0].File != "page-views/v1.avsc" || synced[0].Id != 10 || synced[2].Version != "v1.1.0" {
+		t.Errorf("schemas are expected to be synced in natural order with their IDs and versions but got %+v", synced)
+	}
+
+	lockPath := filepath.Join(dir, LockfileName)
+	lock, err := ReadLock(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Schemas) != 3 || lock.Schemas[2] != synced[2].LockedSchema {
+		t.Errorf("the lockfile is expected to record the synced schemas but got %+v", lock.Schemas)
+	}
+
+	// syncing an unchanged tree issues no request and does not rewrite the lockfile
+	before, _ := os.Stat(lockPath)
+	os.Chtimes(lockPath, before.ModTime().Add(-time.Second), before.ModTime().Add(-time.Second))
+	before, _ = os.Stat(lockPath)
+	requests := server.requests
+	synced, err = Sync(context.Background(), server, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(lockPath)
+	for _, s := range synced {
+		if s.Status != Unchanged {
+			t.Errorf("%s is expected to be unchanged but got %s", s.File, s.Status)
+		}
+	}
+	if server.requests != requests || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("syncing an unchanged tree is expected to be a no-op")
+	}
+
+	// only a changed file is registered
+	ioutil.WriteFile(filepath.Join(dir, "user-events", "v2.avsc"), []byte(v3), 0644)
+	synced, err = Sync(context.Background(), server, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = map[string]Status{"page-views/v1.avsc": Unchanged, "user-events/v1.avsc": Unchanged, "user-events/v2.avsc": Registered}
+	if statuses := statusesOf(synced); !reflect.DeepEqual(statuses, expect) || synced[2].Version != "v1.2.0" {
+		t.Errorf("Sync = %+v, wants %v", synced, expect)
+	}
+}
+
+func TestSyncResume(t *testing.T) {
+	dir := writeTree(t, map[string]string{"user-events/v1.avsc": v1, "user-events/v2.avsc": v2, "user-events/v3.avsc": v3})
+	defer os.RemoveAll(dir)
+	server := newFakeServer()
+	server.failOn = v2
+
+	if _, err := Sync(context.Background(), server, dir); err == nil {
+		t.Fatalf("Sync should fail when registering a schema fails")
+	}
+	lock, err := ReadLock(filepath.Join(dir, LockfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Schemas) != 1 || lock.Schemas[0].File != "user-events/v1.avsc" {
+		t.Errorf("the lockfile is expected to record the schema synced before the failure but got %+v", lock.Schemas)
+	}
+
+	server.failOn = ""
+	synced, err := Sync(context.Background(), server, dir)
+	if err != nil {
+		t.Fatalf("Sync should not be an error but an error was occurred: %v", err)
+	}
+	expect := map[string]Status{"user-events/v1.avsc": Unchanged, "user-events/v2.avsc": Registered, "user-events/v3.avsc": Registered}
+	if statuses := statusesOf(synced); !reflect.DeepEqual(statuses, expect) {
+		t.Errorf("Sync = %v, wants %v", statuses, expect)
+	}
+}
+
+func TestSchemaFilesInNaturalOrder(t *testing.T) {
+	files := map[string]string{"page-views-2/v1.avsc": v1}
+	expect := []string{"page-views-2/v1.avsc"}
+	for i := 1; i <= 12; i++ {
+		name := fmt.Sprintf("page-views/v%d.avsc", i)
+		files[name] = v1
+		expect = append(expect, name)
+	}
+	dir := writeTree(t, files)
+	defer os.RemoveAll(dir)
+
+	actual, err := SchemaFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("schema files are expected to be sorted by the numbers in their names %v but got %v", expect, actual)
+	}
+}
@@ -0,0 +1,168 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// ErrOffline is returned when a Client in offline mode is asked for what is not in its disk cache.
+var ErrOffline = errors.New("the client is offline and the requested data is not cached")
+
+// DiskCache is a directory to persist schemas retrieved from a typebook server.
+// Each schema is stored as a JSON file both under `ids/(id).json` and `subjects/(subject)/(semver).json`.
+// It can be shared among processes since files are replaced atomically.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a DiskCache on the given directory. The directory is created if it does not exist.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	for _, sub := range []string{"ids", "subjects"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (dc *DiskCache) idPath(id int64) string {
+	return filepath.Join(dc.dir, "ids", fmt.Sprintf("%d.json", id))
+}
+
+func (dc *DiskCache) subjectDir(subject string) string {
+	return filepath.Join(dc.dir, "subjects", url.PathEscape(subject))
+}
+
+func (dc *DiskCache) semVerPath(subject string, semver model.SemVer) string {
+	return filepath.Join(dc.subjectDir(subject), semver.String()+".json")
+}
+
+// GetById returns a cached schema with the given ID. The second return value is false if it is not cached.
+func (dc *DiskCache) GetById(id int64) (*model.Schema, bool) {
+	if dc == nil {
+		return nil, false
+	}
+	return dc.read(dc.idPath(id))
+}
+
+// GetBySemVer returns a cached schema with the given subject and semver. The second return value is false if it is not cached.
+func (dc *DiskCache) GetBySemVer(subject string, semver model.SemVer) (*model.Schema, bool) {
+	if dc == nil {
+		return nil, false
+	}
+	return dc.read(dc.semVerPath(subject, semver))
+}
+
+// GetLatest returns the cached schema which has the highest version under the given subject.
+// If majorVersion is not negative, only schemas with the major version are taken into account.
+// The second return value is false if no schema is cached.
+func (dc *DiskCache) GetLatest(subject string, majorVersion int) (*model.Schema, bool) {
+	var latest *model.SemVer
+	for _, version := range dc.ListVersions(subject) {
+		if majorVersion >= 0 && version.Major != majorVersion {
+			continue
+		}
+		if latest == nil || latest.Less(version) {
+			v := version
+			latest = &v
+		}
+	}
+	if latest == nil {
+		return nil, false
+	}
+	return dc.GetBySemVer(subject, *latest)
+}
+
+// ListVersions returns versions of cached schemas under the given subject.
+func (dc *DiskCache) ListVersions(subject string) []model.SemVer {
+	versions := make([]model.SemVer, 0)
+	if dc == nil {
+		return versions
+	}
+	files, err := ioutil.ReadDir(dc.subjectDir(subject))
+	if err != nil {
+		return versions
+	}
+	for _, file := range files {
+		if version, err := model.NewSemVer(strings.TrimSuffix(file.Name(), ".json")); err == nil {
+			versions = append(versions, *version)
+		}
+	}
+	return versions
+}
+
+// Put stores the given schema. Failures are ignored since the cache is just an optimization.
+func (dc *DiskCache) Put(schema *model.Schema) {
+	if dc == nil {
+		return
+	}
+	content, err := json.Marshal(schema)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dc.subjectDir(schema.Subject), 0755); err != nil {
+		return
+	}
+	dc.write(dc.idPath(schema.Id), content)
+	dc.write(dc.semVerPath(schema.Subject, schema.Version), content)
+}
+
+func (dc *DiskCache) read(path string) (*model.Schema, bool) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	schema := new(model.Schema)
+	if err := json.Unmarshal(content, schema); err != nil {
+		return nil, false
+	}
+	return schema, true
+}
+
+// write replaces the file at the given path atomically not to let other processes read a partially written file.
+func (dc *DiskCache) write(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
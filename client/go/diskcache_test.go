@@ -0,0 +1,116 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+func newTestDiskCache(t *testing.T) *DiskCache {
+	cache, err := NewDiskCache(tempDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestDiskCache(t *testing.T) {
+	cache := newTestDiskCache(t)
+	defer os.RemoveAll(cache.dir)
+
+	if _, ok := cache.GetById(1); ok {
+		t.Errorf("GetById(1) on an empty cache should not be found")
+	}
+	schemas := []model.Schema{
+		{Id: 1, Subject: subject, Version: model.SemVer{Major: 1}, Definition: schemaDef},
+		{Id: 2, Subject: subject, Version: model.SemVer{Major: 1, Minor: 10}, Definition: schemaDef},
+		{Id: 3, Subject: subject, Version: model.SemVer{Major: 1, Minor: 2}, Definition: schemaDef},
+		{Id: 4, Subject: subject, Version: model.SemVer{Major: 2}, Definition: schemaDef},
+	}
+	for i := range schemas {
+		cache.Put(&schemas[i])
+	}
+
+	if actual, ok := cache.GetById(3); !ok || *actual != schemas[2] {
+		t.Errorf("GetById(3) = %v, wants %v", actual, schemas[2])
+	}
+	if actual, ok := cache.GetBySemVer(subject, schemas[1].Version); !ok || *actual != schemas[1] {
+		t.Errorf("GetBySemVer(%s, %s) = %v, wants %v", subject, schemas[1].Version.String(), actual, schemas[1])
+	}
+	if actual, ok := cache.GetLatest(subject, -1); !ok || *actual != schemas[3] {
+		t.Errorf("GetLatest(%s, -1) = %v, wants %v", subject, actual, schemas[3])
+	}
+	if actual, ok := cache.GetLatest(subject, 1); !ok || *actual != schemas[1] {
+		t.Errorf("GetLatest(%s, 1) = %v, wants %v", subject, actual, schemas[1])
+	}
+	if versions := cache.ListVersions(subject); len(versions) != len(schemas) {
+		t.Errorf("ListVersions(%s) = %v, wants %d versions", subject, versions, len(schemas))
+	}
+}
+
+func TestClientWithDiskCache(t *testing.T) {
+	server := newCountingServer(0)
+	defer server.Close()
+	cache := newTestDiskCache(t)
+	defer os.RemoveAll(cache.dir)
+
+	expect := testSchema()
+	client := NewClient(server.URL, WithDiskCache(cache))
+	if _, err := client.GetLatestSchema(subject); err != nil {
+		t.Errorf("GetLatestSchema(%s) should not be an error. But an error was occurred: %v", subject, err)
+	}
+	// populated by the previous request
+	if actual, err := client.GetSchemaById(expect.Id); err != nil {
+		t.Errorf("GetSchemaById(%d) should not be an error. But an error was occurred: %v", expect.Id, err)
+	} else if *actual != expect {
+		t.Errorf("GetSchemaById(%d) = %v, wants %v", expect.Id, *actual, expect)
+	}
+	if server.received() != 1 {
+		t.Errorf("the server received %d requests, wants 1", server.received())
+	}
+
+	// another client can start from the cache without the server
+	offline := NewClient(server.URL, WithDiskCache(cache), WithOffline())
+	if actual, err := offline.GetLatestSchema(subject); err != nil {
+		t.Errorf("GetLatestSchema(%s) should not be an error. But an error was occurred: %v", subject, err)
+	} else if *actual != expect {
+		t.Errorf("GetLatestSchema(%s) = %v, wants %v", subject, *actual, expect)
+	}
+	if actual, err := offline.GetSchemaBySemVer(subject, expect.Version); err != nil {
+		t.Errorf("GetSchemaBySemVer(%s, %s) should not be an error. But an error was occurred: %v", subject, expect.Version.String(), err)
+	} else if *actual != expect {
+		t.Errorf("GetSchemaBySemVer(%s, %s) = %v, wants %v", subject, expect.Version.String(), *actual, expect)
+	}
+	if _, err := offline.GetSchemaByMajorVersion(subject, 2); err == nil || err.ClientError[0] != ErrOffline {
+		t.Errorf("GetSchemaByMajorVersion(%s, 2) should be ErrOffline, but got %v", subject, err)
+	}
+	if _, err := offline.ListSubjects(); err == nil || err.ClientError[0] != ErrOffline {
+		t.Errorf("ListSubjects() should be ErrOffline, but got %v", err)
+	}
+	if server.received() != 1 {
+		t.Errorf("the offline client should not issue any request, but the server received %d requests", server.received())
+	}
+}
@@ -0,0 +1,159 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balancing is a strategy to choose an endpoint to send a request to among multiple endpoints.
+type Balancing int
+
+const (
+	// RoundRobin distributes requests among healthy endpoints in turn.
+	RoundRobin Balancing = iota
+	// FirstHealthy sends requests to the first healthy endpoint in the given order,
+	// so that the rest of endpoints are used only for failover.
+	FirstHealthy
+)
+
+// DefaultHealthCheckInterval is the default interval to probe ejected endpoints.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+type endpoint struct {
+	url     string
+	ejected int32 // accessed atomically, 1 if ejected
+}
+
+func (e *endpoint) isHealthy() bool {
+	return atomic.LoadInt32(&e.ejected) == 0
+}
+
+// endpointPool holds endpoints of typebook server replicas.
+// An endpoint is ejected when a request to it fails with a connection error or unavailability of the server,
+// and it is brought back when it passes a health check which is periodically run in background.
+type endpointPool struct {
+	endpoints []*endpoint
+	balancing Balancing
+	next      uint32 // accessed atomically, a counter for RoundRobin
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// parseEndpoints splits a comma separated list of endpoints and normalizes each of them.
+func parseEndpoints(endpoints string) []*endpoint {
+	parsed := make([]*endpoint, 0)
+	for _, url := range strings.Split(endpoints, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			parsed = append(parsed, &endpoint{url: normalizeEndpoint(url)})
+		}
+	}
+	if len(parsed) == 0 { // let a request fail with an error describing an invalid URL
+		parsed = append(parsed, &endpoint{url: normalizeEndpoint(endpoints)})
+	}
+	return parsed
+}
+
+func newEndpointPool(endpoints []*endpoint, balancing Balancing) *endpointPool {
+	return &endpointPool{
+		endpoints: endpoints,
+		balancing: balancing,
+		closed:    make(chan struct{}),
+	}
+}
+
+// pick chooses an endpoint to send a request to.
+// If all endpoints are ejected, it chooses one of them in turn rather than failing immediately.
+func (ep *endpointPool) pick() *endpoint {
+	if len(ep.endpoints) == 1 {
+		return ep.endpoints[0]
+	}
+
+	start := 0
+	if ep.balancing == RoundRobin {
+		start = int(atomic.AddUint32(&ep.next, 1)-1) % len(ep.endpoints)
+	}
+	for i := 0; i < len(ep.endpoints); i++ {
+		if e := ep.endpoints[(start+i)%len(ep.endpoints)]; e.isHealthy() {
+			return e
+		}
+	}
+	return ep.endpoints[int(atomic.AddUint32(&ep.next, 1)-1)%len(ep.endpoints)]
+}
+
+// report ejects the endpoint if the result of a request to it indicates the server is not available.
+// Errors other than transport ones, such as those of an Authenticator, say nothing about the endpoint.
+func (ep *endpointPool) report(ctx context.Context, e *endpoint, response *http.Response, err error) {
+	if len(ep.endpoints) == 1 || ctx.Err() != nil {
+		return
+	}
+	if err != nil && isTransient(err) || err == nil && isUnavailable(response.StatusCode) {
+		atomic.StoreInt32(&e.ejected, 1)
+	}
+}
+
+func isUnavailable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// healthCheck probes ejected endpoints every interval until the pool is closed.
+// probe should return true if the endpoint is healthy.
+func (ep *endpointPool) healthCheck(interval time.Duration, probe func(ctx context.Context, e *endpoint) bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ep.closed:
+			return
+		case <-ticker.C:
+			for _, e := range ep.endpoints {
+				if e.isHealthy() {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if probe(ctx, e) {
+					atomic.StoreInt32(&e.ejected, 0)
+				}
+				cancel()
+			}
+		}
+	}
+}
+
+func (ep *endpointPool) close() {
+	ep.closeOnce.Do(func() {
+		close(ep.closed)
+	})
+}
@@ -0,0 +1,208 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// replica is a typebook server replica for tests which can be brought down and up.
+type replica struct {
+	*httptest.Server
+	requests int32
+	down     int32
+}
+
+func newReplica() *replica {
+	r := new(replica)
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&r.down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if req.URL.Path == "/health" {
+			w.Write([]byte("OK"))
+			return
+		}
+		atomic.AddInt32(&r.requests, 1)
+		w.Write([]byte(`["` + subject + `"]`))
+	}))
+	return r
+}
+
+func (r *replica) received() int32 {
+	return atomic.LoadInt32(&r.requests)
+}
+
+func endpointsOf(replicas ...*replica) string {
+	urls := make([]string, 0)
+	for _, r := range replicas {
+		urls = append(urls, r.URL)
+	}
+	return strings.Join(urls, ",")
+}
+
+func TestParseEndpoints(t *testing.T) {
+	testCases := []struct {
+		endpoints string
+		expect    []string
+	}{
+		{endpoints: "foo.bar", expect: []string{"http://foo.bar"}},
+		{endpoints: "foo:8888, https://bar/typebook/", expect: []string{"http://foo:8888", "https://bar/typebook"}},
+	}
+	for _, testCase := range testCases {
+		actual := make([]string, 0)
+		for _, e := range parseEndpoints(testCase.endpoints) {
+			actual = append(actual, e.url)
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("parseEndpoints(%s) = %v, wants %v", testCase.endpoints, actual, testCase.expect)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	r1, r2 := newReplica(), newReplica()
+	defer r1.Close()
+	defer r2.Close()
+
+	client := NewClient(endpointsOf(r1, r2))
+	defer client.Close()
+	for i := 0; i < 10; i++ {
+		if _, err := client.ListSubjects(); err != nil {
+			t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+		}
+	}
+	if r1.received() != 5 || r2.received() != 5 {
+		t.Errorf("requests should be distributed evenly, but %d and %d", r1.received(), r2.received())
+	}
+}
+
+func TestFirstHealthy(t *testing.T) {
+	r1, r2 := newReplica(), newReplica()
+	defer r1.Close()
+	defer r2.Close()
+
+	client := NewClient(endpointsOf(r1, r2), WithBalancing(FirstHealthy), WithRetryPolicy(testRetryPolicy))
+	defer client.Close()
+	for i := 0; i < 10; i++ {
+		client.ListSubjects()
+	}
+	if r1.received() != 10 || r2.received() != 0 {
+		t.Errorf("all requests should be sent to the first endpoint, but %d and %d", r1.received(), r2.received())
+	}
+
+	atomic.StoreInt32(&r1.down, 1)
+	for i := 0; i < 10; i++ {
+		if _, err := client.ListSubjects(); err != nil {
+			t.Errorf("ListSubjects() should fail over to the second endpoint. But an error was occurred: %v", err)
+		}
+	}
+	if r2.received() != 10 {
+		t.Errorf("all requests should fail over to the second endpoint, but %d", r2.received())
+	}
+}
+
+func TestFailoverAndHealthCheck(t *testing.T) {
+	r1, r2 := newReplica(), newReplica()
+	defer r1.Close()
+	defer r2.Close()
+
+	client := NewClient(endpointsOf(r1, r2), WithRetryPolicy(testRetryPolicy), WithHealthCheckInterval(10*time.Millisecond))
+	defer client.Close()
+
+	// r1 is ejected passively by a failed request
+	atomic.StoreInt32(&r1.down, 1)
+	for i := 0; i < 10; i++ {
+		if _, err := client.ListSubjects(); err != nil {
+			t.Errorf("ListSubjects() should fail over to a healthy endpoint. But an error was occurred: %v", err)
+		}
+	}
+	if r1.received() != 0 || r2.received() != 10 {
+		t.Errorf("all requests should be sent to the healthy endpoint, but %d and %d", r1.received(), r2.received())
+	}
+
+	// r1 is brought back by the health check
+	atomic.StoreInt32(&r1.down, 0)
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		client.ListSubjects()
+	}
+	if r1.received() != 5 {
+		t.Errorf("the recovered endpoint should receive requests again, but %d", r1.received())
+	}
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	r1, r2 := newReplica(), newReplica()
+	r1.Close() // not listening any more
+	defer r2.Close()
+
+	client := NewClient(endpointsOf(r1, r2), WithBalancing(FirstHealthy), WithRetryPolicy(testRetryPolicy))
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListSubjects(); err != nil {
+			t.Errorf("ListSubjects() should fail over to a healthy endpoint. But an error was occurred: %v", err)
+		}
+	}
+	if r2.received() != 3 {
+		t.Errorf("all requests should be sent to the healthy endpoint, but %d", r2.received())
+	}
+}
+
+func TestNoEjectionOnAuthError(t *testing.T) {
+	r1, r2 := newReplica(), newReplica()
+	defer r1.Close()
+	defer r2.Close()
+
+	var failing int32 = 1
+	auth := TokenSource(func(ctx context.Context, forceRefresh bool) (string, error) {
+		if atomic.LoadInt32(&failing) == 1 {
+			return "", errors.New("invalid credentials")
+		}
+		return "token", nil
+	})
+	client := NewClient(endpointsOf(r1, r2), WithBalancing(FirstHealthy), WithAuth(auth))
+	defer client.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListSubjects(); err == nil {
+			t.Errorf("ListSubjects() should be an error when no token is obtained. But no error was occurred")
+		}
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	for i := 0; i < 4; i++ {
+		client.ListSubjects()
+	}
+	if r1.received() != 4 || r2.received() != 0 {
+		t.Errorf("an authentication error should not eject endpoints, but the requests were sent %d and %d", r1.received(), r2.received())
+	}
+}
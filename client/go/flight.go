@@ -0,0 +1,89 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// flight is a request in progress or completed.
+type flight struct {
+	done   chan struct{}
+	schema *model.Schema
+	err    *model.Error
+	// canceled is whether the request ended up with an error because the context of its caller was done
+	canceled bool
+}
+
+// flightGroup deduplicates concurrent requests for the same key
+// so that only one of them is sent to a typebook server and the others wait for its result.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do calls fetch unless another call of do with the same key is in progress,
+// in which case it waits for the call and returns the same result.
+// ctx is the context of the caller, which fetch is supposed to use.
+// A waiting caller returns as soon as its ctx is done, and sends the request again by itself
+// if the call it waited for failed only because the context of that caller was done.
+func (fg *flightGroup) do(ctx context.Context, key string, fetch func() (*model.Schema, *model.Error)) (*model.Schema, *model.Error) {
+	for {
+		fg.mu.Lock()
+		if fg.flights == nil {
+			fg.flights = make(map[string]*flight)
+		}
+		f, ok := fg.flights[key]
+		if !ok {
+			f = &flight{done: make(chan struct{})}
+			fg.flights[key] = f
+			fg.mu.Unlock()
+			return fg.lead(ctx, key, f, fetch)
+		}
+		fg.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, model.NewError(nil, []error{ctx.Err()})
+		}
+		if !f.canceled {
+			return f.schema, f.err
+		}
+	}
+}
+
+// lead calls fetch as the leader of the given flight and lets the waiting callers know its result.
+func (fg *flightGroup) lead(ctx context.Context, key string, f *flight, fetch func() (*model.Schema, *model.Error)) (*model.Schema, *model.Error) {
+	f.schema, f.err = fetch()
+	f.canceled = f.err != nil && ctx.Err() != nil
+
+	fg.mu.Lock()
+	delete(fg.flights, key)
+	fg.mu.Unlock()
+	close(f.done)
+	return f.schema, f.err
+}
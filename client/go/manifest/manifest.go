@@ -0,0 +1,118 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package manifest manages a typebook registry declaratively.
+// A manifest written in YAML or JSON lists subjects with their descriptions, configs and schema files,
+// and a Plan is the set of changes to bring a typebook server into the state, which Apply performs.
+//
+//	subjects:
+//	  - name: user-events
+//	    description: events of users
+//	    config:
+//	      compatibility: BACKWARD
+//	    schemas:
+//	      - schemas/user-events/v1.avsc
+//	      - schemas/user-events/v2.avsc
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+)
+
+// Manifest is the desired state of subjects in a typebook registry.
+// Subjects which are not listed are left as they are.
+type Manifest struct {
+	Subjects []Subject `json:"subjects" yaml:"subjects"`
+}
+
+// Subject is the desired state of a subject.
+// Description and Config are left as they are if nil.
+// Schemas are paths to files of schemas to register in this order, relative to the manifest.
+type Subject struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description *string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Config      *Config  `json:"config,omitempty" yaml:"config,omitempty"`
+	Schemas     []string `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+
+	definitions []string
+}
+
+// Config is the desired config of a subject. An empty property is left as it is.
+type Config struct {
+	Compatibility string `json:"compatibility,omitempty" yaml:"compatibility,omitempty"`
+}
+
+// Load reads a manifest in YAML or JSON from the file and the schema files listed in it.
+// It fails if the manifest has an unknown key, a subject is listed twice, a compatibility is invalid
+// or a schema file is not a valid Avro schema.
+func Load(path string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is also parsed as YAML
+	m := new(Manifest)
+	if err := yaml.UnmarshalStrict(content, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	names := make(map[string]bool)
+	for i := range m.Subjects {
+		s := &m.Subjects[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("invalid manifest %s: subject #%d has no name", path, i+1)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("invalid manifest %s: subject `%s` is listed twice", path, s.Name)
+		}
+		names[s.Name] = true
+		if s.Config != nil && s.Config.Compatibility != "" {
+			if _, err := compatibility.ParseLevel(s.Config.Compatibility); err != nil {
+				return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+			}
+		}
+
+		s.definitions = make([]string, 0, len(s.Schemas))
+		for _, file := range s.Schemas {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			definition, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := avro.Parse(string(definition)); err != nil {
+				return nil, fmt.Errorf("invalid schema %s: %v", file, err)
+			}
+			s.definitions = append(s.definitions, string(definition))
+		}
+	}
+	return m, nil
+}
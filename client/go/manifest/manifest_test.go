@@ -0,0 +1,113 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files in a new temporary directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "typebook-manifest-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"registry.yaml": `
+subjects:
+  - name: user-events
+    description: events of users
+    config:
+      compatibility: BACKWARD
+    schemas:
+      - schemas/user-events.avsc
+  - name: page-views
+`,
+		"registry.json":            `{"subjects": [{"name": "user-events", "schemas": ["schemas/user-events.avsc"]}]}`,
+		"schemas/user-events.avsc": `{"type": "record", "name": "UserEvent", "fields": [{"name": "id", "type": "long"}]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	m, err := Load(filepath.Join(dir, "registry.yaml"))
+	if err != nil {
+		t.Fatalf("Load should not be an error but an error was occurred: %v", err)
+	}
+	if len(m.Subjects) != 2 {
+		t.Fatalf("2 subjects are expected to be loaded but got %+v", m.Subjects)
+	}
+	events := m.Subjects[0]
+	if events.Name != "user-events" || *events.Description != "events of users" || events.Config.Compatibility != "BACKWARD" ||
+		len(events.definitions) != 1 || !strings.Contains(events.definitions[0], "UserEvent") {
+		t.Errorf("user-events is not loaded as expected: %+v", events)
+	}
+	if views := m.Subjects[1]; views.Description != nil || views.Config != nil {
+		t.Errorf("the description and config of page-views are expected to be left as they are but got %+v", views)
+	}
+
+	m, err = Load(filepath.Join(dir, "registry.json"))
+	if err != nil {
+		t.Fatalf("Load should not be an error for JSON but an error was occurred: %v", err)
+	}
+	if len(m.Subjects) != 1 || len(m.Subjects[0].definitions) != 1 {
+		t.Errorf("user-events is expected to be loaded from JSON but got %+v", m.Subjects)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	testCases := []struct {
+		manifest string
+		message  string
+	}{
+		{manifest: "subjects:\n  - name: a\n    descripton: typo\n", message: "descripton"},
+		{manifest: "subjects:\n  - description: no name\n", message: "no name"},
+		{manifest: "subjects:\n  - name: a\n  - name: a\n", message: "listed twice"},
+		{manifest: "subjects:\n  - name: a\n    config:\n      compatibility: STRICT\n", message: "not a compatibility"},
+		{manifest: "subjects:\n  - name: a\n    schemas: [invalid.avsc]\n", message: "invalid schema"},
+		{manifest: "subjects:\n  - name: a\n    schemas: [missing.avsc]\n", message: "no such file"},
+	}
+	for _, testCase := range testCases {
+		dir := writeFiles(t, map[string]string{"registry.yaml": testCase.manifest, "invalid.avsc": `{"type": "unknown"}`})
+		_, err := Load(filepath.Join(dir, "registry.yaml"))
+		if err == nil || !strings.Contains(err.Error(), testCase.message) {
+			t.Errorf("Load is expected to fail with `%s` for\n%s\nbut got %v", testCase.message, testCase.manifest, err)
+		}
+		os.RemoveAll(dir)
+	}
+}
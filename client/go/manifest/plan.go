@@ -0,0 +1,263 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"context"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Server is a typebook server to plan changes against and apply them to, which is usually a *typebook.Client.
+type Server interface {
+	GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error)
+	CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error)
+	UpdateDescriptionContext(ctx context.Context, name, description string) (int64, *model.Error)
+	GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error)
+	SetConfigContext(ctx context.Context, subject string, config model.Config) (int64, *model.Error)
+	LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error)
+	GetSchemaByIdContext(ctx context.Context, id int64) (*model.Schema, *model.Error)
+	PredictNextVersionContext(ctx context.Context, subject, definition string) (*model.VersionPrediction, *model.Error)
+	RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error)
+}
+
+// Action is a kind of a change.
+type Action string
+
+const (
+	CreateSubject     Action = "CREATE_SUBJECT"
+	UpdateDescription Action = "UPDATE_DESCRIPTION"
+	SetCompatibility  Action = "SET_COMPATIBILITY"
+	RegisterSchema    Action = "REGISTER_SCHEMA"
+)
+
+// Change is a change to a typebook server.
+// From and To are the current and desired description or compatibility, and To is also the description of a subject to create.
+// File is the schema file to register and Version is the version it will be given,
+// which is empty if it depends on preceding schemas under the subject.
+// IsAllowed is false if the schema would be rejected by the compatibility of the subject.
+// Id is the ID of a registered schema, which is set only by Apply.
+type Change struct {
+	Action    Action `json:"action"`
+	Subject   string `json:"subject"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	File      string `json:"file,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Id        int64  `json:"id,omitempty"`
+	IsAllowed bool   `json:"is_allowed"`
+
+	definition string
+}
+
+// Plan is the changes to bring a typebook server into the state of a manifest, in the order they are applied.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// IsAllowed reports whether every schema to register satisfies the compatibility of its subject.
+func (p *Plan) IsAllowed() bool {
+	for _, c := range p.Changes {
+		if !c.IsAllowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Plan compares the manifest with the server and returns the changes to make.
+// For each subject, it is created or its description is updated, then its compatibility is set
+// and schemas not registered under it yet are registered, whose versions are predicted by PredictNextVersion.
+// The compatibility to set is taken into account to tell whether a schema will be allowed.
+func (m *Manifest) Plan(ctx context.Context, server Server) (*Plan, error) {
+	plan := &Plan{Changes: make([]Change, 0)}
+	for _, s := range m.Subjects {
+		changes, err := planSubject(ctx, server, s)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	return plan, nil
+}
+
+func planSubject(ctx context.Context, server Server, s Subject) ([]Change, error) {
+	changes := make([]Change, 0)
+
+	live, err := server.GetSubjectContext(ctx, s.Name)
+	isNew := err.IsNotFound()
+	switch {
+	case isNew:
+		description := ""
+		if s.Description != nil {
+			description = *s.Description
+		}
+		changes = append(changes, Change{Action: CreateSubject, Subject: s.Name, To: description, IsAllowed: true})
+	case err != nil:
+		return nil, err
+	case s.Description != nil && *s.Description != live.Description:
+		changes = append(changes, Change{Action: UpdateDescription, Subject: s.Name, From: live.Description, To: *s.Description, IsAllowed: true})
+	}
+
+	// a new subject has the default config
+	restriction := string(compatibility.None)
+	if !isNew {
+		config, err := server.GetConfigContext(ctx, s.Name)
+		if err != nil {
+			return nil, err
+		}
+		restriction = config.Compatibility
+	}
+	if s.Config != nil && s.Config.Compatibility != "" && s.Config.Compatibility != restriction {
+		changes = append(changes, Change{Action: SetCompatibility, Subject: s.Name, From: restriction, To: s.Config.Compatibility, IsAllowed: true})
+		restriction = s.Config.Compatibility
+	}
+
+	// schemas to register are checked against the preceding ones locally as well as the live schemas with the latest major version
+	latestMajor := make([]avro.Schema, 0)
+	preceding := make([]avro.Schema, 0)
+	pending := 0
+	for i, definition := range s.definitions {
+		if !isNew {
+			_, err := server.LookupSchemaContext(ctx, s.Name, definition)
+			if err == nil {
+				continue
+			}
+			if !err.IsNotFound() {
+				return nil, err
+			}
+		}
+
+		target, perr := avro.Parse(definition)
+		if perr != nil {
+			return nil, perr
+		}
+		change := Change{Action: RegisterSchema, Subject: s.Name, File: s.Schemas[i], IsAllowed: true, definition: definition}
+		switch {
+		case pending > 0:
+			// the version depends on the preceding schemas, which are not registered yet
+			change.IsAllowed = isCompatible(target, append(latestMajor, preceding...), restriction)
+		case isNew:
+			first := model.SemVer{Major: 1}
+			change.Version = first.String()
+		default:
+			prediction, err := server.PredictNextVersionContext(ctx, s.Name, definition)
+			if err != nil {
+				return nil, err
+			}
+			change.Version = prediction.Version.String()
+			change.IsAllowed = isAllowed(prediction.Comparisons, restriction)
+			schemas, lerr := liveSchemas(ctx, server, prediction.Comparisons)
+			if lerr != nil {
+				return nil, lerr
+			}
+			latestMajor = schemas
+		}
+		preceding = append(preceding, target)
+		pending++
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// isAllowed reports whether the compatibility with every comparison satisfies the restriction.
+func isAllowed(comparisons []model.VersionComparison, restriction string) bool {
+	level, err := compatibility.ParseLevel(restriction)
+	if err != nil {
+		return true
+	}
+	for _, c := range comparisons {
+		if !compatibility.Level(c.Compatibility).IsStrongerThanOrEqualTo(level) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCompatible reports whether target satisfies the restriction with every existing schema.
+// It is stricter than a typebook server when a preceding schema bumps the major version, which only FORWARD or NONE allows.
+func isCompatible(target avro.Schema, existing []avro.Schema, restriction string) bool {
+	level, err := compatibility.ParseLevel(restriction)
+	if err != nil {
+		return true
+	}
+	return compatibility.Check(target, existing, level)
+}
+
+// liveSchemas retrieves the schemas compared by a prediction, i.e. those with the latest major version.
+func liveSchemas(ctx context.Context, server Server, comparisons []model.VersionComparison) ([]avro.Schema, error) {
+	schemas := make([]avro.Schema, 0, len(comparisons))
+	for _, c := range comparisons {
+		schema, err := server.GetSchemaByIdContext(ctx, c.Id)
+		if err != nil {
+			return nil, err
+		}
+		parsed, perr := avro.Parse(schema.Definition)
+		if perr != nil {
+			return nil, perr
+		}
+		schemas = append(schemas, parsed)
+	}
+	return schemas, nil
+}
+
+// Apply makes the changes in order and returns the applied ones. The plan must be the one returned by Manifest.Plan,
+// which holds the definitions of schemas to register.
+// It stops at the first failure, in which case the changes applied so far are returned with the error.
+func (p *Plan) Apply(ctx context.Context, server Server) ([]Change, error) {
+	applied := make([]Change, 0, len(p.Changes))
+	for _, c := range p.Changes {
+		var err *model.Error
+		switch c.Action {
+		case CreateSubject:
+			_, err = server.CreateSubjectContext(ctx, c.Subject, c.To)
+		case UpdateDescription:
+			_, err = server.UpdateDescriptionContext(ctx, c.Subject, c.To)
+		case SetCompatibility:
+			_, err = server.SetConfigContext(ctx, c.Subject, model.Config{Compatibility: c.To})
+		case RegisterSchema:
+			err = registerSchema(ctx, server, &c)
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, c)
+	}
+	return applied, nil
+}
+
+// registerSchema registers the schema of the change and fills in its ID and the version actually given.
+func registerSchema(ctx context.Context, server Server, c *Change) *model.Error {
+	if _, err := server.RegisterSchemaContext(ctx, c.Subject, c.definition); err != nil {
+		return err
+	}
+	registered, err := server.LookupSchemaContext(ctx, c.Subject, c.definition)
+	if err != nil {
+		return err
+	}
+	c.Id = registered.Id
+	c.Version = registered.Version.String()
+	return nil
+}
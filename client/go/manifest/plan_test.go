@@ -0,0 +1,247 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// fakeServer is an in-memory Server, which bumps the minor version for every new schema.
+// The compatibility of a definition with the latest schema is given by compatibilities, FULL by default.
+type fakeServer struct {
+	subjects        map[string]*model.Subject
+	configs         map[string]model.Config
+	schemas         map[string][]model.Schema
+	compatibilities map[string]string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		subjects:        make(map[string]*model.Subject),
+		configs:         make(map[string]model.Config),
+		schemas:         make(map[string][]model.Schema),
+		compatibilities: make(map[string]string),
+	}
+}
+
+var errNotFound = model.NewError(&model.ServerError{ErrorCode: 404, Message: "Not Found"}, nil)
+
+func (fs *fakeServer) GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error) {
+	if s, ok := fs.subjects[name]; ok {
+		return s, nil
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeServer) CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	fs.subjects[name] = &model.Subject{Name: name, Description: description}
+	fs.configs[name] = model.Config{Compatibility: "NONE"}
+	return 0, nil
+}
+
+func (fs *fakeServer) UpdateDescriptionContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	fs.subjects[name].Description = description
+	return 1, nil
+}
+
+func (fs *fakeServer) GetConfigContext(ctx context.Context, subject string) (*model.Config, *model.Error) {
+	config := fs.configs[subject]
+	return &config, nil
+}
+
+func (fs *fakeServer) SetConfigContext(ctx context.Context, subject string, config model.Config) (int64, *model.Error) {
+	fs.configs[subject] = config
+	return 1, nil
+}
+
+func (fs *fakeServer) LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error) {
+	schemas := fs.schemas[subject]
+	for i := len(schemas) - 1; i >= 0; i-- {
+		if schemas[i].Definition == definition {
+			return &schemas[i], nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeServer) GetSchemaByIdContext(ctx context.Context, id int64) (*model.Schema, *model.Error) {
+	for _, schemas := range fs.schemas {
+		for i := range schemas {
+			if schemas[i].Id == id {
+				return &schemas[i], nil
+			}
+		}
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeServer) PredictNextVersionContext(ctx context.Context, subject, definition string) (*model.VersionPrediction, *model.Error) {
+	schemas := fs.schemas[subject]
+	prediction := &model.VersionPrediction{Subject: subject, Version: model.SemVer{Major: 1}, IsNew: true, IsAllowed: true}
+	if len(schemas) > 0 {
+		latest := schemas[len(schemas)-1]
+		prediction.Version = latest.Version.NextMinor()
+		compatibility, ok := fs.compatibilities[definition]
+		if !ok {
+			compatibility = "FULL"
+		}
+		prediction.Comparisons = []model.VersionComparison{{Id: latest.Id, Version: latest.Version, Compatibility: compatibility, IsDecisive: true}}
+	}
+	return prediction, nil
+}
+
+func (fs *fakeServer) RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error) {
+	prediction, _ := fs.PredictNextVersionContext(ctx, subject, definition)
+	schema := model.Schema{Id: int64(len(fs.schemas[subject]) + 1), Subject: subject, Version: prediction.Version, Definition: definition}
+	fs.schemas[subject] = append(fs.schemas[subject], schema)
+	return &model.SchemaId{Id: schema.Id}, nil
+}
+
+const (
+	v1 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "int"}]}`
+	v2 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "long"}]}`
+	v3 = `{"type": "record", "name": "E", "fields": [{"name": "id", "type": "string"}]}`
+)
+
+func loadManifest(t *testing.T, manifest string) *Manifest {
+	dir := writeFiles(t, map[string]string{"registry.yaml": manifest, "v1.avsc": v1, "v2.avsc": v2, "v3.avsc": v3})
+	defer os.RemoveAll(dir)
+	m, err := Load(filepath.Join(dir, "registry.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestPlanAndApply(t *testing.T) {
+	server := newFakeServer()
+	server.subjects["page-views"] = &model.Subject{Name: "page-views", Description: "views"}
+	server.configs["page-views"] = model.Config{Compatibility: "NONE"}
+	server.schemas["page-views"] = []model.Schema{{Id: 1, Subject: "page-views", Version: model.SemVer{Major: 1}, Definition: v1}}
+	server.compatibilities[v3] = "NONE"
+
+	m := loadManifest(t, `
+subjects:
+  - name: user-events
+    description: events of users
+    config:
+      compatibility: BACKWARD
+    schemas: [v1.avsc, v2.avsc]
+  - name: page-views
+    description: views of pages
+    config:
+      compatibility: FULL
+    schemas: [v1.avsc, v2.avsc]
+`)
+	plan, err := m.Plan(context.Background(), server)
+	if err != nil {
+		t.Fatalf("Plan should not be an error but an error was occurred: %v", err)
+	}
+	expect := []Change{
+		{Action: CreateSubject, Subject: "user-events", To: "events of users", IsAllowed: true},
+		{Action: SetCompatibility, Subject: "user-events", From: "NONE", To: "BACKWARD", IsAllowed: true},
+		{Action: RegisterSchema, Subject: "user-events", File: "v1.avsc", Version: "v1.0.0", IsAllowed: true, definition: v1},
+		{Action: RegisterSchema, Subject: "user-events", File: "v2.avsc", IsAllowed: true, definition: v2},
+		{Action: UpdateDescription, Subject: "page-views", From: "views", To: "views of pages", IsAllowed: true},
+		{Action: SetCompatibility, Subject: "page-views", From: "NONE", To: "FULL", IsAllowed: true},
+		{Action: RegisterSchema, Subject: "page-views", File: "v2.avsc", Version: "v1.1.0", IsAllowed: true, definition: v2},
+	}
+	if !reflect.DeepEqual(plan.Changes, expect) {
+		t.Fatalf("Plan = %+v, wants %+v", plan.Changes, expect)
+	}
+
+	applied, err := plan.Apply(context.Background(), server)
+	if err != nil {
+		t.Fatalf("Apply should not be an error but an error was occurred: %v", err)
+	}
+	if len(applied) != len(expect) || applied[3].Version != "v1.1.0" || applied[3].Id != 2 {
+		t.Errorf("every change is expected to be applied with the versions given but got %+v", applied)
+	}
+	if server.subjects["page-views"].Description != "views of pages" || server.configs["user-events"].Compatibility != "BACKWARD" {
+		t.Errorf("the description and config are expected to be applied")
+	}
+
+	plan, err = m.Plan(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 0 {
+		t.Errorf("no change is expected after the plan is applied but got %+v", plan.Changes)
+	}
+}
+
+func TestPlanNotAllowed(t *testing.T) {
+	server := newFakeServer()
+	server.subjects["user-events"] = &model.Subject{Name: "user-events"}
+	server.configs["user-events"] = model.Config{Compatibility: "NONE"}
+	server.schemas["user-events"] = []model.Schema{{Id: 1, Subject: "user-events", Version: model.SemVer{Major: 1}, Definition: v1}}
+	server.compatibilities[v2] = "FORWARD"
+
+	// FORWARD is allowed by the current config but not by the config to set
+	m := loadManifest(t, "subjects:\n  - name: user-events\n    config:\n      compatibility: BACKWARD\n    schemas: [v2.avsc]\n")
+	plan, err := m.Plan(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.IsAllowed() || len(plan.Changes) != 2 || plan.Changes[1].IsAllowed {
+		t.Errorf("v2.avsc is expected not to be allowed by BACKWARD but got %+v", plan.Changes)
+	}
+}
+
+func TestPlanPendingNotAllowed(t *testing.T) {
+	server := newFakeServer()
+	server.subjects["page-views"] = &model.Subject{Name: "page-views"}
+	server.configs["page-views"] = model.Config{Compatibility: "BACKWARD"}
+	server.schemas["page-views"] = []model.Schema{{Id: 1, Subject: "page-views", Version: model.SemVer{Major: 1}, Definition: v1}}
+	server.compatibilities[v2] = "BACKWARD"
+
+	// v3.avsc is incompatible with v2.avsc, which is not registered yet, and with v1.avsc
+	m := loadManifest(t, `
+subjects:
+  - name: user-events
+    config:
+      compatibility: BACKWARD
+    schemas: [v1.avsc, v2.avsc, v3.avsc]
+  - name: page-views
+    schemas: [v1.avsc, v2.avsc, v3.avsc]
+`)
+	plan, err := m.Plan(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := make([]bool, 0)
+	for _, c := range plan.Changes {
+		if c.Action == RegisterSchema {
+			allowed = append(allowed, c.IsAllowed)
+		}
+	}
+	if expect := []bool{true, true, false, true, false}; plan.IsAllowed() || !reflect.DeepEqual(allowed, expect) {
+		t.Errorf("only v3.avsc is expected not to be allowed by BACKWARD but got %+v", plan.Changes)
+	}
+}
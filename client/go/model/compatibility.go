@@ -25,3 +25,37 @@ package model
 type Compatibility struct {
 	IsCompatible bool `json:"is_compatible"`
 }
+
+// Kinds of Incompatibility
+const (
+	NameMismatch       = "NAME_MISMATCH"
+	FixedSizeMismatch  = "FIXED_SIZE_MISMATCH"
+	EnumSymbolRemoved  = "ENUM_SYMBOL_REMOVED"
+	MissingDefault     = "MISSING_DEFAULT"
+	TypeMismatch       = "TYPE_MISMATCH"
+	MissingUnionBranch = "MISSING_UNION_BRANCH"
+	AmbiguousField     = "AMBIGUOUS_FIELD"
+)
+
+// Incompatibility is a reason why data written with a writer's schema cannot be read with a reader's schema.
+// Path points to the reader's field in the form of `$.field.nested`, where `[]` stands for items of an array and `{}` values of a map.
+// Direction is BACKWARD if the target schema cannot read data written with the comparison, or FORWARD if vice versa.
+type Incompatibility struct {
+	Direction string `json:"direction"`
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	Reader    string `json:"reader"`
+	Writer    string `json:"writer"`
+	Message   string `json:"message"`
+}
+
+// CompatibilityReport is a detailed result of a compatibility check of a target schema against a comparison one.
+// IsCompatible is true if the target can read data written with the comparison as a typebook server checks.
+// Compatibility is one of NONE, FORWARD, BACKWARD or FULL.
+type CompatibilityReport struct {
+	IsCompatible      bool              `json:"is_compatible"`
+	Compatibility     string            `json:"compatibility"`
+	ComparisonId      int64             `json:"comparison_id,omitempty"`
+	ComparisonVersion string            `json:"comparison_version,omitempty"`
+	Incompatibilities []Incompatibility `json:"incompatibilities"`
+}
@@ -0,0 +1,176 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var partialVerRegExp = regexp.MustCompile("^v?(0|[1-9][0-9]*)(\\.(0|[1-9][0-9]*))?(\\.(0|[1-9][0-9]*))?$")
+
+// Constraint is a range of versions, which is expressed with the following syntax.
+//
+//	v2, 2           any version with the major version 2, i.e. >=v2.0.0 <v3.0.0
+//	v1.2, =1.2      any version with the major and minor versions, i.e. >=v1.2.0 <v1.3.0
+//	v1.2.3          exactly v1.2.3
+//	^1.2            a version which can be upgraded to without changing the major version, i.e. >=v1.2.0 <v2.0.0
+//	~1.2.3          a version which can be upgraded to without changing the minor version, i.e. >=v1.2.3 <v1.3.0
+//	>=1.0.0 <2.0.0  comparisons with >, >=, < and <=, all of which must be satisfied
+//	^1.2 || ^3      either of ranges
+//
+// The leading "v" of a version is optional, and omitted minor and patch versions are regarded as 0
+// except that comparisons with > and <= exclude and include all versions sharing the given part respectively.
+type Constraint struct {
+	text   string
+	ranges []versionRange
+}
+
+// versionRange is a range of versions from lower (inclusive) to upper (exclusive, unbounded if nil).
+type versionRange struct {
+	lower SemVer
+	upper *SemVer
+}
+
+func (r versionRange) contains(version SemVer) bool {
+	return !version.Less(r.lower) && (r.upper == nil || version.Less(*r.upper))
+}
+
+// intersect narrows r down to versions also within other.
+func (r versionRange) intersect(other versionRange) versionRange {
+	if r.lower.Less(other.lower) {
+		r.lower = other.lower
+	}
+	if other.upper != nil && (r.upper == nil || other.upper.Less(*r.upper)) {
+		r.upper = other.upper
+	}
+	return r
+}
+
+// NewConstraint parses a version constraint.
+// If a given constraint is not a valid syntax, this returns an error.
+func NewConstraint(constraint string) (*Constraint, error) {
+	c := &Constraint{text: constraint}
+	for _, alternative := range strings.Split(constraint, "||") {
+		// an operator may be separated from its version by spaces, e.g. ">= 1.0.0"
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid version constraint `%s`: a range is empty", constraint)
+		}
+		r := versionRange{}
+		for i := 0; i < len(fields); i++ {
+			comparator := fields[i]
+			if strings.Trim(comparator, "<>=^~") == "" && i+1 < len(fields) {
+				i++
+				comparator += fields[i]
+			}
+			cr, err := parseComparator(comparator)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint `%s`: %v", constraint, err)
+			}
+			r = r.intersect(cr)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// parseComparator parses an operator followed by a version, possibly with minor and patch versions omitted.
+func parseComparator(comparator string) (versionRange, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(comparator, o) {
+			op = o
+			break
+		}
+	}
+	version := comparator[len(op):]
+	matches := partialVerRegExp.FindStringSubmatch(version)
+	if matches == nil {
+		return versionRange{}, fmt.Errorf("`%s` is not a version", version)
+	}
+
+	// lower is the lowest version sharing the given part, and next is the lowest one above all of them
+	var lower, next SemVer
+	lower.Major, _ = strconv.Atoi(matches[1])
+	switch {
+	case matches[5] != "":
+		lower.Minor, _ = strconv.Atoi(matches[3])
+		lower.Patch, _ = strconv.Atoi(matches[5])
+		next = lower.NextPatch()
+	case matches[3] != "":
+		lower.Minor, _ = strconv.Atoi(matches[3])
+		next = lower.NextMinor()
+	default:
+		next = lower.NextMajor()
+	}
+
+	switch op {
+	case ">=":
+		return versionRange{lower: lower}, nil
+	case ">":
+		return versionRange{lower: next}, nil
+	case "<":
+		return versionRange{upper: &lower}, nil
+	case "<=":
+		return versionRange{upper: &next}, nil
+	case "^":
+		upper := lower.NextMajor()
+		return versionRange{lower: lower, upper: &upper}, nil
+	case "~":
+		upper := lower.NextMinor()
+		if matches[3] == "" {
+			upper = lower.NextMajor()
+		}
+		return versionRange{lower: lower, upper: &upper}, nil
+	}
+	return versionRange{lower: lower, upper: &next}, nil
+}
+
+func (c *Constraint) String() string {
+	return c.text
+}
+
+// Check reports whether the version satisfies the constraint.
+func (c *Constraint) Check(version SemVer) bool {
+	for _, r := range c.ranges {
+		if r.contains(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the highest version satisfying the constraint among versions.
+// The second return value is false if none of them satisfies it.
+func (c *Constraint) Latest(versions []SemVer) (SemVer, bool) {
+	satisfying := make([]SemVer, 0, len(versions))
+	for _, v := range versions {
+		if c.Check(v) {
+			satisfying = append(satisfying, v)
+		}
+	}
+	return MaxSemVer(satisfying)
+}
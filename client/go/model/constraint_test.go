@@ -0,0 +1,127 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package model
+
+import "testing"
+
+func TestConstraintCheck(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		matches    []string
+		mismatches []string
+	}{
+		{
+			constraint: "v2",
+			matches:    []string{"v2.0.0", "v2.10.3"},
+			mismatches: []string{"v1.9.9", "v3.0.0"},
+		},
+		{
+			constraint: "1.2",
+			matches:    []string{"v1.2.0", "v1.2.9"},
+			mismatches: []string{"v1.1.0", "v1.3.0"},
+		},
+		{
+			constraint: "v1.2.3",
+			matches:    []string{"v1.2.3"},
+			mismatches: []string{"v1.2.2", "v1.2.4"},
+		},
+		{
+			constraint: "^1.2",
+			matches:    []string{"v1.2.0", "v1.10.0"},
+			mismatches: []string{"v1.1.9", "v2.0.0"},
+		},
+		{
+			constraint: "~1.2.3",
+			matches:    []string{"v1.2.3", "v1.2.10"},
+			mismatches: []string{"v1.2.2", "v1.3.0"},
+		},
+		{
+			constraint: "~1",
+			matches:    []string{"v1.0.0", "v1.5.0"},
+			mismatches: []string{"v0.9.0", "v2.0.0"},
+		},
+		{
+			constraint: ">=1.0.0 <2.0.0",
+			matches:    []string{"v1.0.0", "v1.99.99"},
+			mismatches: []string{"v0.1.0", "v2.0.0"},
+		},
+		{
+			constraint: ">= v1.2 <= v1.3",
+			matches:    []string{"v1.2.0", "v1.3.5"},
+			mismatches: []string{"v1.1.9", "v1.4.0"},
+		},
+		{
+			constraint: ">1.2 <3",
+			matches:    []string{"v1.3.0", "v2.9.9"},
+			mismatches: []string{"v1.2.9", "v3.0.0"},
+		},
+		{
+			constraint: "^1.2 || v3",
+			matches:    []string{"v1.5.0", "v3.1.0"},
+			mismatches: []string{"v2.0.0", "v4.0.0"},
+		},
+	}
+	for _, testCase := range testCases {
+		c, err := NewConstraint(testCase.constraint)
+		if err != nil {
+			t.Errorf("NewConstraint(%s) causes an error: %v", testCase.constraint, err)
+			continue
+		}
+		for _, version := range testCase.matches {
+			if semver, _ := NewSemVer(version); !c.Check(*semver) {
+				t.Errorf("%s is expected to satisfy `%s`", version, testCase.constraint)
+			}
+		}
+		for _, version := range testCase.mismatches {
+			if semver, _ := NewSemVer(version); c.Check(*semver) {
+				t.Errorf("%s is not expected to satisfy `%s`", version, testCase.constraint)
+			}
+		}
+	}
+
+	abnormalCases := []string{
+		"",
+		"latest",
+		"^",
+		"v1.2.3.4",
+		">=01.0.0",
+		"^1 ||",
+	}
+	for _, testCase := range abnormalCases {
+		if _, err := NewConstraint(testCase); err == nil {
+			t.Errorf("NewConstraint(%s) should have caused an error. But no error occurred.", testCase)
+		}
+	}
+}
+
+func TestConstraintLatest(t *testing.T) {
+	versions := []SemVer{{Major: 1, Minor: 2}, {Major: 1, Minor: 10, Patch: 1}, {Major: 1, Minor: 3}, {Major: 2}}
+	c, _ := NewConstraint("v1")
+	if latest, ok := c.Latest(versions); !ok || latest != (SemVer{Major: 1, Minor: 10, Patch: 1}) {
+		t.Errorf("the latest version satisfying v1 among %v = %v, wants v1.10.1", versions, latest)
+	}
+	c, _ = NewConstraint("^3")
+	if latest, ok := c.Latest(versions); ok {
+		t.Errorf("no version among %v is expected to satisfy ^3 but got %v", versions, latest)
+	}
+}
@@ -0,0 +1,56 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package model
+
+// Kinds of Change
+const (
+	FieldAdded         = "FIELD_ADDED"
+	FieldRemoved       = "FIELD_REMOVED"
+	FieldRenamed       = "FIELD_RENAMED"
+	TypeChanged        = "TYPE_CHANGED"
+	TypePromoted       = "TYPE_PROMOTED"
+	LogicalTypeChanged = "LOGICAL_TYPE_CHANGED"
+	UnionBranchAdded   = "UNION_BRANCH_ADDED"
+	UnionBranchRemoved = "UNION_BRANCH_REMOVED"
+	DefaultAdded       = "DEFAULT_ADDED"
+	DefaultRemoved     = "DEFAULT_REMOVED"
+	DefaultChanged     = "DEFAULT_CHANGED"
+	DocChanged         = "DOC_CHANGED"
+	AliasesChanged     = "ALIASES_CHANGED"
+	OrderChanged       = "ORDER_CHANGED"
+	NameChanged        = "NAME_CHANGED"
+	SymbolAdded        = "SYMBOL_ADDED"
+	SymbolRemoved      = "SYMBOL_REMOVED"
+	SizeChanged        = "SIZE_CHANGED"
+)
+
+// Change is a semantic difference from a schema to another.
+// Path points to the node in the new schema in the same form as Incompatibility, or in the old one if the node is removed.
+// From and To describe the node before and after the change, e.g. types, defaults in JSON or docs, and either is empty if it is absent.
+type Change struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Message string `json:"message"`
+}
@@ -22,7 +22,10 @@
 
 package model
 
-import "strings"
+import (
+	"net/http"
+	"strings"
+)
 
 type ServerError struct {
 	ErrorCode int    `json:"error_code"`
@@ -61,6 +64,11 @@ func (err *Error) Error() string {
 	return strings.Join(err.Messages(), "\n")
 }
 
+// IsNotFound reports whether err is caused by a typebook server replying 404 Not Found.
+func (err *Error) IsNotFound() bool {
+	return err != nil && err.ServerError != nil && err.ErrorCode == http.StatusNotFound
+}
+
 func NewError(serverError *ServerError, clientError []error) *Error {
 	return &Error{
 		ServerError: serverError,
@@ -25,6 +25,7 @@ package model
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -43,6 +44,67 @@ func (sv *SemVer) String() string {
 	return fmt.Sprintf("v%d.%d.%d", sv.Major, sv.Minor, sv.Patch)
 }
 
+// Compare compares sv with other by major, minor and patch versions in this order.
+// It returns -1 if sv is lower than other, 1 if it is higher and 0 if they are the same.
+func (sv SemVer) Compare(other SemVer) int {
+	for _, d := range [...]int{sv.Major - other.Major, sv.Minor - other.Minor, sv.Patch - other.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Less reports whether sv is lower than other.
+func (sv SemVer) Less(other SemVer) bool {
+	return sv.Compare(other) < 0
+}
+
+// NextMajor returns the version whose major version is bumped from sv, i.e. vX+1.0.0.
+func (sv SemVer) NextMajor() SemVer {
+	return SemVer{Major: sv.Major + 1}
+}
+
+// NextMinor returns the version whose minor version is bumped from sv, i.e. vX.Y+1.0.
+func (sv SemVer) NextMinor() SemVer {
+	return SemVer{Major: sv.Major, Minor: sv.Minor + 1}
+}
+
+// NextPatch returns the version whose patch version is bumped from sv, i.e. vX.Y.Z+1.
+func (sv SemVer) NextPatch() SemVer {
+	return SemVer{Major: sv.Major, Minor: sv.Minor, Patch: sv.Patch + 1}
+}
+
+// SemVers attaches the methods of sort.Interface to []SemVer, sorting in increasing order.
+type SemVers []SemVer
+
+func (svs SemVers) Len() int           { return len(svs) }
+func (svs SemVers) Less(i, j int) bool { return svs[i].Less(svs[j]) }
+func (svs SemVers) Swap(i, j int)      { svs[i], svs[j] = svs[j], svs[i] }
+
+// SortSemVers sorts versions in increasing order.
+func SortSemVers(versions []SemVer) {
+	sort.Sort(SemVers(versions))
+}
+
+// MaxSemVer returns the highest version among versions.
+// The second return value is false if versions is empty.
+func MaxSemVer(versions []SemVer) (SemVer, bool) {
+	if len(versions) == 0 {
+		return SemVer{}, false
+	}
+	max := versions[0]
+	for _, v := range versions[1:] {
+		if max.Less(v) {
+			max = v
+		}
+	}
+	return max, true
+}
+
 // IsSemVer checks if the given version string is valid form of semantic version and return true when it is valid.
 func IsSemVer(version string) bool {
 	return SemVerRegExp.MatchString(version)
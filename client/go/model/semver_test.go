@@ -58,3 +58,54 @@ func TestNewSemVer(t *testing.T) {
 		}
 	}
 }
+
+func TestSemVerCompare(t *testing.T) {
+	testCases := []struct {
+		a, b   SemVer
+		expect int
+	}{
+		{a: SemVer{Major: 1, Minor: 2, Patch: 3}, b: SemVer{Major: 1, Minor: 2, Patch: 3}, expect: 0},
+		{a: SemVer{Major: 1, Minor: 10}, b: SemVer{Major: 1, Minor: 2}, expect: 1},
+		{a: SemVer{Major: 1, Minor: 9, Patch: 9}, b: SemVer{Major: 2}, expect: -1},
+		{a: SemVer{Major: 1, Minor: 2, Patch: 3}, b: SemVer{Major: 1, Minor: 2, Patch: 4}, expect: -1},
+	}
+	for _, testCase := range testCases {
+		if actual := testCase.a.Compare(testCase.b); actual != testCase.expect {
+			t.Errorf("%v.Compare(%v) = %d, wants %d", testCase.a, testCase.b, actual, testCase.expect)
+		}
+		if actual := testCase.a.Less(testCase.b); actual != (testCase.expect < 0) {
+			t.Errorf("%v.Less(%v) = %t, wants %t", testCase.a, testCase.b, actual, testCase.expect < 0)
+		}
+	}
+}
+
+func TestSemVerNext(t *testing.T) {
+	version := SemVer{Major: 1, Minor: 2, Patch: 3}
+	if next := version.NextMajor(); next != (SemVer{Major: 2}) {
+		t.Errorf("NextMajor of v1.2.3 = %v, wants v2.0.0", next)
+	}
+	if next := version.NextMinor(); next != (SemVer{Major: 1, Minor: 3}) {
+		t.Errorf("NextMinor of v1.2.3 = %v, wants v1.3.0", next)
+	}
+	if next := version.NextPatch(); next != (SemVer{Major: 1, Minor: 2, Patch: 4}) {
+		t.Errorf("NextPatch of v1.2.3 = %v, wants v1.2.4", next)
+	}
+}
+
+func TestSortSemVers(t *testing.T) {
+	versions := []SemVer{{Major: 2}, {Major: 1, Minor: 10}, {Major: 1, Minor: 2, Patch: 1}, {Major: 1, Minor: 2}}
+	SortSemVers(versions)
+	expect := []SemVer{{Major: 1, Minor: 2}, {Major: 1, Minor: 2, Patch: 1}, {Major: 1, Minor: 10}, {Major: 2}}
+	for i := range expect {
+		if versions[i] != expect[i] {
+			t.Fatalf("SortSemVers sorted versions into %v, wants %v", versions, expect)
+		}
+	}
+
+	if max, ok := MaxSemVer(versions[:3]); !ok || max != (SemVer{Major: 1, Minor: 10}) {
+		t.Errorf("MaxSemVer(%v) = %v, wants v1.10.0", versions[:3], max)
+	}
+	if _, ok := MaxSemVer(nil); ok {
+		t.Errorf("MaxSemVer of no versions should not return a version")
+	}
+}
@@ -0,0 +1,72 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package model
+
+import "encoding/json"
+
+// VersionComparison is the compatibility of a schema to register with an existing one.
+// IsDecisive is true for the schema that decided the next version.
+type VersionComparison struct {
+	Id            int64
+	Version       SemVer
+	Compatibility string
+	IsDecisive    bool
+}
+
+func (vc VersionComparison) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":            vc.Id,
+		"version":       vc.Version.String(),
+		"compatibility": vc.Compatibility,
+		"is_decisive":   vc.IsDecisive,
+	})
+}
+
+// VersionPrediction is the version that a schema will be given when it is registered under a subject.
+// IsNew is false when the schema is the same as the latest one, in which case ExistingId is its ID.
+// IsAllowed is false when the schema violates Restriction, the compatibility configured to the subject.
+type VersionPrediction struct {
+	Subject     string
+	Version     SemVer
+	IsNew       bool
+	ExistingId  int64
+	Restriction string
+	IsAllowed   bool
+	Comparisons []VersionComparison
+}
+
+func (vp VersionPrediction) MarshalJSON() ([]byte, error) {
+	comparisons := vp.Comparisons
+	if comparisons == nil {
+		comparisons = []VersionComparison{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"subject":     vp.Subject,
+		"version":     vp.Version.String(),
+		"is_new":      vp.IsNew,
+		"existing_id": vp.ExistingId,
+		"restriction": vp.Restriction,
+		"is_allowed":  vp.IsAllowed,
+		"comparisons": comparisons,
+	})
+}
@@ -0,0 +1,143 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how a Client retries a request which has failed transiently.
+// Requests that only read data (GET, lookup and compatibility check) are retried.
+// RegisterSchema is retried only if RetryRegisterSchema is true because it creates a schema,
+// although a typebook server does not create a new one if the posted schema is identical to the latest.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the duration to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the duration to wait between attempts.
+	MaxBackoff time.Duration
+	// Multiplier is the factor to multiply the backoff by after each retry.
+	Multiplier float64
+	// Jitter randomizes each backoff within the range of ±Jitter (0.0 to 1.0) of it.
+	Jitter float64
+	// RetryableStatusCodes are status codes of responses which should be retried.
+	// Transport errors such as timeouts and reset or refused connections are always retried,
+	// while the other errors are not.
+	RetryableStatusCodes []int
+	// RetryRegisterSchema enables retries for RegisterSchema.
+	RetryRegisterSchema bool
+}
+
+// DefaultRetryPolicy is a RetryPolicy with reasonable defaults for typical deployments.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryableStatusCodes: []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// shouldRetry reports whether a request should be sent again after it ends up with the given response or error.
+func (rp *RetryPolicy) shouldRetry(ctx context.Context, response *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return isTransient(err)
+	}
+	for _, code := range rp.RetryableStatusCodes {
+		if response.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient reports whether err is a transport error which may not occur again,
+// i.e. a timeout, a refused or reset connection, or a connection closed before a whole response arrives.
+// Other errors such as those of an Authenticator are not transient.
+func isTransient(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		cause := opErr.Err
+		if syscallErr, ok := cause.(*os.SyscallError); ok {
+			cause = syscallErr.Err
+		}
+		if cause == syscall.ECONNREFUSED || cause == syscall.ECONNRESET {
+			return true
+		}
+	}
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
+
+// backoff returns the duration to wait before the given number of retry that begins with 1.
+func (rp *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := float64(rp.InitialBackoff)
+	if rp.Multiplier > 0 {
+		backoff *= math.Pow(rp.Multiplier, float64(retry-1))
+	}
+	if rp.MaxBackoff > 0 && backoff > float64(rp.MaxBackoff) {
+		backoff = float64(rp.MaxBackoff)
+	}
+	if rp.Jitter > 0 {
+		backoff *= 1 + rp.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(backoff)
+}
+
+// sleep waits for the given duration unless ctx is done in the meantime.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
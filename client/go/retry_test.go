@@ -0,0 +1,185 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	InitialBackoff:       time.Millisecond,
+	MaxBackoff:           10 * time.Millisecond,
+	Multiplier:           2,
+	Jitter:               0.2,
+	RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+}
+
+// flakyServer fails the first `failures` requests by replying 503 or resetting the connection,
+// then it replies the given body.
+type flakyServer struct {
+	*httptest.Server
+	requests int32
+}
+
+func newFlakyServer(t *testing.T, failures int32, reset bool, status int, body interface{}) *flakyServer {
+	fs := new(flakyServer)
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fs.requests, 1) <= failures {
+			if reset {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error_code":503,"message":"service unavailable"}`))
+			return
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	return fs
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	server := newFlakyServer(t, 2, false, http.StatusOK, testSchema())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(testRetryPolicy))
+	if _, err := client.GetSchemaById(1); err != nil {
+		t.Errorf("GetSchemaById(1) should be retried and succeed. But an error was occurred: %v", err)
+	}
+	if server.requests != 3 {
+		t.Errorf("the server received %d requests, wants 3", server.requests)
+	}
+}
+
+func TestRetryOnConnectionReset(t *testing.T) {
+	server := newFlakyServer(t, 1, true, http.StatusOK, testSchema())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(testRetryPolicy))
+	if _, err := client.LookupSchema(subject, schemaDef); err != nil {
+		t.Errorf("LookupSchema(%s, ...) should be retried and succeed. But an error was occurred: %v", subject, err)
+	}
+	if server.requests != 2 {
+		t.Errorf("the server received %d requests, wants 2", server.requests)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	server := newFlakyServer(t, 5, false, http.StatusOK, testSchema())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(testRetryPolicy))
+	if _, err := client.GetLatestSchema(subject); err == nil || err.ServerError == nil || err.ErrorCode != http.StatusServiceUnavailable {
+		t.Errorf("GetLatestSchema(%s) should be an error with code 503 after all attempts, but %v", subject, err)
+	}
+	if server.requests != 3 {
+		t.Errorf("the server received %d requests, wants 3", server.requests)
+	}
+}
+
+func TestNoRetryForRegisterSchema(t *testing.T) {
+	server := newFlakyServer(t, 1, false, http.StatusCreated, model.SchemaId{Id: 1})
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(testRetryPolicy))
+	if _, err := client.RegisterSchema(subject, schemaDef); err == nil {
+		t.Errorf("RegisterSchema(%s, ...) should not be retried by default. But no error was occurred", subject)
+	}
+	if server.requests != 1 {
+		t.Errorf("the server received %d requests, wants 1", server.requests)
+	}
+
+	policy := testRetryPolicy
+	policy.RetryRegisterSchema = true
+	server.requests = 0
+	client = NewClient(server.URL, WithRetryPolicy(policy))
+	if _, err := client.RegisterSchema(subject, schemaDef); err != nil {
+		t.Errorf("RegisterSchema(%s, ...) should be retried when it is enabled. But an error was occurred: %v", subject, err)
+	}
+}
+
+func TestNoRetryForAuthError(t *testing.T) {
+	server := newFlakyServer(t, 0, false, http.StatusOK, testSchema())
+	defer server.Close()
+
+	var calls int32
+	auth := TokenSource(func(ctx context.Context, forceRefresh bool) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", errors.New("invalid credentials")
+	})
+	client := NewClient(server.URL, WithRetryPolicy(testRetryPolicy), WithAuth(auth))
+	if _, err := client.GetSchemaById(1); err == nil {
+		t.Errorf("GetSchemaById(1) should be an error when no token is obtained. But no error was occurred")
+	}
+	if calls != 1 || server.requests != 0 {
+		t.Errorf("an authentication error should not be retried, but the token was requested %d times", calls)
+	}
+}
+
+func TestNoRetryWithoutPolicy(t *testing.T) {
+	server := newFlakyServer(t, 1, false, http.StatusOK, testSchema())
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).GetSchemaById(1); err == nil {
+		t.Errorf("GetSchemaById(1) should not be retried without a retry policy. But no error was occurred")
+	}
+	if server.requests != 1 {
+		t.Errorf("the server received %d requests, wants 1", server.requests)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	expects := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, expect := range expects {
+		if actual := policy.backoff(i + 1); actual != expect {
+			t.Errorf("backoff(%d) = %v, wants %v", i+1, actual, expect)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if actual := policy.backoff(1); actual < 50*time.Millisecond || actual > 150*time.Millisecond {
+			t.Errorf("backoff(1) with jitter = %v, wants within [50ms, 150ms]", actual)
+		}
+	}
+}
+
+func testSchema() model.Schema {
+	return model.Schema{Id: 1, Subject: subject, Version: model.SemVer{Major: 1}, Definition: schemaDef}
+}
@@ -23,26 +23,41 @@
 package _go
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
 	"github.com/cyberagent/typebook/client/go/model"
+	"github.com/cyberagent/typebook/client/go/version"
 )
 
+// ErrNoMatchingVersion is returned by ResolveVersion when no version under the subject satisfies the constraint.
+var ErrNoMatchingVersion = errors.New("no version satisfies the constraint")
+
 type schemaClient struct {
 	*baseClient
 }
 
 // RegisterSchema issues a POST /subjects/(subject string)/versions request with a schema definition in its body to a typebook server.
 // It will register a new schema under the given subject according to the given definition.
+// The definition is validated as an Avro schema before the request is issued.
 // This method returns model.SchemaId that represents id for the created schema on success, otherwise non-nil model.Error is returned.
 func (sc *schemaClient) RegisterSchema(subject, definition string) (*model.SchemaId, *model.Error) {
-	response, body, errs := sc.baseClient.
-		Post(fmt.Sprintf("/subjects/%s/versions", subject)).
-		Type("json").
-		Send(definition).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.RegisterSchemaContext(context.Background(), subject, definition)
+}
+
+// RegisterSchemaContext is the same as RegisterSchema except that the request is bound to the given context.
+func (sc *schemaClient) RegisterSchemaContext(ctx context.Context, subject, definition string) (*model.SchemaId, *model.Error) {
+	if _, err := avro.Parse(definition); err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+
+	retryable := sc.baseClient.retryPolicy != nil && sc.baseClient.retryPolicy.RetryRegisterSchema
+	body, err := sc.baseClient.Post(ctx, fmt.Sprintf("/subjects/%s/versions", subject), contentTypeJSON, []byte(definition), retryable)
+	if err != nil {
 		return nil, err
 	}
 
@@ -53,17 +68,47 @@ func (sc *schemaClient) RegisterSchema(subject, definition string) (*model.Schem
 	return id, nil
 }
 
+// RegisterType issues a POST /subjects/(subject string)/versions request with a schema derived from the Go type of v to a typebook server.
+// The schema is derived by avro.SchemaOf, so values of the type can be serialized with the registered schema.
+// This method returns model.SchemaId that represents id for the created schema on success, otherwise non-nil model.Error is returned.
+func (sc *schemaClient) RegisterType(subject string, v interface{}) (*model.SchemaId, *model.Error) {
+	return sc.RegisterTypeContext(context.Background(), subject, v)
+}
+
+// RegisterTypeContext is the same as RegisterType except that the request is bound to the given context.
+func (sc *schemaClient) RegisterTypeContext(ctx context.Context, subject string, v interface{}) (*model.SchemaId, *model.Error) {
+	definition, err := definitionOf(v)
+	if err != nil {
+		return nil, err
+	}
+	return sc.RegisterSchemaContext(ctx, subject, definition)
+}
+
+// definitionOf returns the definition of the schema derived from the Go type of v.
+func definitionOf(v interface{}) (string, *model.Error) {
+	s, err := avro.SchemaOf(v)
+	if err != nil {
+		return "", model.NewError(nil, []error{err})
+	}
+	b, err := avro.Marshal(s)
+	if err != nil {
+		return "", model.NewError(nil, []error{err})
+	}
+	return string(b), nil
+}
+
 // LookupSchema issues a POST /subjects/(subject string)/schema/lookup request with a schema definition to lookup in its body to a typebook server.
 // It will lookup a schema by its definition within the given subject.
 // If multiple schemas are found, the latest one is chosen.
 // This method returns model.Schema whose definition conforms to the given one if found otherwise it returns non-nil model.Error.
 func (sc *schemaClient) LookupSchema(subject, definition string) (*model.Schema, *model.Error) {
-	response, body, errs := sc.baseClient.
-		Post(fmt.Sprintf("/subjects/%s/schema/lookup", subject)).
-		Type("json").
-		Send(definition).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.LookupSchemaContext(context.Background(), subject, definition)
+}
+
+// LookupSchemaContext is the same as LookupSchema except that the request is bound to the given context.
+func (sc *schemaClient) LookupSchemaContext(ctx context.Context, subject, definition string) (*model.Schema, *model.Error) {
+	body, err := sc.baseClient.Post(ctx, fmt.Sprintf("/subjects/%s/schema/lookup", subject), contentTypeJSON, []byte(definition), true)
+	if err != nil {
 		return nil, err
 	}
 
@@ -71,6 +116,7 @@ func (sc *schemaClient) LookupSchema(subject, definition string) (*model.Schema,
 	if err := json.Unmarshal(body, schema); err != nil {
 		return nil, model.NewError(nil, []error{err})
 	}
+	sc.baseClient.diskCache.Put(schema)
 	return schema, nil
 }
 
@@ -79,12 +125,13 @@ func (sc *schemaClient) LookupSchema(subject, definition string) (*model.Schema,
 // If multiple schemas are found, all schemas are returned.
 // This method returns non-nil model.Error on failure.
 func (sc *schemaClient) LookupAllSchemas(subject, definition string) ([]model.Schema, *model.Error) {
-	response, body, errs := sc.baseClient.
-		Post(fmt.Sprintf("/subjects/%s/schema/lookupAll", subject)).
-		Type("json").
-		Send(definition).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.LookupAllSchemasContext(context.Background(), subject, definition)
+}
+
+// LookupAllSchemasContext is the same as LookupAllSchemas except that the request is bound to the given context.
+func (sc *schemaClient) LookupAllSchemasContext(ctx context.Context, subject, definition string) ([]model.Schema, *model.Error) {
+	body, err := sc.baseClient.Post(ctx, fmt.Sprintf("/subjects/%s/schema/lookupAll", subject), contentTypeJSON, []byte(definition), true)
+	if err != nil {
 		return nil, err
 	}
 
@@ -92,6 +139,9 @@ func (sc *schemaClient) LookupAllSchemas(subject, definition string) ([]model.Sc
 	if err := json.Unmarshal(body, &schemas); err != nil {
 		return nil, model.NewError(nil, []error{err})
 	}
+	for i := range schemas {
+		sc.baseClient.diskCache.Put(&schemas[i])
+	}
 	return schemas, nil
 }
 
@@ -99,8 +149,16 @@ func (sc *schemaClient) LookupAllSchemas(subject, definition string) ([]model.Sc
 // It will retrieve the schema that matches the given id.
 // This method returns model.Schema on success, otherwise non-nil model.Error is returned.
 func (sc *schemaClient) GetSchemaById(id int64) (*model.Schema, *model.Error) {
-	response, body, errs := sc.baseClient.Get(fmt.Sprintf("/schemas/ids/%d", id)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.GetSchemaByIdContext(context.Background(), id)
+}
+
+// GetSchemaByIdContext is the same as GetSchemaById except that the request is bound to the given context.
+func (sc *schemaClient) GetSchemaByIdContext(ctx context.Context, id int64) (*model.Schema, *model.Error) {
+	if schema, ok := sc.baseClient.diskCache.GetById(id); ok {
+		return schema, nil
+	}
+	body, err := sc.baseClient.Get(ctx, fmt.Sprintf("/schemas/ids/%d", id))
+	if err != nil {
 		return nil, err
 	}
 
@@ -108,12 +166,13 @@ func (sc *schemaClient) GetSchemaById(id int64) (*model.Schema, *model.Error) {
 	if err := json.Unmarshal(body, schema); err != nil {
 		return nil, model.NewError(nil, []error{err})
 	}
+	sc.baseClient.diskCache.Put(schema)
 	return schema, nil
 }
 
-func (sc *schemaClient) getSchemaByVersionString(subject, version string) (*model.Schema, *model.Error) {
-	response, body, errs := sc.baseClient.Get(fmt.Sprintf("/subjects/%s/versions/%s", subject, version)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+func (sc *schemaClient) getSchemaByVersionString(ctx context.Context, subject, version string) (*model.Schema, *model.Error) {
+	body, err := sc.baseClient.Get(ctx, fmt.Sprintf("/subjects/%s/versions/%s", subject, version))
+	if err != nil {
 		return nil, err
 	}
 
@@ -121,36 +180,78 @@ func (sc *schemaClient) getSchemaByVersionString(subject, version string) (*mode
 	if err := json.Unmarshal(body, schema); err != nil {
 		return nil, model.NewError(nil, []error{err})
 	}
+	sc.baseClient.diskCache.Put(schema)
 	return schema, nil
 }
 
+// getLatestFromDisk returns the cached schema with the highest version in offline mode.
+// A negative majorVersion means any major version.
+func (sc *schemaClient) getLatestFromDisk(subject string, majorVersion int) (*model.Schema, *model.Error) {
+	if schema, ok := sc.baseClient.diskCache.GetLatest(subject, majorVersion); ok {
+		return schema, nil
+	}
+	return nil, model.NewError(nil, []error{ErrOffline})
+}
+
 // GetLatestSchema issues a GET /subjects/(subject string)/versions/latest request to a typebook server.
 // It will retrieve the latest schema under the given subject.
 // This method returns model.Schema on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) GetLatestSchema(subject string) (*model.Schema, *model.Error) {
-	return sc.getSchemaByVersionString(subject, "latest")
+	return sc.GetLatestSchemaContext(context.Background(), subject)
+}
+
+// GetLatestSchemaContext is the same as GetLatestSchema except that the request is bound to the given context.
+func (sc *schemaClient) GetLatestSchemaContext(ctx context.Context, subject string) (*model.Schema, *model.Error) {
+	if sc.baseClient.offline {
+		return sc.getLatestFromDisk(subject, -1)
+	}
+	return sc.getSchemaByVersionString(ctx, subject, "latest")
 }
 
 // GetSchemaByMajorVersion issues a GET /subjects/(subject string)/versions/v(majorVersion int) request to a typebook server.
 // It will retrieve a latest schema that has designated major version under the given subject.
 // This method returns model.Schema on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) GetSchemaByMajorVersion(subject string, majorVersion int) (*model.Schema, *model.Error) {
-	return sc.getSchemaByVersionString(subject, fmt.Sprintf("v%d", majorVersion))
+	return sc.GetSchemaByMajorVersionContext(context.Background(), subject, majorVersion)
+}
+
+// GetSchemaByMajorVersionContext is the same as GetSchemaByMajorVersion except that the request is bound to the given context.
+func (sc *schemaClient) GetSchemaByMajorVersionContext(ctx context.Context, subject string, majorVersion int) (*model.Schema, *model.Error) {
+	if sc.baseClient.offline {
+		return sc.getLatestFromDisk(subject, majorVersion)
+	}
+	return sc.getSchemaByVersionString(ctx, subject, fmt.Sprintf("v%d", majorVersion))
 }
 
 // GetSchemaBySemVer issues a GET /subjects/(subject string)/versions/(semver string) request to a typebook server.
 // It will retrieve a schema that has designated semver under the given subject.
 // This method returns model.Schema on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) GetSchemaBySemVer(subject string, semver model.SemVer) (*model.Schema, *model.Error) {
-	return sc.getSchemaByVersionString(subject, semver.String())
+	return sc.GetSchemaBySemVerContext(context.Background(), subject, semver)
+}
+
+// GetSchemaBySemVerContext is the same as GetSchemaBySemVer except that the request is bound to the given context.
+func (sc *schemaClient) GetSchemaBySemVerContext(ctx context.Context, subject string, semver model.SemVer) (*model.Schema, *model.Error) {
+	if schema, ok := sc.baseClient.diskCache.GetBySemVer(subject, semver); ok {
+		return schema, nil
+	}
+	return sc.getSchemaByVersionString(ctx, subject, semver.String())
 }
 
 // ListVersions issues a GET /subjects/(subject string)/versions request to a typebook server.
 // It retrieves all existing versions under the given subject.
 // This method returns a list of model.SemVer on success, otherwise non-nil model.Error is returned.
 func (sc *schemaClient) ListVersions(subject string) ([]model.SemVer, *model.Error) {
-	response, body, errs := sc.baseClient.Get(fmt.Sprintf("/subjects/%s/versions", subject)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.ListVersionsContext(context.Background(), subject)
+}
+
+// ListVersionsContext is the same as ListVersions except that the request is bound to the given context.
+func (sc *schemaClient) ListVersionsContext(ctx context.Context, subject string) ([]model.SemVer, *model.Error) {
+	if sc.baseClient.offline {
+		return sc.baseClient.diskCache.ListVersions(subject), nil
+	}
+	body, err := sc.baseClient.Get(ctx, fmt.Sprintf("/subjects/%s/versions", subject))
+	if err != nil {
 		return nil, err
 	}
 
@@ -170,13 +271,35 @@ func (sc *schemaClient) ListVersions(subject string) ([]model.SemVer, *model.Err
 	return semvers, nil
 }
 
-func (sc *schemaClient) checkCompatibilityWithVersion(subject, version, definition string) (*model.Compatibility, *model.Error) {
-	response, body, errs := sc.baseClient.
-		Post(fmt.Sprintf("/compatibility/subjects/%s/versions/%s", subject, version)).
-		Type("json").
-		Send(definition).
-		EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+// ResolveVersion issues a GET /subjects/(subject string)/versions request to a typebook server
+// and picks the highest version satisfying the given constraint (e.g. ^1.2, ~1.2.3, >=1.0.0 <2.0.0 or v2).
+// See model.Constraint for the syntax of a constraint.
+// This method returns model.SemVer on success otherwise non-nil model.Error is returned,
+// which wraps ErrNoMatchingVersion if no version satisfies the constraint.
+func (sc *schemaClient) ResolveVersion(subject, constraint string) (*model.SemVer, *model.Error) {
+	return sc.ResolveVersionContext(context.Background(), subject, constraint)
+}
+
+// ResolveVersionContext is the same as ResolveVersion except that the request is bound to the given context.
+func (sc *schemaClient) ResolveVersionContext(ctx context.Context, subject, constraint string) (*model.SemVer, *model.Error) {
+	c, err := model.NewConstraint(constraint)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	versions, merr := sc.ListVersionsContext(ctx, subject)
+	if merr != nil {
+		return nil, merr
+	}
+	latest, ok := c.Latest(versions)
+	if !ok {
+		return nil, model.NewError(nil, []error{ErrNoMatchingVersion})
+	}
+	return &latest, nil
+}
+
+func (sc *schemaClient) checkCompatibilityWithVersion(ctx context.Context, subject, version, definition string) (*model.Compatibility, *model.Error) {
+	body, err := sc.baseClient.Post(ctx, fmt.Sprintf("/compatibility/subjects/%s/versions/%s", subject, version), contentTypeJSON, []byte(definition), true)
+	if err != nil {
 		return nil, err
 	}
 
@@ -191,19 +314,194 @@ func (sc *schemaClient) checkCompatibilityWithVersion(subject, version, definiti
 // It will check if the posted schema is compatible with the latest one under the given subject.
 // This method returns model.Compatibility on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) CheckCompatibilityWithLatest(subject, definition string) (*model.Compatibility, *model.Error) {
-	return sc.checkCompatibilityWithVersion(subject, "latest", definition)
+	return sc.CheckCompatibilityWithLatestContext(context.Background(), subject, definition)
+}
+
+// CheckCompatibilityWithLatestContext is the same as CheckCompatibilityWithLatest except that the request is bound to the given context.
+func (sc *schemaClient) CheckCompatibilityWithLatestContext(ctx context.Context, subject, definition string) (*model.Compatibility, *model.Error) {
+	return sc.checkCompatibilityWithVersion(ctx, subject, "latest", definition)
 }
 
 // CheckCompatibilityWithMajorVersion issues POST /compatibility/subjects/(subject string)/versions/v(majorVersion int) with a schema definition in its body to a typebook server.
 // It will check if the posted schema is compatible with the latest one that has the designated major version under the given subject.
 // This method returns model.Compatibility on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) CheckCompatibilityWithMajorVersion(subject string, majorVersion int, definition string) (*model.Compatibility, *model.Error) {
-	return sc.checkCompatibilityWithVersion(subject, fmt.Sprintf("v%d", majorVersion), definition)
+	return sc.CheckCompatibilityWithMajorVersionContext(context.Background(), subject, majorVersion, definition)
+}
+
+// CheckCompatibilityWithMajorVersionContext is the same as CheckCompatibilityWithMajorVersion except that the request is bound to the given context.
+func (sc *schemaClient) CheckCompatibilityWithMajorVersionContext(ctx context.Context, subject string, majorVersion int, definition string) (*model.Compatibility, *model.Error) {
+	return sc.checkCompatibilityWithVersion(ctx, subject, fmt.Sprintf("v%d", majorVersion), definition)
 }
 
 // CheckCompatibilityWithSemVer issues POST /compatibility/subjects/(subject string)/versions/(semver string) with a schema definition in its body to a typebook server.
 // It will check if the posted schema is compatible with the one that has the designated semver under the given subject.
 // This method returns model.Compatibility on success otherwise non-nil model.Error is returned.
 func (sc *schemaClient) CheckCompatibilityWithSemVer(subject string, semver model.SemVer, definition string) (*model.Compatibility, *model.Error) {
-	return sc.checkCompatibilityWithVersion(subject, semver.String(), definition)
+	return sc.CheckCompatibilityWithSemVerContext(context.Background(), subject, semver, definition)
+}
+
+// CheckCompatibilityWithSemVerContext is the same as CheckCompatibilityWithSemVer except that the request is bound to the given context.
+func (sc *schemaClient) CheckCompatibilityWithSemVerContext(ctx context.Context, subject string, semver model.SemVer, definition string) (*model.Compatibility, *model.Error) {
+	return sc.checkCompatibilityWithVersion(ctx, subject, semver.String(), definition)
+}
+
+func reportCompatibility(definition string, comparison *model.Schema) (*model.CompatibilityReport, *model.Error) {
+	target, err := avro.Parse(definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	comparisonSchema, err := avro.Parse(comparison.Definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+
+	report := compatibility.Report(target, comparisonSchema)
+	report.ComparisonId = comparison.Id
+	report.ComparisonVersion = comparison.Version.String()
+	return report, nil
+}
+
+// ReportCompatibilityWithLatest retrieves the latest schema under the given subject and checks if the given schema is compatible with it.
+// Unlike CheckCompatibilityWithLatest, compatibility is calculated locally in the same way as a typebook server,
+// so that it can report every incompatibility in both directions.
+// This method returns model.CompatibilityReport on success otherwise non-nil model.Error is returned.
+func (sc *schemaClient) ReportCompatibilityWithLatest(subject, definition string) (*model.CompatibilityReport, *model.Error) {
+	return sc.ReportCompatibilityWithLatestContext(context.Background(), subject, definition)
+}
+
+// ReportCompatibilityWithLatestContext is the same as ReportCompatibilityWithLatest except that the request is bound to the given context.
+func (sc *schemaClient) ReportCompatibilityWithLatestContext(ctx context.Context, subject, definition string) (*model.CompatibilityReport, *model.Error) {
+	comparison, err := sc.GetLatestSchemaContext(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	return reportCompatibility(definition, comparison)
+}
+
+// ReportCompatibilityWithMajorVersion retrieves the latest schema that has the designated major version under the given subject
+// and checks if the given schema is compatible with it. Compatibility is calculated locally as ReportCompatibilityWithLatest.
+// This method returns model.CompatibilityReport on success otherwise non-nil model.Error is returned.
+func (sc *schemaClient) ReportCompatibilityWithMajorVersion(subject string, majorVersion int, definition string) (*model.CompatibilityReport, *model.Error) {
+	return sc.ReportCompatibilityWithMajorVersionContext(context.Background(), subject, majorVersion, definition)
+}
+
+// ReportCompatibilityWithMajorVersionContext is the same as ReportCompatibilityWithMajorVersion except that the request is bound to the given context.
+func (sc *schemaClient) ReportCompatibilityWithMajorVersionContext(ctx context.Context, subject string, majorVersion int, definition string) (*model.CompatibilityReport, *model.Error) {
+	comparison, err := sc.GetSchemaByMajorVersionContext(ctx, subject, majorVersion)
+	if err != nil {
+		return nil, err
+	}
+	return reportCompatibility(definition, comparison)
+}
+
+// ReportCompatibilityWithSemVer retrieves the schema that has the designated semver under the given subject
+// and checks if the given schema is compatible with it. Compatibility is calculated locally as ReportCompatibilityWithLatest.
+// This method returns model.CompatibilityReport on success otherwise non-nil model.Error is returned.
+func (sc *schemaClient) ReportCompatibilityWithSemVer(subject string, semver model.SemVer, definition string) (*model.CompatibilityReport, *model.Error) {
+	return sc.ReportCompatibilityWithSemVerContext(context.Background(), subject, semver, definition)
+}
+
+// ReportCompatibilityWithSemVerContext is the same as ReportCompatibilityWithSemVer except that the request is bound to the given context.
+func (sc *schemaClient) ReportCompatibilityWithSemVerContext(ctx context.Context, subject string, semver model.SemVer, definition string) (*model.CompatibilityReport, *model.Error) {
+	comparison, err := sc.GetSchemaBySemVerContext(ctx, subject, semver)
+	if err != nil {
+		return nil, err
+	}
+	return reportCompatibility(definition, comparison)
+}
+
+// PredictNextVersion calculates the version that the given schema will be given when it is registered under the given subject,
+// in the same way as a typebook server but without registering it.
+// It retrieves schemas with the latest major version under the subject and the compatibility configured to the subject,
+// which is left empty and not enforced in offline mode.
+// This method returns model.VersionPrediction on success otherwise non-nil model.Error is returned.
+func (sc *schemaClient) PredictNextVersion(subject, definition string) (*model.VersionPrediction, *model.Error) {
+	return sc.PredictNextVersionContext(context.Background(), subject, definition)
+}
+
+// PredictNextVersionContext is the same as PredictNextVersion except that the requests are bound to the given context.
+func (sc *schemaClient) PredictNextVersionContext(ctx context.Context, subject, definition string) (*model.VersionPrediction, *model.Error) {
+	target, err := avro.Parse(definition)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+
+	latestMajorSchemas, merr := sc.getLatestMajorSchemas(ctx, subject)
+	if merr != nil {
+		return nil, merr
+	}
+	restriction := ""
+	if !sc.baseClient.offline {
+		body, merr := sc.baseClient.Get(ctx, fmt.Sprintf("/config/%s/properties/%s", subject, model.CompatibilityProp))
+		if merr != nil {
+			return nil, merr
+		}
+		restriction = string(body)
+	}
+
+	next, comparisons, err := version.Next(target, latestMajorSchemas)
+	if err != nil {
+		return nil, model.NewError(nil, []error{err})
+	}
+	prediction := &model.VersionPrediction{
+		Subject:     subject,
+		Version:     next,
+		IsNew:       true,
+		Restriction: restriction,
+		IsAllowed:   true,
+		Comparisons: comparisons,
+	}
+	if restriction != "" {
+		level, err := compatibility.ParseLevel(restriction)
+		if err != nil {
+			return nil, model.NewError(nil, []error{err})
+		}
+		for _, c := range comparisons {
+			if !compatibility.Level(c.Compatibility).IsStrongerThanOrEqualTo(level) {
+				prediction.IsAllowed = false
+			}
+		}
+	}
+	// comparisons are sorted in descending order by version, so the first one is the latest schema
+	if len(comparisons) > 0 && prediction.IsAllowed {
+		for _, schema := range latestMajorSchemas {
+			if schema.Id != comparisons[0].Id {
+				continue
+			}
+			if latest, err := avro.Parse(schema.Definition); err == nil && version.IsSame(target, latest) {
+				prediction.Version = schema.Version
+				prediction.IsNew = false
+				prediction.ExistingId = schema.Id
+			}
+		}
+	}
+	return prediction, nil
+}
+
+// getLatestMajorSchemas retrieves schemas under the subject whose major version is the latest.
+func (sc *schemaClient) getLatestMajorSchemas(ctx context.Context, subject string) ([]model.Schema, *model.Error) {
+	versions, err := sc.ListVersionsContext(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	latestMajor := -1
+	for _, v := range versions {
+		if v.Major > latestMajor {
+			latestMajor = v.Major
+		}
+	}
+
+	schemas := make([]model.Schema, 0)
+	for _, v := range versions {
+		if v.Major != latestMajor {
+			continue
+		}
+		schema, err := sc.GetSchemaBySemVerContext(ctx, subject, v)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, *schema)
+	}
+	return schemas, nil
 }
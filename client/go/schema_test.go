@@ -23,13 +23,20 @@
 package _go
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/h2non/gock.v1"
 
 	"github.com/cyberagent/typebook/client/go/model"
+	"github.com/cyberagent/typebook/client/go/typebooktest"
 )
 
 const schemaDef = `
@@ -72,6 +79,46 @@ func TestRegisterSchema(t *testing.T) {
 	}
 }
 
+func TestRegisterInvalidSchema(t *testing.T) {
+	defer gock.Off()
+
+	// no request should be issued
+	invalid := `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "integer"}]}`
+	if _, err := client.RegisterSchema(subject, invalid); err == nil {
+		t.Errorf(`RegisterSchema("%s", "%s") should be an error. But no error was occurred`, subject, invalid)
+	} else if err.ServerError != nil {
+		t.Errorf(`RegisterSchema("%s", "%s") should fail before it is sent, but the server replied %v`, subject, invalid, err)
+	}
+}
+
+type registeredPerson struct {
+	Id        int64   `avro:"id"`
+	FirstName *string `avro:"first_name"`
+}
+
+func TestRegisterType(t *testing.T) {
+	defer gock.Off()
+
+	expect := model.SchemaId{Id: 1}
+	definition := `{"type":"record","name":"registeredPerson","fields":[{"name":"id","type":"long"},{"name":"first_name","type":["null","string"],"default":null}]}`
+	gock.New(host).
+		Post("/subjects/" + subject + "/versions").
+		BodyString(regexp.QuoteMeta(definition)).
+		Reply(201).
+		JSON(expect)
+
+	if actual, err := client.RegisterType(subject, &registeredPerson{}); err != nil {
+		t.Errorf(`RegisterType("%s", &registeredPerson{}) should not be an error. But an error was occurred: %v`, subject, err)
+	} else if *actual != expect {
+		t.Errorf(`RegisterType("%s", &registeredPerson{}) = %v, wants %v`, subject, actual, expect)
+	}
+
+	// no request should be issued
+	if _, err := client.RegisterType(subject, map[int]string{}); err == nil {
+		t.Errorf(`RegisterType("%s", map[int]string{}) should be an error. But no error was occurred`, subject)
+	}
+}
+
 func TestLookupSchema(t *testing.T) {
 	defer gock.Off()
 
@@ -156,6 +203,27 @@ func TestGetSchemaById(t *testing.T) {
 	}
 }
 
+func TestGetSchemaByIdContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // never reply until the client gives up
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetSchemaByIdContext(ctx, 1); err == nil {
+		t.Errorf(`GetSchemaByIdContext(ctx, 1) should be an error when the deadline is exceeded. But no error was occurred`)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetSchemaByIdContext(ctx, 1); err == nil {
+		t.Errorf(`GetSchemaByIdContext(ctx, 1) should be an error when the context is cancelled. But no error was occurred`)
+	}
+}
+
 func TestGetLatestSchema(t *testing.T) {
 	defer gock.Off()
 
@@ -255,6 +323,59 @@ func TestListVersions(t *testing.T) {
 	}
 }
 
+func TestResolveVersion(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(host).
+		Get("/subjects/" + subject + "/versions").
+		Times(2).
+		Reply(200).
+		JSON(`["v1.2.0", "v1.10.0", "v1.9.3", "v2.0.0"]`)
+
+	expect := model.SemVer{Major: 1, Minor: 10, Patch: 0}
+	if actual, err := client.ResolveVersion(subject, "^1.2"); err != nil {
+		t.Errorf(`ResolveVersion("%s", "^1.2") should not be an error. But an error was occurred: %v`, subject, err)
+	} else if *actual != expect {
+		t.Errorf(`ResolveVersion("%s", "^1.2") = %v, wants %v`, subject, *actual, expect)
+	}
+
+	if _, err := client.ResolveVersion(subject, "v3"); err == nil || err.ClientError[0] != ErrNoMatchingVersion {
+		t.Errorf(`ResolveVersion("%s", "v3") should return ErrNoMatchingVersion but got %v`, subject, err)
+	}
+	if _, err := client.ResolveVersion(subject, "latest"); err == nil {
+		t.Errorf(`ResolveVersion("%s", "latest") should be an error of an invalid constraint`, subject)
+	}
+	if !gock.IsDone() {
+		t.Errorf("versions are expected to be retrieved twice")
+	}
+}
+
+func TestResolveVersionWithServer(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	for i, v := range []string{"v1.2.0", "v1.10.0", "v1.9.3", "v2.0.0"} {
+		semver, _ := model.NewSemVer(v)
+		if err := server.AddSchema(model.Schema{Id: int64(i + 1), Subject: subject, Version: *semver, Definition: schemaDef}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewClient(server.URL)
+
+	expect := model.SemVer{Major: 1, Minor: 10, Patch: 0}
+	if actual, err := client.ResolveVersion(subject, "^1.2"); err != nil {
+		t.Errorf(`ResolveVersion("%s", "^1.2") should not be an error. But an error was occurred: %v`, subject, err)
+	} else if *actual != expect {
+		t.Errorf(`ResolveVersion("%s", "^1.2") = %v, wants %v`, subject, *actual, expect)
+	}
+
+	if _, err := client.ResolveVersion(subject, "v3"); err == nil || err.ClientError[0] != ErrNoMatchingVersion {
+		t.Errorf(`ResolveVersion("%s", "v3") should return ErrNoMatchingVersion but got %v`, subject, err)
+	}
+	if _, err := client.ResolveVersion(subject, "latest"); err == nil {
+		t.Errorf(`ResolveVersion("%s", "latest") should be an error of an invalid constraint`, subject)
+	}
+}
+
 func TestSchemaCheckCompatibilityWithLatest(t *testing.T) {
 	defer gock.Off()
 
@@ -318,3 +439,119 @@ func TestCheckCompatibilityWithSemVer(t *testing.T) {
 		t.Errorf(`CheckCompatibilityWithSemVer("%s", %v, "%s") = %v, wants %v`, subject, ver, schemaDef, *actual, expect)
 	}
 }
+
+func TestReportCompatibilityWithLatest(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(host).
+		Get("/subjects/" + subject + "/versions/latest").
+		Reply(200).
+		JSON(model.Schema{Id: 1, Subject: subject, Version: model.SemVer{Major: 1, Minor: 0, Patch: 0}, Definition: schemaDef})
+
+	// last_name without a default cannot be read from the latest schema
+	definition := `{"namespace": "com.example", "type": "record", "name": "Person", "fields": [
+		{"name": "id", "type": "int"}, {"name": "first_name", "type": "string"}, {"name": "last_name", "type": "string"}
+	]}`
+	actual, err := client.ReportCompatibilityWithLatest(subject, definition)
+	if err != nil {
+		t.Fatalf(`ReportCompatibilityWithLatest("%s", "%s") should not be an error, but an error was occurred: %v`, subject, definition, err)
+	}
+	expect := model.Incompatibility{
+		Direction: "BACKWARD", Path: "$.last_name", Kind: model.MissingDefault, Reader: "string",
+		Message: "field `last_name` is missing in the writer and has no default",
+	}
+	if actual.IsCompatible || actual.Compatibility != "FORWARD" || actual.ComparisonVersion != "v1.0.0" ||
+		len(actual.Incompatibilities) != 1 || actual.Incompatibilities[0] != expect {
+		t.Errorf(`ReportCompatibilityWithLatest("%s", "%s") = %v, wants an incompatible report with %v`, subject, definition, *actual, expect)
+	}
+}
+
+func TestPredictNextVersion(t *testing.T) {
+	defer gock.Off()
+
+	latest := model.Schema{Id: 3, Subject: subject, Version: model.SemVer{Major: 2, Minor: 0, Patch: 0}, Definition: schemaDef}
+	mockRegistry := func() {
+		gock.New(host).
+			Get("/subjects/" + subject + "/versions").
+			Reply(200).
+			JSON([]string{"v1.0.0", "v1.1.0", "v2.0.0"})
+		gock.New(host).
+			Get("/subjects/" + subject + "/versions/v2.0.0").
+			Reply(200).
+			JSON(latest)
+		gock.New(host).
+			Get("/config/" + subject + "/properties/compatibility").
+			Reply(200).
+			BodyString("BACKWARD")
+	}
+
+	// a new field with a default keeps full compatibility
+	definition := `{"namespace": "com.example", "type": "record", "name": "Person", "fields": [
+		{"name": "id", "type": "int"}, {"name": "first_name", "type": "string"}, {"name": "last_name", "type": "string", "default": ""}
+	]}`
+	mockRegistry()
+	actual, err := client.PredictNextVersion(subject, definition)
+	if err != nil {
+		t.Fatalf(`PredictNextVersion("%s", "%s") should not be an error, but an error was occurred: %v`, subject, definition, err)
+	}
+	expect := model.VersionComparison{Id: 3, Version: latest.Version, Compatibility: "FULL", IsDecisive: true}
+	if actual.Version != (model.SemVer{Major: 2, Minor: 0, Patch: 1}) || !actual.IsNew || !actual.IsAllowed ||
+		actual.Restriction != "BACKWARD" || len(actual.Comparisons) != 1 || actual.Comparisons[0] != expect {
+		t.Errorf(`PredictNextVersion("%s", "%s") = %v, wants v2.0.1 decided by %v`, subject, definition, *actual, expect)
+	}
+
+	// the same definition as the latest is not registered again
+	mockRegistry()
+	actual, err = client.PredictNextVersion(subject, schemaDef)
+	if err != nil {
+		t.Fatalf(`PredictNextVersion("%s", "%s") should not be an error, but an error was occurred: %v`, subject, schemaDef, err)
+	}
+	if actual.IsNew || actual.ExistingId != latest.Id || actual.Version != latest.Version {
+		t.Errorf(`PredictNextVersion("%s", "%s") = %v, wants the latest schema %v`, subject, schemaDef, *actual, latest)
+	}
+}
+
+func TestPredictNextVersionWithServer(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	latest := model.Schema{Id: 3, Subject: subject, Version: model.SemVer{Major: 2, Minor: 0, Patch: 0}, Definition: schemaDef}
+	if err := server.AddSchema(latest); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(server.URL)
+	if _, err := client.SetProperty(subject, model.CompatibilityProp, "BACKWARD"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new field with a default keeps full compatibility
+	definition := `{"namespace": "com.example", "type": "record", "name": "Person", "fields": [
+		{"name": "id", "type": "int"}, {"name": "first_name", "type": "string"}, {"name": "last_name", "type": "string", "default": ""}
+	]}`
+	actual, err := client.PredictNextVersion(subject, definition)
+	if err != nil {
+		t.Fatalf(`PredictNextVersion("%s", "%s") should not be an error, but an error was occurred: %v`, subject, definition, err)
+	}
+	expect := model.VersionComparison{Id: 3, Version: latest.Version, Compatibility: "FULL", IsDecisive: true}
+	if actual.Version != (model.SemVer{Major: 2, Minor: 0, Patch: 1}) || !actual.IsNew || !actual.IsAllowed ||
+		actual.Restriction != "BACKWARD" || len(actual.Comparisons) != 1 || actual.Comparisons[0] != expect {
+		t.Errorf(`PredictNextVersion("%s", "%s") = %v, wants v2.0.1 decided by %v`, subject, definition, *actual, expect)
+	}
+
+	// the prediction agrees with the version the server gives
+	id, err := client.RegisterSchema(subject, definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered, err := client.GetSchemaById(id.Id); err != nil || registered.Version != actual.Version {
+		t.Errorf("the schema is expected to be registered as %s but got %v, %v", actual.Version.String(), registered, err)
+	}
+
+	// the same definition as the latest is not registered again
+	actual, err = client.PredictNextVersion(subject, definition)
+	if err != nil {
+		t.Fatalf(`PredictNextVersion("%s", "%s") should not be an error, but an error was occurred: %v`, subject, definition, err)
+	}
+	if actual.IsNew || actual.ExistingId != id.Id {
+		t.Errorf(`PredictNextVersion("%s", "%s") = %v, wants the latest schema %d`, subject, definition, *actual, id.Id)
+	}
+}
@@ -0,0 +1,261 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serde
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+)
+
+// assign sets dst to src, a native Go value of s returned by a decoder.
+func assign(dst reflect.Value, s avro.Schema, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if u, ok := s.(*avro.UnionSchema); ok {
+		branch := branchOf(u, src)
+		if branch == nil {
+			return fmt.Errorf("%v does not match any type of union", src)
+		}
+		if dst.Type().Implements(unionType) && dst.Kind() == reflect.Struct {
+			return assignUnion(dst, branch, src)
+		}
+		return assign(dst, branch, src)
+	}
+	if ok, err := assignLogical(dst, s, src); ok {
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assign(elem.Elem(), s, src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if sv := reflect.ValueOf(src); sv.Type().AssignableTo(dst.Type()) {
+			dst.Set(sv)
+			return nil
+		}
+		return cannotAssign(dst, src)
+	}
+
+	switch s := s.(type) {
+	case *avro.RecordSchema:
+		return assignRecord(dst, s, src.(map[string]interface{}))
+	case *avro.ArraySchema:
+		items := src.([]interface{})
+		switch dst.Kind() {
+		case reflect.Slice:
+			dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		case reflect.Array:
+			if dst.Len() != len(items) {
+				return cannotAssign(dst, src)
+			}
+		default:
+			return cannotAssign(dst, src)
+		}
+		for i, item := range items {
+			if err := assign(dst.Index(i), s.Items, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *avro.MapSchema:
+		values := src.(map[string]interface{})
+		if dst.Kind() != reflect.Map || dst.Type().Key().Kind() != reflect.String {
+			return cannotAssign(dst, src)
+		}
+		dst.Set(reflect.MakeMap(dst.Type()))
+		for key, value := range values {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assign(elem, s.Values, value); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+		}
+		return nil
+	}
+	return assignScalar(dst, src)
+}
+
+func assignRecord(dst reflect.Value, s *avro.RecordSchema, fields map[string]interface{}) error {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for _, f := range s.Fields {
+			fv, ok := fieldOf(dst, f.Name)
+			if !ok {
+				continue
+			}
+			if err := assign(fv, f.Type, fields[f.Name]); err != nil {
+				return fmt.Errorf("field `%s` of record `%s`: %v", f.Name, s.Name, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if sv := reflect.ValueOf(fields); sv.Type().AssignableTo(dst.Type()) {
+			dst.Set(sv)
+			return nil
+		}
+	}
+	return cannotAssign(dst, fields)
+}
+
+// assignUnion sets the field of dst, which implements Union, tagged with the type name of branch.
+func assignUnion(dst reflect.Value, branch avro.Schema, src interface{}) error {
+	dst.Set(reflect.Zero(dst.Type()))
+	name := avro.TypeName(branch)
+	for i := 0; i < dst.NumField(); i++ {
+		sf := dst.Type().Field(i)
+		if sf.PkgPath == "" && sf.Type.Kind() == reflect.Ptr && tagOf(sf) == name {
+			return assign(dst.Field(i), branch, src)
+		}
+	}
+	return fmt.Errorf("%s has no field for type `%s` of union", dst.Type(), name)
+}
+
+// assignScalar sets dst to a value of a primitive type, an enum or fixed, converting it into the type of dst.
+func assignScalar(dst reflect.Value, src interface{}) error {
+	sv := reflect.ValueOf(src)
+	switch sv.Kind() {
+	case reflect.Bool:
+		if dst.Kind() != reflect.Bool {
+			return cannotAssign(dst, src)
+		}
+	case reflect.Int32, reflect.Int64:
+		if _, ok := integerOf(reflect.Zero(dst.Type())); !ok && dst.Kind() != reflect.Float32 && dst.Kind() != reflect.Float64 {
+			return cannotAssign(dst, src)
+		}
+		if overflows(dst, sv.Int()) {
+			return fmt.Errorf("%v overflows %s", src, dst.Type())
+		}
+	case reflect.Float32, reflect.Float64:
+		if dst.Kind() != reflect.Float32 && dst.Kind() != reflect.Float64 {
+			return cannotAssign(dst, src)
+		}
+	case reflect.String:
+		if dst.Kind() != reflect.String {
+			if _, ok := bytesOf(reflect.Zero(dst.Type())); !ok || dst.Kind() == reflect.Array {
+				return cannotAssign(dst, src)
+			}
+		}
+	case reflect.Slice: // bytes and fixed
+		switch {
+		case dst.Kind() == reflect.Array && dst.Type().Elem().Kind() == reflect.Uint8:
+			if dst.Len() != sv.Len() {
+				return cannotAssign(dst, src)
+			}
+			reflect.Copy(dst, sv)
+			return nil
+		case dst.Kind() != reflect.String && !(dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8):
+			return cannotAssign(dst, src)
+		}
+	}
+	dst.Set(sv.Convert(dst.Type()))
+	return nil
+}
+
+// branchOf returns the branch of s that a decoder returns src for.
+func branchOf(s *avro.UnionSchema, src interface{}) avro.Schema {
+	for _, branch := range s.Types {
+		if isNativeOf(branch, src) {
+			return branch
+		}
+	}
+	return nil
+}
+
+// isNativeOf reports whether src is a native Go value that a decoder returns for s.
+func isNativeOf(s avro.Schema, src interface{}) bool {
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		switch src.(type) {
+		case nil:
+			return s.Primitive == avro.Null
+		case bool:
+			return s.Primitive == avro.Boolean
+		case int32:
+			return s.Primitive == avro.Int
+		case int64:
+			return s.Primitive == avro.Long
+		case float32:
+			return s.Primitive == avro.Float
+		case float64:
+			return s.Primitive == avro.Double
+		case string:
+			return s.Primitive == avro.String
+		case []byte:
+			return s.Primitive == avro.Bytes
+		}
+	case *avro.EnumSchema:
+		symbol, ok := src.(string)
+		return ok && s.HasSymbol(symbol)
+	case *avro.FixedSchema:
+		b, ok := src.([]byte)
+		return ok && len(b) == s.Size
+	case *avro.ArraySchema:
+		_, ok := src.([]interface{})
+		return ok
+	case *avro.MapSchema:
+		_, ok := src.(map[string]interface{})
+		return ok
+	case *avro.RecordSchema:
+		// a decoder returns every field of the reader's record
+		fields, ok := src.(map[string]interface{})
+		if !ok || len(fields) != len(s.Fields) {
+			return false
+		}
+		for _, f := range s.Fields {
+			if _, ok := fields[f.Name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func overflows(dst reflect.Value, n int64) bool {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return dst.OverflowInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return n < 0 || dst.OverflowUint(uint64(n))
+	}
+	return false
+}
+
+func tagOf(sf reflect.StructField) string {
+	return strings.Split(sf.Tag.Get("avro"), ",")[0]
+}
+
+func cannotAssign(dst reflect.Value, src interface{}) error {
+	return fmt.Errorf("%v (%T) cannot be set to %s", src, src, dst.Type())
+}
@@ -0,0 +1,445 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serde
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+)
+
+// decoder reads a value written with a writer schema and returns it as a native Go value of a reader schema:
+// nil, bool, int32, int64, float32, float64, []byte, string, []interface{} or map[string]interface{}.
+// Enums are decoded as strings and fixed as []byte. A union is decoded as the value of its branch.
+type decoder func(r *reader) (interface{}, error)
+
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) readLong() (int64, error) {
+	n, size := binary.Varint(r.buf[r.pos:])
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid variable-length integer at %d", r.pos)
+	}
+	r.pos += size
+	return n, nil
+}
+
+func (r *reader) readN(n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf)-r.pos {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) readBytes() ([]byte, error) {
+	n, err := r.readLong()
+	if err != nil {
+		return nil, err
+	}
+	if n > int64(len(r.buf)-r.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b, err := r.readN(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// maxEmptyItems is the maximum number of items in a block which exceeds the remaining bytes.
+// Only items of zero size such as null can be written that way.
+const maxEmptyItems = 1 << 16
+
+// readBlocks reads blocks of an array or a map and calls f for each item.
+// It fails with a count of items which the remaining bytes cannot hold, so that malformed data does not make it loop endlessly.
+func (r *reader) readBlocks(f func() error) error {
+	for {
+		count, err := r.readLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 { // followed by the size of the block in bytes
+			if count == math.MinInt64 {
+				return fmt.Errorf("invalid block count %d", count)
+			}
+			count = -count
+			if _, err := r.readLong(); err != nil {
+				return err
+			}
+		}
+		if count > int64(len(r.buf)-r.pos) && count > maxEmptyItems {
+			return fmt.Errorf("block count %d exceeds %d remaining bytes", count, len(r.buf)-r.pos)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := f(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type schemaPair struct {
+	writer, reader avro.Schema
+}
+
+// resolver builds decoders according to Avro schema resolution.
+type resolver struct {
+	decoders map[schemaPair]*decoder
+}
+
+// newDecoder returns a decoder which reads data written with writer as reader.
+// It fails if writer can never be resolved to reader.
+func newDecoder(writer, reader avro.Schema) (decoder, error) {
+	return (&resolver{decoders: make(map[schemaPair]*decoder)}).resolve(writer, reader)
+}
+
+func (rs *resolver) resolve(w, r avro.Schema) (decoder, error) {
+	pair := schemaPair{w, r}
+	if d, ok := rs.decoders[pair]; ok {
+		// refer to it lazily since it may be under construction for recursive types
+		return func(rd *reader) (interface{}, error) { return (*d)(rd) }, nil
+	}
+	d := new(decoder)
+	rs.decoders[pair] = d
+
+	var err error
+	wu, wIsUnion := w.(*avro.UnionSchema)
+	ru, rIsUnion := r.(*avro.UnionSchema)
+	switch {
+	case wIsUnion:
+		*d, err = rs.resolveWriterUnion(wu, r)
+	case rIsUnion:
+		*d, err = rs.resolveReaderUnion(w, ru)
+	default:
+		*d, err = rs.resolveSame(w, r)
+	}
+	if err != nil {
+		delete(rs.decoders, pair)
+		return nil, err
+	}
+	return *d, nil
+}
+
+func (rs *resolver) resolveWriterUnion(w *avro.UnionSchema, r avro.Schema) (decoder, error) {
+	branches := make([]decoder, len(w.Types))
+	resolved := false
+	for i, branch := range w.Types {
+		d, err := rs.resolve(branch, r)
+		if err != nil {
+			// it is an error only when data of the branch actually appears
+			d = func(*reader) (interface{}, error) { return nil, err }
+		} else {
+			resolved = true
+		}
+		branches[i] = d
+	}
+	if !resolved {
+		return nil, incompatible(w, r)
+	}
+	return func(rd *reader) (interface{}, error) {
+		index, err := rd.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(branches)) {
+			return nil, fmt.Errorf("union index %d is out of range", index)
+		}
+		return branches[index](rd)
+	}, nil
+}
+
+// resolveReaderUnion resolves writer to the first branch of reader that matches it,
+// preferring branches which do not need any promotion.
+func (rs *resolver) resolveReaderUnion(w avro.Schema, r *avro.UnionSchema) (decoder, error) {
+	for _, promotable := range []bool{false, true} {
+		for _, branch := range r.Types {
+			if matches(w, branch, promotable) {
+				return rs.resolve(w, branch)
+			}
+		}
+	}
+	return nil, incompatible(w, r)
+}
+
+func (rs *resolver) resolveSame(w, r avro.Schema) (decoder, error) {
+	if !matches(w, r, true) {
+		return nil, incompatible(w, r)
+	}
+
+	switch w := w.(type) {
+	case *avro.PrimitiveSchema:
+		return primitiveDecoder(w.Primitive, r.Type()), nil
+	case *avro.FixedSchema:
+		return func(rd *reader) (interface{}, error) {
+			b, err := rd.readN(w.Size)
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte(nil), b...), nil
+		}, nil
+	case *avro.EnumSchema:
+		r := r.(*avro.EnumSchema)
+		return func(rd *reader) (interface{}, error) {
+			index, err := rd.readLong()
+			if err != nil {
+				return nil, err
+			}
+			if index < 0 || index >= int64(len(w.Symbols)) {
+				return nil, fmt.Errorf("enum index %d of `%s` is out of range", index, w.Name)
+			}
+			symbol := w.Symbols[index]
+			if r.HasSymbol(symbol) {
+				return symbol, nil
+			}
+			if r.Default != "" {
+				return r.Default, nil
+			}
+			return nil, fmt.Errorf("`%s` is not a symbol of enum `%s`", symbol, r.Name)
+		}, nil
+	case *avro.ArraySchema:
+		items, err := rs.resolve(w.Items, r.(*avro.ArraySchema).Items)
+		if err != nil {
+			return nil, err
+		}
+		return func(rd *reader) (interface{}, error) {
+			array := make([]interface{}, 0)
+			err := rd.readBlocks(func() error {
+				item, err := items(rd)
+				array = append(array, item)
+				return err
+			})
+			return array, err
+		}, nil
+	case *avro.MapSchema:
+		values, err := rs.resolve(w.Values, r.(*avro.MapSchema).Values)
+		if err != nil {
+			return nil, err
+		}
+		return func(rd *reader) (interface{}, error) {
+			m := make(map[string]interface{})
+			err := rd.readBlocks(func() error {
+				key, err := rd.readBytes()
+				if err != nil {
+					return err
+				}
+				m[string(key)], err = values(rd)
+				return err
+			})
+			return m, err
+		}, nil
+	case *avro.RecordSchema:
+		return rs.resolveRecord(w, r.(*avro.RecordSchema))
+	}
+	return nil, incompatible(w, r)
+}
+
+func primitiveDecoder(w, r avro.Type) decoder {
+	switch w {
+	case avro.Null:
+		return func(*reader) (interface{}, error) { return nil, nil }
+	case avro.Boolean:
+		return func(rd *reader) (interface{}, error) {
+			b, err := rd.readN(1)
+			if err != nil {
+				return nil, err
+			}
+			return b[0] != 0, nil
+		}
+	case avro.Int, avro.Long:
+		return func(rd *reader) (interface{}, error) {
+			n, err := rd.readLong()
+			if err != nil {
+				return nil, err
+			}
+			return promote(r, float64(n), n), nil
+		}
+	case avro.Float:
+		return func(rd *reader) (interface{}, error) {
+			b, err := rd.readN(4)
+			if err != nil {
+				return nil, err
+			}
+			f := math.Float32frombits(binary.LittleEndian.Uint32(b))
+			if r == avro.Double {
+				return float64(f), nil
+			}
+			return f, nil
+		}
+	case avro.Double:
+		return func(rd *reader) (interface{}, error) {
+			b, err := rd.readN(8)
+			if err != nil {
+				return nil, err
+			}
+			return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+		}
+	default: // bytes or string
+		return func(rd *reader) (interface{}, error) {
+			b, err := rd.readBytes()
+			if err != nil {
+				return nil, err
+			}
+			if r == avro.String {
+				return string(b), nil
+			}
+			return b, nil
+		}
+	}
+}
+
+func (rs *resolver) resolveRecord(w, r *avro.RecordSchema) (decoder, error) {
+	type fieldDecoder struct {
+		name   string // empty if the field is skipped
+		decode decoder
+	}
+	fields := make([]fieldDecoder, 0, len(w.Fields))
+	found := make(map[string]bool)
+	for _, wf := range w.Fields {
+		rf := readerField(r, wf)
+		if rf == nil {
+			skip, err := rs.resolve(wf.Type, wf.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, fieldDecoder{decode: skip})
+			continue
+		}
+		d, err := rs.resolve(wf.Type, rf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field `%s` of record `%s`: %v", rf.Name, r.Name, err)
+		}
+		found[rf.Name] = true
+		fields = append(fields, fieldDecoder{name: rf.Name, decode: d})
+	}
+
+	defaults := make(map[string]interface{})
+	for _, rf := range r.Fields {
+		if found[rf.Name] {
+			continue
+		}
+		if !rf.HasDefault {
+			return nil, fmt.Errorf("field `%s` of record `%s` is missing in the writer's schema and has no default", rf.Name, r.Name)
+		}
+		defaults[rf.Name] = rf.Default
+	}
+
+	return func(rd *reader) (interface{}, error) {
+		record := make(map[string]interface{}, len(r.Fields))
+		for _, f := range fields {
+			v, err := f.decode(rd)
+			if err != nil {
+				return nil, err
+			}
+			if f.name != "" {
+				record[f.name] = v
+			}
+		}
+		for name, def := range defaults {
+			record[name] = def
+		}
+		return record, nil
+	}, nil
+}
+
+// readerField returns the field of reader record r that corresponds to writer field wf by its name or aliases.
+func readerField(r *avro.RecordSchema, wf *avro.Field) *avro.Field {
+	if rf := r.Field(wf.Name); rf != nil {
+		return rf
+	}
+	for _, rf := range r.Fields {
+		if indexOf(rf.Aliases, wf.Name) >= 0 {
+			return rf
+		}
+	}
+	return nil
+}
+
+// matches reports whether a value of writer can be read as reader, not looking into their children.
+// If promotable is false, primitive types should be the same.
+func matches(w, r avro.Schema, promotable bool) bool {
+	if w.Type() == r.Type() {
+		switch w := w.(type) {
+		case *avro.RecordSchema, *avro.EnumSchema:
+			return namedAs(r.(avro.NamedSchema), w.(avro.NamedSchema).FullName())
+		case *avro.FixedSchema:
+			return namedAs(r.(avro.NamedSchema), w.Name) && w.Size == r.(*avro.FixedSchema).Size
+		}
+		return true
+	}
+	if !promotable {
+		return false
+	}
+	switch w.Type() {
+	case avro.Int:
+		return r.Type() == avro.Long || r.Type() == avro.Float || r.Type() == avro.Double
+	case avro.Long:
+		return r.Type() == avro.Float || r.Type() == avro.Double
+	case avro.Float:
+		return r.Type() == avro.Double
+	case avro.String:
+		return r.Type() == avro.Bytes
+	case avro.Bytes:
+		return r.Type() == avro.String
+	}
+	return false
+}
+
+// namedAs reports whether s can be referred to as name by its full name or one of its aliases.
+// Unqualified names are also compared as the specification says.
+func namedAs(s avro.NamedSchema, name string) bool {
+	for _, n := range append([]string{s.FullName()}, s.AliasNames()...) {
+		if n == name || avro.ShortName(n) == avro.ShortName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// promote converts a number into a native Go value of reader type.
+func promote(reader avro.Type, f float64, n int64) interface{} {
+	switch reader {
+	case avro.Int:
+		return int32(n)
+	case avro.Long:
+		return n
+	case avro.Float:
+		return float32(f)
+	default:
+		return f
+	}
+}
+
+func incompatible(w, r avro.Schema) error {
+	return fmt.Errorf("the writer's schema %s cannot be resolved to the reader's schema %s", avro.TypeName(w), avro.TypeName(r))
+}
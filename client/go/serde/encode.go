@@ -0,0 +1,363 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serde
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+)
+
+// encode appends the Avro binary encoding of v according to s.
+// v may be a native Go value such as map[string]interface{} or a struct whose fields are matched to record fields
+// by their `avro` tags or case-insensitively by their names.
+func encode(buf *bytes.Buffer, s avro.Schema, v interface{}) error {
+	return encodeValue(buf, s, reflect.ValueOf(v))
+}
+
+func encodeValue(buf *bytes.Buffer, s avro.Schema, v reflect.Value) error {
+	v, err := fromLogical(s, indirect(v))
+	if err != nil {
+		return err
+	}
+	switch s := s.(type) {
+	case *avro.UnionSchema:
+		return encodeUnion(buf, s, v)
+	case *avro.RecordSchema:
+		if !v.IsValid() {
+			return fmt.Errorf("nil cannot be encoded as record `%s`", s.Name)
+		}
+		return encodeRecord(buf, s, v)
+	case *avro.EnumSchema:
+		if v.Kind() != reflect.String {
+			return mismatch(s, v)
+		}
+		index := indexOf(s.Symbols, v.String())
+		if index < 0 {
+			return fmt.Errorf("`%s` is not a symbol of enum `%s`", v.String(), s.Name)
+		}
+		writeLong(buf, int64(index))
+	case *avro.FixedSchema:
+		b, ok := bytesOf(v)
+		if !ok || len(b) != s.Size {
+			return fmt.Errorf("%v cannot be encoded as fixed `%s` of size %d", v, s.Name, s.Size)
+		}
+		buf.Write(b)
+	case *avro.ArraySchema:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch(s, v)
+		}
+		if v.Len() > 0 {
+			writeLong(buf, int64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				if err := encodeValue(buf, s.Items, v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+		writeLong(buf, 0)
+	case *avro.MapSchema:
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return mismatch(s, v)
+		}
+		if v.Len() > 0 {
+			writeLong(buf, int64(v.Len()))
+			for _, key := range v.MapKeys() {
+				writeLong(buf, int64(len(key.String())))
+				buf.WriteString(key.String())
+				if err := encodeValue(buf, s.Values, v.MapIndex(key)); err != nil {
+					return err
+				}
+			}
+		}
+		writeLong(buf, 0)
+	case *avro.PrimitiveSchema:
+		return encodePrimitive(buf, s, v)
+	}
+	return nil
+}
+
+func encodePrimitive(buf *bytes.Buffer, s *avro.PrimitiveSchema, v reflect.Value) error {
+	if !v.IsValid() {
+		if s.Primitive == avro.Null {
+			return nil
+		}
+		return fmt.Errorf("nil cannot be encoded as %s", s.Primitive)
+	}
+
+	switch s.Primitive {
+	case avro.Null:
+		return mismatch(s, v)
+	case avro.Boolean:
+		if v.Kind() != reflect.Bool {
+			return mismatch(s, v)
+		}
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case avro.Int:
+		n, ok := integerOf(v)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return mismatch(s, v)
+		}
+		writeLong(buf, n)
+	case avro.Long:
+		n, ok := integerOf(v)
+		if !ok {
+			return mismatch(s, v)
+		}
+		writeLong(buf, n)
+	case avro.Float:
+		f, ok := floatOf(v)
+		if !ok {
+			return mismatch(s, v)
+		}
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(f)))
+		buf.Write(b[:])
+	case avro.Double:
+		f, ok := floatOf(v)
+		if !ok {
+			return mismatch(s, v)
+		}
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		buf.Write(b[:])
+	case avro.Bytes, avro.String:
+		b, ok := bytesOf(v)
+		if !ok {
+			return mismatch(s, v)
+		}
+		writeLong(buf, int64(len(b)))
+		buf.Write(b)
+	}
+	return nil
+}
+
+func encodeRecord(buf *bytes.Buffer, s *avro.RecordSchema, v reflect.Value) error {
+	for _, f := range s.Fields {
+		fv, ok := fieldOf(v, f.Name)
+		if !ok {
+			if !f.HasDefault {
+				return fmt.Errorf("field `%s` of record `%s` is missing in %v and has no default", f.Name, s.Name, v)
+			}
+			fv = reflect.ValueOf(f.Default)
+		}
+		if err := encodeValue(buf, f.Type, fv); err != nil {
+			return fmt.Errorf("field `%s` of record `%s`: %v", f.Name, s.Name, err)
+		}
+	}
+	return nil
+}
+
+// encodeUnion writes the value with the first branch that accepts it.
+// If the value implements Union, it is written with the branch that its non-nil field is tagged with.
+func encodeUnion(buf *bytes.Buffer, s *avro.UnionSchema, v reflect.Value) error {
+	if v.IsValid() && v.Type().Implements(unionType) {
+		name, fv := unionBranchOf(v)
+		for i, branch := range s.Types {
+			if avro.TypeName(branch) == name {
+				writeLong(buf, int64(i))
+				return encodeValue(buf, branch, fv)
+			}
+		}
+		return fmt.Errorf("union has no type `%s` set in %s", name, v.Type())
+	}
+	for i, branch := range s.Types {
+		if accepts(branch, v) {
+			writeLong(buf, int64(i))
+			return encodeValue(buf, branch, v)
+		}
+	}
+	return fmt.Errorf("%v does not match any type of union", v)
+}
+
+// accepts reports whether v can be encoded as s without any loss.
+func accepts(s avro.Schema, v reflect.Value) bool {
+	v, err := fromLogical(s, indirect(v))
+	if err != nil {
+		return false
+	}
+	if !v.IsValid() {
+		return s.Type() == avro.Null
+	}
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		switch s.Primitive {
+		case avro.Boolean:
+			return v.Kind() == reflect.Bool
+		case avro.Int:
+			n, ok := integerOf(v)
+			return ok && n >= math.MinInt32 && n <= math.MaxInt32
+		case avro.Long:
+			_, ok := integerOf(v)
+			return ok
+		case avro.Float:
+			return v.Kind() == reflect.Float32
+		case avro.Double:
+			return v.Kind() == reflect.Float64
+		case avro.String:
+			return v.Kind() == reflect.String
+		case avro.Bytes:
+			return v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8
+		}
+	case *avro.FixedSchema:
+		b, ok := bytesOf(v)
+		return ok && v.Kind() != reflect.String && len(b) == s.Size
+	case *avro.EnumSchema:
+		return v.Kind() == reflect.String && s.HasSymbol(v.String())
+	case *avro.ArraySchema:
+		return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+	case *avro.MapSchema:
+		return v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String
+	case *avro.RecordSchema:
+		if v.Kind() == reflect.Struct {
+			return true
+		}
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		for _, f := range s.Fields {
+			if _, ok := fieldOf(v, f.Name); !ok && !f.HasDefault {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// fieldOf returns a value for the named field from a map or a struct.
+func fieldOf(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		fv := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return fv, fv.IsValid()
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" { // unexported
+				continue
+			}
+			tag := tagOf(sf)
+			if tag == name || (tag == "" && strings.EqualFold(sf.Name, name)) {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// unionBranchOf returns the type name of the branch set in v, which implements Union, and its value.
+// It returns `null` if no branch is set.
+func unionBranchOf(v reflect.Value) (string, reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" || v.Field(i).Kind() != reflect.Ptr || v.Field(i).IsNil() {
+			continue
+		}
+		return tagOf(t.Field(i)), v.Field(i)
+	}
+	return string(avro.Null), reflect.Value{}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func integerOf(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(v.Uint()), true
+	case reflect.Uint, reflect.Uint64:
+		if v.Uint() <= math.MaxInt64 {
+			return int64(v.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+func floatOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	if n, ok := integerOf(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func bytesOf(v reflect.Value) ([]byte, bool) {
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), true
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b, true
+	}
+	return nil, false
+}
+
+func indexOf(ss []string, s string) int {
+	for i, e := range ss {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func mismatch(s avro.Schema, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("nil cannot be encoded as %s", avro.TypeName(s))
+	}
+	return fmt.Errorf("%v (%s) cannot be encoded as %s", v, v.Type(), avro.TypeName(s))
+}
+
+func writeLong(buf *bytes.Buffer, n int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], n)])
+}
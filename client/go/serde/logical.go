@@ -0,0 +1,232 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serde
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	ratType      = reflect.TypeOf(big.Rat{})
+)
+
+func logicalTypeOf(s avro.Schema) *avro.LogicalType {
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		return s.LogicalType
+	case *avro.FixedSchema:
+		return s.LogicalType
+	}
+	return nil
+}
+
+// fromLogical converts time.Time, time.Duration and big.Rat into the value of the type underlying the logical type of s.
+// Other values are returned as they are.
+func fromLogical(s avro.Schema, v reflect.Value) (reflect.Value, error) {
+	lt := logicalTypeOf(s)
+	if lt == nil || !v.IsValid() {
+		return v, nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		switch lt.Name {
+		case "date":
+			days := t.Unix() / 86400
+			if t.Unix() < 0 && t.Unix()%86400 != 0 {
+				days--
+			}
+			return reflect.ValueOf(days), nil
+		case "timestamp-millis", "local-timestamp-millis":
+			return reflect.ValueOf(t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)), nil
+		case "timestamp-micros", "local-timestamp-micros":
+			return reflect.ValueOf(t.Unix()*1000000 + int64(t.Nanosecond())/int64(time.Microsecond)), nil
+		}
+	case durationType:
+		d := v.Interface().(time.Duration)
+		switch lt.Name {
+		case "time-millis":
+			return reflect.ValueOf(int64(d / time.Millisecond)), nil
+		case "time-micros":
+			return reflect.ValueOf(int64(d / time.Microsecond)), nil
+		}
+	case ratType:
+		if lt.Name != "decimal" {
+			break
+		}
+		var r *big.Rat
+		if v.CanAddr() {
+			r = v.Addr().Interface().(*big.Rat)
+		} else {
+			copied := v.Interface().(big.Rat)
+			r = &copied
+		}
+		size := 0
+		if fixed, ok := s.(*avro.FixedSchema); ok {
+			size = fixed.Size
+		}
+		b, err := encodeDecimal(r, lt.Scale, size)
+		if err != nil {
+			return v, err
+		}
+		return reflect.ValueOf(b), nil
+	}
+	return v, nil
+}
+
+// encodeDecimal returns the two's-complement big-endian representation of the unscaled value of r.
+// If size is positive, the representation is sign-extended to size bytes.
+func encodeDecimal(r *big.Rat, scale, size int) ([]byte, error) {
+	unscaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	if !unscaled.IsInt() {
+		return nil, fmt.Errorf("%s cannot be represented as a decimal with scale %d", r.RatString(), scale)
+	}
+	n := unscaled.Num()
+
+	var b []byte
+	if n.Sign() >= 0 {
+		b = n.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+	} else {
+		// two's complement of a negative number -n is 2^(8k) - n for the smallest k that keeps the sign bit
+		k := len(n.Bytes())
+		if k == 0 {
+			k = 1
+		}
+		for {
+			complement := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), uint(8*k)), n)
+			b = complement.Bytes()
+			for len(b) < k {
+				b = append([]byte{0}, b...)
+			}
+			if b[0]&0x80 != 0 {
+				break
+			}
+			k++
+		}
+	}
+
+	if size <= 0 {
+		return b, nil
+	}
+	if len(b) > size {
+		return nil, fmt.Errorf("%s does not fit in a fixed of size %d", r.RatString(), size)
+	}
+	padding := byte(0)
+	if n.Sign() < 0 {
+		padding = 0xff
+	}
+	fixed := make([]byte, size)
+	for i := 0; i < size-len(b); i++ {
+		fixed[i] = padding
+	}
+	copy(fixed[size-len(b):], b)
+	return fixed, nil
+}
+
+// decodeDecimal is the reverse of encodeDecimal.
+func decodeDecimal(b []byte, scale int) *big.Rat {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return new(big.Rat).SetFrac(n, pow10(scale))
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// assignLogical sets dst to src, a native value of the type underlying the logical type of s,
+// if dst is time.Time, time.Duration, big.Rat or *big.Rat. It reports whether dst is one of them.
+func assignLogical(dst reflect.Value, s avro.Schema, src interface{}) (bool, error) {
+	lt := logicalTypeOf(s)
+	if lt == nil {
+		return false, nil
+	}
+
+	switch dst.Type() {
+	case timeType:
+		n, ok := src.(int64)
+		if i, isInt := src.(int32); isInt {
+			n, ok = int64(i), true
+		}
+		if !ok {
+			return true, fmt.Errorf("%v cannot be read as %s", src, lt.Name)
+		}
+		var t time.Time
+		switch lt.Name {
+		case "date":
+			t = time.Unix(n*86400, 0)
+		case "timestamp-millis", "local-timestamp-millis":
+			t = time.Unix(n/1000, (n%1000)*int64(time.Millisecond))
+		case "timestamp-micros", "local-timestamp-micros":
+			t = time.Unix(n/1000000, (n%1000000)*int64(time.Microsecond))
+		default:
+			return true, fmt.Errorf("%s cannot be read as time.Time", lt.Name)
+		}
+		dst.Set(reflect.ValueOf(t.UTC()))
+		return true, nil
+	case durationType:
+		var d time.Duration
+		switch n := src.(type) {
+		case int32:
+			if lt.Name != "time-millis" {
+				return true, fmt.Errorf("%s cannot be read as time.Duration", lt.Name)
+			}
+			d = time.Duration(n) * time.Millisecond
+		case int64:
+			if lt.Name != "time-micros" {
+				return true, fmt.Errorf("%s cannot be read as time.Duration", lt.Name)
+			}
+			d = time.Duration(n) * time.Microsecond
+		default:
+			return true, fmt.Errorf("%v cannot be read as %s", src, lt.Name)
+		}
+		dst.Set(reflect.ValueOf(d))
+		return true, nil
+	case ratType, reflect.PtrTo(ratType):
+		b, ok := src.([]byte)
+		if !ok || lt.Name != "decimal" {
+			return true, fmt.Errorf("%v cannot be read as decimal", src)
+		}
+		r := decodeDecimal(b, lt.Scale)
+		if dst.Kind() == reflect.Ptr {
+			dst.Set(reflect.ValueOf(r))
+		} else {
+			dst.Set(reflect.ValueOf(r).Elem())
+		}
+		return true, nil
+	}
+	return false, nil
+}
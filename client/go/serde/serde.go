@@ -0,0 +1,250 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package serde encodes and decodes data in Avro binary format with schemas managed by a typebook server.
+//
+// Each encoded message is framed as follows so that a consumer can find the schema it was written with.
+//
+//	| 0x00 (magic byte) | schema ID (8 bytes, big endian) | Avro binary encoded data |
+package serde
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+const (
+	magicByte  byte = 0
+	headerSize      = 9
+)
+
+// ErrInvalidFrame is returned when data to deserialize does not start with the magic byte and a schema ID.
+var ErrInvalidFrame = errors.New("data is not framed with the magic byte and a schema ID")
+
+// SchemaRegisterer registers a schema. *typebook.Client implements it.
+type SchemaRegisterer interface {
+	RegisterSchema(subject, definition string) (*model.SchemaId, *model.Error)
+}
+
+// SchemaLookuper looks up a registered schema. *typebook.Client implements it.
+type SchemaLookuper interface {
+	LookupSchema(subject, definition string) (*model.Schema, *model.Error)
+}
+
+// SchemaGetter retrieves a schema by its ID. Both *typebook.Client and *typebook.CachedClient implement it.
+type SchemaGetter interface {
+	GetSchemaById(id int64) (*model.Schema, *model.Error)
+}
+
+// Serializer encodes Go values in Avro binary format framed with the ID of its schema.
+// It is safe for concurrent use by multiple goroutines.
+type Serializer struct {
+	id     int64
+	schema avro.Schema
+}
+
+// NewSerializer creates a Serializer with the schema already registered with the given ID.
+func NewSerializer(id int64, definition string) (*Serializer, error) {
+	s, err := avro.Parse(definition)
+	if err != nil {
+		return nil, err
+	}
+	return &Serializer{id: id, schema: s}, nil
+}
+
+// RegisterSerializer registers the given schema under the subject and creates a Serializer with the registered ID.
+// If the same schema has already been registered, its ID is used.
+func RegisterSerializer(client SchemaRegisterer, subject, definition string) (*Serializer, error) {
+	id, err := client.RegisterSchema(subject, definition)
+	if err != nil {
+		return nil, err
+	}
+	return NewSerializer(id.Id, definition)
+}
+
+// LookupSerializer looks up the given schema under the subject and creates a Serializer with the ID of the found schema.
+// It fails if the schema has not been registered.
+func LookupSerializer(client SchemaLookuper, subject, definition string) (*Serializer, error) {
+	schema, err := client.LookupSchema(subject, definition)
+	if err != nil {
+		return nil, err
+	}
+	return NewSerializer(schema.Id, definition)
+}
+
+// SchemaId returns the ID of the schema which data is serialized with.
+func (s *Serializer) SchemaId() int64 {
+	return s.id
+}
+
+// Serialize encodes v with the schema of the Serializer.
+// v is a native Go value: nil, bool, integers, floats, string, []byte, slices, maps with string keys and structs.
+// A record accepts either a map keyed by field names or a struct whose fields are matched to record fields
+// by their `avro` tags or case-insensitively by their names. A missing field is filled with its default.
+// An enum accepts a string of its symbol, and a union is encoded as the first branch that accepts v.
+func (s *Serializer) Serialize(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(magicByte)
+	var id [headerSize - 1]byte
+	binary.BigEndian.PutUint64(id[:], uint64(s.id))
+	buf.Write(id[:])
+	if err := encode(buf, s.schema, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Union is implemented by structs that represent Avro unions, such as ones generated by package codegen.
+// Each exported pointer field of the struct holds a branch of the union and is tagged with
+// the type name of the branch in the `avro` tag, e.g. `avro:"string"` or `avro:"com.example.Address"`.
+// At most one field is set and the union is null if none is set.
+type Union interface {
+	AvroUnion()
+}
+
+var unionType = reflect.TypeOf((*Union)(nil)).Elem()
+
+// Deserializer decodes data serialized by Serializer.
+// It retrieves the writer's schema by the ID in the data and resolves it to the reader's schema
+// according to Avro schema resolution, e.g. fields absent in the writer's schema are filled with their defaults.
+// Resolutions are cached by schema IDs. It is safe for concurrent use by multiple goroutines.
+type Deserializer struct {
+	client SchemaGetter
+	reader avro.Schema
+
+	mu          sync.Mutex
+	resolutions map[int64]resolution
+}
+
+// resolution is a decoder of data written with a schema and the reader's schema it decodes data as.
+type resolution struct {
+	decode decoder
+	reader avro.Schema
+}
+
+// NewDeserializer creates a Deserializer that reads data as readerDefinition.
+// If readerDefinition is empty, data is read as the writer's schema.
+// Wrap *typebook.Client with typebook.NewCachedClient not to retrieve the same schema repeatedly across Deserializers.
+func NewDeserializer(client SchemaGetter, readerDefinition string) (*Deserializer, error) {
+	d := &Deserializer{client: client, resolutions: make(map[int64]resolution)}
+	if readerDefinition != "" {
+		reader, err := avro.Parse(readerDefinition)
+		if err != nil {
+			return nil, err
+		}
+		d.reader = reader
+	}
+	return d, nil
+}
+
+// Deserialize decodes data into a native Go value of the reader's schema.
+// Records and maps are decoded as map[string]interface{}, arrays as []interface{}, enums as string,
+// bytes and fixed as []byte, and int, long, float and double as int32, int64, float32 and float64 respectively.
+func (d *Deserializer) Deserialize(data []byte) (interface{}, error) {
+	v, _, err := d.deserialize(data)
+	return v, err
+}
+
+// DeserializeInto decodes data into v, which must be a non-nil pointer.
+// Records are set to structs whose fields are matched to record fields in the same way as Serialize,
+// and unions to structs that implement Union as well as pointers and interfaces.
+// Logical types date, timestamp-millis and timestamp-micros can be set to time.Time,
+// time-millis and time-micros to time.Duration and decimal to big.Rat.
+func (d *Deserializer) DeserializeInto(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("data cannot be decoded into %T, which is not a non-nil pointer", v)
+	}
+	native, reader, err := d.deserialize(data)
+	if err != nil {
+		return err
+	}
+	return assign(rv.Elem(), reader, native)
+}
+
+func (d *Deserializer) deserialize(data []byte) (interface{}, avro.Schema, error) {
+	id, err := SchemaIdOf(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := d.resolution(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &reader{buf: data, pos: headerSize}
+	v, err := res.decode(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.pos != len(data) {
+		return nil, nil, fmt.Errorf("%d bytes are left after decoding", len(data)-r.pos)
+	}
+	return v, res.reader, nil
+}
+
+func (d *Deserializer) resolution(id int64) (resolution, error) {
+	d.mu.Lock()
+	res, ok := d.resolutions[id]
+	d.mu.Unlock()
+	if ok {
+		return res, nil
+	}
+
+	schema, modelErr := d.client.GetSchemaById(id)
+	if modelErr != nil {
+		return resolution{}, modelErr
+	}
+	writer, err := avro.Parse(schema.Definition)
+	if err != nil {
+		return resolution{}, err
+	}
+	reader := d.reader
+	if reader == nil {
+		reader = writer
+	}
+	decode, err := newDecoder(writer, reader)
+	if err != nil {
+		return resolution{}, err
+	}
+	res = resolution{decode: decode, reader: reader}
+
+	d.mu.Lock()
+	d.resolutions[id] = res
+	d.mu.Unlock()
+	return res, nil
+}
+
+// SchemaIdOf returns the ID of the schema which the given data is serialized with.
+func SchemaIdOf(data []byte) (int64, error) {
+	if len(data) < headerSize || data[0] != magicByte {
+		return 0, ErrInvalidFrame
+	}
+	return int64(binary.BigEndian.Uint64(data[1:headerSize])), nil
+}
@@ -0,0 +1,357 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serde
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// registry is an in-memory stub of a typebook server.
+type registry struct {
+	schemas map[int64]string
+}
+
+func (r *registry) RegisterSchema(subject, definition string) (*model.SchemaId, *model.Error) {
+	id := int64(len(r.schemas) + 1)
+	r.schemas[id] = definition
+	return &model.SchemaId{Id: id}, nil
+}
+
+func (r *registry) GetSchemaById(id int64) (*model.Schema, *model.Error) {
+	definition, ok := r.schemas[id]
+	if !ok {
+		return nil, model.NewError(&model.ServerError{ErrorCode: 40403, Message: "schema not found"}, nil)
+	}
+	return &model.Schema{Id: id, Subject: "test", Version: model.SemVer{Major: 1}, Definition: definition}, nil
+}
+
+const userV1 = `{
+  "type": "record",
+  "name": "User",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "int"},
+    {"name": "name", "type": "string"},
+    {"name": "email", "type": ["null", "string"], "default": null},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "MEMBER", "GUEST"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "scores", "type": {"type": "map", "values": "double"}},
+    {"name": "hash", "type": {"type": "fixed", "name": "MD5", "size": 4}},
+    {"name": "friends", "type": {"type": "array", "items": "User"}, "default": []}
+  ]
+}`
+
+const userV2 = `{
+  "type": "record",
+  "name": "User",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "fullName", "type": "string", "aliases": ["name"]},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "MEMBER", "UNKNOWN"], "default": "UNKNOWN"}},
+    {"name": "scores", "type": {"type": "map", "values": "double"}},
+    {"name": "active", "type": "boolean", "default": true},
+    {"name": "nickname", "type": ["string", "null"], "default": "anonymous"}
+  ]
+}`
+
+type user struct {
+	Id      int32
+	Name    string
+	Email   *string
+	Kind    string
+	Tags    []string
+	Scores  map[string]float64
+	Hash    [4]byte
+	Friends []user
+	ignored int
+}
+
+func TestRoundTrip(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	serializer, err := RegisterSerializer(reg, "test", userV1)
+	if err != nil {
+		t.Fatalf("RegisterSerializer should not be an error. But an error was occurred: %v", err)
+	}
+	deserializer, err := NewDeserializer(reg, "")
+	if err != nil {
+		t.Fatalf("NewDeserializer should not be an error. But an error was occurred: %v", err)
+	}
+
+	email := "alice@example.com"
+	data, err := serializer.Serialize(user{
+		Id: 1, Name: "alice", Email: &email, Kind: "ADMIN", Tags: []string{"a", "b"}, Scores: map[string]float64{"x": 1.5},
+		Hash: [4]byte{1, 2, 3, 4}, Friends: []user{{Id: 2, Name: "bob", Kind: "GUEST", Hash: [4]byte{5, 6, 7, 8}}},
+	})
+	if err != nil {
+		t.Fatalf("Serialize should not be an error. But an error was occurred: %v", err)
+	}
+	if id, err := SchemaIdOf(data); err != nil || id != serializer.SchemaId() {
+		t.Errorf("SchemaIdOf = %d, %v, wants %d", id, err, serializer.SchemaId())
+	}
+
+	actual, err := deserializer.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize should not be an error. But an error was occurred: %v", err)
+	}
+	expect := map[string]interface{}{
+		"id": int32(1), "name": "alice", "email": "alice@example.com", "kind": "ADMIN",
+		"tags": []interface{}{"a", "b"}, "scores": map[string]interface{}{"x": 1.5}, "hash": []byte{1, 2, 3, 4},
+		"friends": []interface{}{map[string]interface{}{
+			"id": int32(2), "name": "bob", "email": nil, "kind": "GUEST",
+			"tags": []interface{}{}, "scores": map[string]interface{}{}, "hash": []byte{5, 6, 7, 8}, "friends": []interface{}{},
+		}},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Deserialize = %v, wants %v", actual, expect)
+	}
+}
+
+func TestSchemaResolution(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	serializer, _ := RegisterSerializer(reg, "test", userV1)
+	deserializer, err := NewDeserializer(reg, userV2)
+	if err != nil {
+		t.Fatalf("NewDeserializer should not be an error. But an error was occurred: %v", err)
+	}
+
+	data, err := serializer.Serialize(map[string]interface{}{
+		"id": 1, "name": "alice", "kind": "GUEST", "tags": []string{}, "scores": map[string]float64{}, "hash": []byte{1, 2, 3, 4},
+	})
+	if err != nil {
+		t.Fatalf("Serialize should not be an error. But an error was occurred: %v", err)
+	}
+	actual, err := deserializer.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize should not be an error. But an error was occurred: %v", err)
+	}
+	expect := map[string]interface{}{
+		"id": int64(1), "fullName": "alice", "kind": "UNKNOWN", "scores": map[string]interface{}{},
+		"active": true, "nickname": "anonymous",
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Deserialize = %v, wants %v", actual, expect)
+	}
+}
+
+func TestUnresolvableSchema(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	serializer, _ := RegisterSerializer(reg, "test", `"string"`)
+	data, _ := serializer.Serialize("foo")
+
+	cases := []string{
+		`"int"`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "int"}]}`,
+	}
+	for _, reader := range cases {
+		deserializer, _ := NewDeserializer(reg, reader)
+		if _, err := deserializer.Deserialize(data); err == nil {
+			t.Errorf("a string should not be read as %s", reader)
+		}
+	}
+
+	deserializer, _ := NewDeserializer(reg, `["null", "int", "bytes"]`)
+	if actual, err := deserializer.Deserialize(data); err != nil || !reflect.DeepEqual(actual, []byte("foo")) {
+		t.Errorf("a string should be read as bytes in a union, but got %v, %v", actual, err)
+	}
+}
+
+func TestInvalidData(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	deserializer, _ := NewDeserializer(reg, "")
+	if _, err := deserializer.Deserialize([]byte{1, 2, 3}); err != ErrInvalidFrame {
+		t.Errorf("Deserialize should be ErrInvalidFrame, but got %v", err)
+	}
+	if _, err := deserializer.Deserialize([]byte{0, 0, 0, 0, 0, 0, 0, 0, 9}); err == nil {
+		t.Errorf("Deserialize with an unknown schema ID should be an error")
+	}
+
+	serializer, _ := NewSerializer(1, `{"type": "enum", "name": "E", "symbols": ["A"]}`)
+	if _, err := serializer.Serialize("B"); err == nil {
+		t.Errorf("Serialize with an unknown symbol should be an error")
+	}
+	if _, err := NewSerializer(1, `{"type": "record", "name": "R", "fields": [{"name": "f", "type": "Unknown"}]}`); err == nil {
+		t.Errorf("NewSerializer with an unknown type should be an error")
+	}
+
+	for _, definition := range []string{`{"type": "enum", "name": "E", "symbols": ["A"]}`, `{"type": "array", "items": "int"}`, `{"type": "map", "values": "int"}`} {
+		serializer, _ := NewSerializer(1, definition)
+		if _, err := serializer.Serialize(nil); err == nil {
+			t.Errorf("Serialize(nil) as %s should be an error", definition)
+		}
+	}
+}
+
+func TestMalformedData(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	stringSerializer, _ := RegisterSerializer(reg, "test", `"string"`)
+	nullsSerializer, _ := RegisterSerializer(reg, "test", `{"type": "array", "items": "null"}`)
+	framed := func(serializer *Serializer, longs ...int64) []byte {
+		data := make([]byte, headerSize)
+		binary.BigEndian.PutUint64(data[1:], uint64(serializer.SchemaId()))
+		for _, n := range longs {
+			var b [binary.MaxVarintLen64]byte
+			data = append(data, b[:binary.PutVarint(b[:], n)]...)
+		}
+		return data
+	}
+
+	cases := map[string][]byte{
+		"a string with a length which overflows": append(framed(stringSerializer, math.MaxInt64), 'a'),
+		"an array with too many nulls":           framed(nullsSerializer, math.MaxInt64),
+		"an array with the minimum block count":  framed(nullsSerializer, math.MinInt64, 0),
+	}
+	deserializer, _ := NewDeserializer(reg, "")
+	for name, data := range cases {
+		if _, err := deserializer.Deserialize(data); err == nil {
+			t.Errorf("Deserialize of %s should be an error", name)
+		}
+	}
+
+	nulls := []interface{}{nil, nil, nil, nil, nil}
+	data, err := nullsSerializer.Serialize(nulls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := deserializer.Deserialize(data); err != nil || !reflect.DeepEqual(actual, nulls) {
+		t.Errorf("an array of nulls should be deserialized, but got %v, %v", actual, err)
+	}
+}
+
+const eventSchema = `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "day", "type": {"type": "int", "logicalType": "date"}},
+    {"name": "elapsed", "type": {"type": "int", "logicalType": "time-millis"}},
+    {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "GUEST"]}},
+    {"name": "value", "type": ["null", "int", "long", "com.example.Kind"]},
+    {"name": "note", "type": ["null", "string"]}
+  ]
+}`
+
+type kind string
+
+type eventValue struct {
+	Int  *int32 `avro:"int"`
+	Long *int64 `avro:"long"`
+	Kind *kind  `avro:"com.example.Kind"`
+}
+
+func (eventValue) AvroUnion() {}
+
+type event struct {
+	At      time.Time     `avro:"at"`
+	Day     time.Time     `avro:"day"`
+	Elapsed time.Duration `avro:"elapsed"`
+	Amount  *big.Rat      `avro:"amount"`
+	Kind    kind          `avro:"kind"`
+	Value   eventValue    `avro:"value"`
+	Note    *string       `avro:"note"`
+}
+
+func TestDeserializeInto(t *testing.T) {
+	reg := &registry{schemas: make(map[int64]string)}
+	serializer, err := RegisterSerializer(reg, "test", eventSchema)
+	if err != nil {
+		t.Fatalf("RegisterSerializer should not be an error. But an error was occurred: %v", err)
+	}
+	deserializer, err := NewDeserializer(reg, "")
+	if err != nil {
+		t.Fatalf("NewDeserializer should not be an error. But an error was occurred: %v", err)
+	}
+
+	// 3 fits in int but the union struct chooses long
+	long := int64(3)
+	expect := event{
+		At:      time.Date(2017, 10, 1, 12, 30, 15, 250000000, time.UTC),
+		Day:     time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC),
+		Elapsed: 90 * time.Second,
+		Amount:  big.NewRat(-12345, 100),
+		Value:   eventValue{Long: &long},
+		Kind:    "GUEST",
+	}
+	data, err := serializer.Serialize(expect)
+	if err != nil {
+		t.Fatalf("Serialize should not be an error. But an error was occurred: %v", err)
+	}
+
+	native, err := deserializer.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize should not be an error. But an error was occurred: %v", err)
+	}
+	fields := native.(map[string]interface{})
+	if fields["at"] != int64(1506861015250) || fields["day"] != int32(-1) || fields["elapsed"] != int32(90000) || fields["value"] != int64(3) {
+		t.Errorf("Deserialize = %v, wants logical types encoded as their underlying types", native)
+	}
+
+	var actual event
+	if err := deserializer.DeserializeInto(data, &actual); err != nil {
+		t.Fatalf("DeserializeInto should not be an error. But an error was occurred: %v", err)
+	}
+	if !actual.At.Equal(expect.At) || !actual.Day.Equal(expect.Day) || actual.Elapsed != expect.Elapsed ||
+		actual.Amount.Cmp(expect.Amount) != 0 || !reflect.DeepEqual(actual.Value, expect.Value) ||
+		actual.Kind != expect.Kind || actual.Note != nil {
+		t.Errorf("DeserializeInto = %+v, wants %+v", actual, expect)
+	}
+
+	if err := deserializer.DeserializeInto(data, actual); err == nil {
+		t.Errorf("DeserializeInto should fail for a non-pointer")
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	cases := []struct {
+		value *big.Rat
+		size  int
+		bytes []byte
+	}{
+		{big.NewRat(0, 1), 0, []byte{0x00}},
+		{big.NewRat(128, 1), 0, []byte{0x00, 0x80}},
+		{big.NewRat(-128, 1), 0, []byte{0x80}},
+		{big.NewRat(-129, 1), 0, []byte{0xff, 0x7f}},
+		{big.NewRat(-1, 1), 4, []byte{0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, c := range cases {
+		actual, err := encodeDecimal(c.value, 0, c.size)
+		if err != nil || !reflect.DeepEqual(actual, c.bytes) {
+			t.Errorf("encodeDecimal(%s, 0, %d) = %v, %v, wants %v", c.value.RatString(), c.size, actual, err, c.bytes)
+		}
+		if decoded := decodeDecimal(c.bytes, 0); decoded.Cmp(c.value) != 0 {
+			t.Errorf("decodeDecimal(%v, 0) = %s, wants %s", c.bytes, decoded.RatString(), c.value.RatString())
+		}
+	}
+	if _, err := encodeDecimal(big.NewRat(1, 3), 2, 0); err == nil {
+		t.Errorf("encodeDecimal should fail for a value that cannot be represented with the scale")
+	}
+}
@@ -23,9 +23,9 @@
 package _go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
 
 	"github.com/cyberagent/typebook/client/go/model"
 )
@@ -38,28 +38,33 @@ type subjectClient struct {
 // It will create a new subject with given name and description.
 // This method returns 0 for success otherwise returns non-nil model.Error.
 func (sc *subjectClient) CreateSubject(name, description string) (int64, *model.Error) {
-	request := sc.baseClient.Post(fmt.Sprintf("/subjects/%s", name)).Type("text")
+	return sc.CreateSubjectContext(context.Background(), name, description)
+}
+
+// CreateSubjectContext is the same as CreateSubject except that the request is bound to the given context.
+func (sc *subjectClient) CreateSubjectContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	var content []byte
 	if description != "" {
-		request.Send(description)
-	}
-	response, body, errs := request.EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
+		content = []byte(description)
 	}
-
-	id, err := strconv.ParseInt(string(body), 10, 64)
+	body, err := sc.baseClient.Post(ctx, fmt.Sprintf("/subjects/%s", name), contentTypeText, content, false)
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return id, nil
+	return parseInt(body)
 }
 
 // GetSubject issues a GET /subjects/(subject string) request to a typebook server.
 // It will retrieve a subject which has the given name.
 // If it fails, non-nil model.Error is returned.
 func (sc *subjectClient) GetSubject(name string) (*model.Subject, *model.Error) {
-	response, body, errs := sc.baseClient.Get(fmt.Sprintf("/subjects/%s", name)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.GetSubjectContext(context.Background(), name)
+}
+
+// GetSubjectContext is the same as GetSubject except that the request is bound to the given context.
+func (sc *subjectClient) GetSubjectContext(ctx context.Context, name string) (*model.Subject, *model.Error) {
+	body, err := sc.baseClient.Get(ctx, fmt.Sprintf("/subjects/%s", name))
+	if err != nil {
 		return nil, err
 	}
 
@@ -74,8 +79,13 @@ func (sc *subjectClient) GetSubject(name string) (*model.Subject, *model.Error)
 // It will retrieve a list of names of existing subjects.
 // If it fails, non-nil model.Error is returned.
 func (sc *subjectClient) ListSubjects() ([]string, *model.Error) {
-	response, body, errs := sc.baseClient.Get("/subjects").EndBytes()
-	if err := checkError(response, body, errs); err != nil {
+	return sc.ListSubjectsContext(context.Background())
+}
+
+// ListSubjectsContext is the same as ListSubjects except that the request is bound to the given context.
+func (sc *subjectClient) ListSubjectsContext(ctx context.Context) ([]string, *model.Error) {
+	body, err := sc.baseClient.Get(ctx, "/subjects")
+	if err != nil {
 		return nil, err
 	}
 
@@ -91,20 +101,20 @@ func (sc *subjectClient) ListSubjects() ([]string, *model.Error) {
 // This method returns the number of updated rows in the backend database so normally it is 1 for success.
 // Otherwise non-nil model.Error is returned.
 func (sc *subjectClient) UpdateDescription(name, description string) (int64, *model.Error) {
-	request := sc.baseClient.Put(fmt.Sprintf("/subjects/%s", name)).Type("text")
+	return sc.UpdateDescriptionContext(context.Background(), name, description)
+}
+
+// UpdateDescriptionContext is the same as UpdateDescription except that the request is bound to the given context.
+func (sc *subjectClient) UpdateDescriptionContext(ctx context.Context, name, description string) (int64, *model.Error) {
+	var content []byte
 	if description != "" {
-		request.Send(description)
+		content = []byte(description)
 	}
-	response, body, errs := request.EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
-
-	updatedRows, err := strconv.ParseInt(string(body), 10, 64)
+	body, err := sc.baseClient.Put(ctx, fmt.Sprintf("/subjects/%s", name), contentTypeText, content)
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return updatedRows, nil
+	return parseInt(body)
 }
 
 // DeleteSubject issues a DELETE /subjects/(subject string) to a typebook server.
@@ -112,14 +122,14 @@ func (sc *subjectClient) UpdateDescription(name, description string) (int64, *mo
 // This method returns the number of deleted rows in the backend database so normally it is 1 for success.
 // Otherwise non-nil model.Error is returned
 func (sc *subjectClient) DeleteSubject(name string) (int64, *model.Error) {
-	response, body, errs := sc.baseClient.Delete(fmt.Sprintf("/subjects/%s", name)).EndBytes()
-	if err := checkError(response, body, errs); err != nil {
-		return -1, err
-	}
+	return sc.DeleteSubjectContext(context.Background(), name)
+}
 
-	deletedRows, err := strconv.ParseInt(string(body), 10, 64)
+// DeleteSubjectContext is the same as DeleteSubject except that the request is bound to the given context.
+func (sc *subjectClient) DeleteSubjectContext(ctx context.Context, name string) (int64, *model.Error) {
+	body, err := sc.baseClient.Delete(ctx, fmt.Sprintf("/subjects/%s", name))
 	if err != nil {
-		return -1, model.NewError(nil, []error{err})
+		return -1, err
 	}
-	return deletedRows, nil
+	return parseInt(body)
 }
@@ -27,7 +27,6 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/parnurzeal/gorequest"
 	"gopkg.in/h2non/gock.v1"
 
 	"github.com/cyberagent/typebook/client/go/model"
@@ -45,7 +44,6 @@ var (
 
 func init() {
 	gock.DisableNetworking()
-	gorequest.DisableTransportSwap = true // to avoid overwriting gock's intercept transport with gorequest's superagent transport
 }
 
 // POST /subjects/(subject name) BODY description
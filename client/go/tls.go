@@ -0,0 +1,98 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TLSOptions is a set of files and parameters to build `tls.Config` by LoadTLSConfig.
+// All fields are optional.
+type TLSOptions struct {
+	// CACertFile is a path to PEM encoded CA certificates to verify the server certificate.
+	// If empty, the system certificate pool is used.
+	CACertFile string
+	// ClientCertFile and ClientKeyFile are paths to a PEM encoded certificate and its private key
+	// presented to the server for mutual TLS. Both of them must be specified together.
+	ClientCertFile string
+	ClientKeyFile  string
+	// ServerName overrides the host name used to verify the server certificate.
+	ServerName string
+	// InsecureSkipVerify disables verification of the server certificate. It should be used only for testing.
+	InsecureSkipVerify bool
+}
+
+// LoadTLSConfig reads certificate files designated by the given options and builds `tls.Config`
+// which can be passed to WithTLSConfig.
+func LoadTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACertFile != "" {
+		pem, err := ioutil.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate is found in %s", opts.CACertFile)
+		}
+		config.RootCAs = pool
+	}
+
+	switch {
+	case opts.ClientCertFile != "" && opts.ClientKeyFile != "":
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	case opts.ClientCertFile != "" || opts.ClientKeyFile != "":
+		return nil, fmt.Errorf("both client certificate and key should be specified for mutual TLS")
+	}
+	return config, nil
+}
+
+// newTLSTransport creates a transport configured as same as `http.DefaultTransport` except for TLS.
+func newTLSTransport(config *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       config,
+	}
+}
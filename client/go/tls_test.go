@@ -0,0 +1,225 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package _go
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSTestServer(t *testing.T, config *tls.Config) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/typebook/subjects" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`["` + subject + `"]`))
+	}))
+	server.TLS = config
+	server.StartTLS()
+	return server
+}
+
+// writePEM writes PEM encoded blocks to a file under dir and returns its path.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, block := range blocks {
+		if err := pem.Encode(file, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// issueClientCert creates a self-signed CA and a client certificate signed by it.
+// It returns the CA certificate and paths to the client certificate and its key.
+func issueClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "typebook test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "typebook test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := writePEM(t, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: clientDER})
+	keyFile := writePEM(t, dir, "client-key.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return caCert, certFile, keyFile
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "typebook-tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestHTTPS(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	server := newTLSTestServer(t, nil)
+	defer server.Close()
+	caFile := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	// without the CA certificate of the server
+	client := NewClient(server.URL + "/typebook/")
+	if _, err := client.ListSubjects(); err == nil {
+		t.Errorf("ListSubjects() should be an error for a server signed by an unknown authority. But no error was occurred")
+	}
+
+	config, err := LoadTLSConfig(TLSOptions{CACertFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = NewClient(server.URL+"/typebook/", WithTLSConfig(config))
+	if actual, err := client.ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	} else if len(actual) != 1 || actual[0] != subject {
+		t.Errorf("ListSubjects() = %v, wants [%s]", actual, subject)
+	}
+}
+
+func TestHTTPSWithServerName(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	server := newTLSTestServer(t, nil)
+	defer server.Close()
+	caFile := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	endpoint := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/typebook"
+
+	// the certificate of the test server is not valid for localhost
+	config, err := LoadTLSConfig(TLSOptions{CACertFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(endpoint, WithTLSConfig(config)).ListSubjects(); err == nil {
+		t.Errorf("ListSubjects() should be an error for a mismatched server name. But no error was occurred")
+	}
+
+	config, err = LoadTLSConfig(TLSOptions{CACertFile: caFile, ServerName: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(endpoint, WithTLSConfig(config)).ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	caCert, certFile, keyFile := issueClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(caCert)
+
+	server := newTLSTestServer(t, &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs})
+	defer server.Close()
+	caFile := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	config, err := LoadTLSConfig(TLSOptions{CACertFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(server.URL+"/typebook", WithTLSConfig(config)).ListSubjects(); err == nil {
+		t.Errorf("ListSubjects() should be an error without a client certificate. But no error was occurred")
+	}
+
+	config, err = LoadTLSConfig(TLSOptions{CACertFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(server.URL+"/typebook", WithTLSConfig(config)).ListSubjects(); err != nil {
+		t.Errorf("ListSubjects() should not be an error. But an error was occurred: %v", err)
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	abnormalCases := []TLSOptions{
+		{CACertFile: "/path/to/nonexistent.pem"},
+		{ClientCertFile: "client.pem"},
+		{ClientKeyFile: "client-key.pem"},
+	}
+	for _, testCase := range abnormalCases {
+		if _, err := LoadTLSConfig(testCase); err == nil {
+			t.Errorf("LoadTLSConfig(%+v) should have caused an error. But no error occurred.", testCase)
+		}
+	}
+}
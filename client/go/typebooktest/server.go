@@ -0,0 +1,572 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package typebooktest provides an in-memory typebook server for tests.
+// It implements the REST API of a typebook server with the same status codes and messages,
+// assigning semvers to schemas and enforcing the compatibility configured to subjects in the same way,
+// so that code using a client can be tested without mocking each request.
+package typebooktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+	"github.com/cyberagent/typebook/client/go/model"
+	"github.com/cyberagent/typebook/client/go/version"
+)
+
+// Server is an in-memory typebook server listening on a local address, which a client connects to by URL.
+// It keeps subjects, schemas and configs in memory, which are lost when it is closed.
+// As a typebook server backed by MySQL, a subject cannot be created twice and cannot be deleted while it has schemas or configs,
+// and schemas and configs cannot be created under a non-existent subject. Such requests fail with 500 Internal Server Error.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	subjects map[string]*subject
+	schemas  map[int64]model.Schema
+	lastId   int64
+}
+
+type subject struct {
+	description string
+	configs     map[string]string
+	schemas     []model.Schema // in ascending order by version
+}
+
+// NewServer starts and returns a new Server, which should be closed by Close when finished.
+func NewServer() *Server {
+	s := &Server{subjects: make(map[string]*subject), schemas: make(map[int64]model.Schema)}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// AddSchema stores a schema with its ID and version as it is, creating its subject if it does not exist,
+// so that a test can start with a registry in a particular state.
+// It returns an error if the definition is invalid or the ID or the version is already used.
+func (s *Server) AddSchema(schema model.Schema) error {
+	parsed, err := avro.Parse(schema.Definition)
+	if err != nil {
+		return err
+	}
+	definition, err := normalize(parsed)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schemas[schema.Id]; ok {
+		return fmt.Errorf("schema with ID %d already exists", schema.Id)
+	}
+	sub := s.subjects[schema.Subject]
+	if sub == nil {
+		sub = &subject{configs: make(map[string]string)}
+		s.subjects[schema.Subject] = sub
+	}
+	for _, existing := range sub.schemas {
+		if existing.Version == schema.Version {
+			return fmt.Errorf("schema with version %s already exists under `%s`", schema.Version.String(), schema.Subject)
+		}
+	}
+	schema.Definition = definition
+	s.insert(sub, schema)
+	if schema.Id > s.lastId {
+		s.lastId = schema.Id
+	}
+	return nil
+}
+
+// insert adds the schema to the subject keeping schemas sorted by version.
+func (s *Server) insert(sub *subject, schema model.Schema) {
+	s.schemas[schema.Id] = schema
+	sub.schemas = append(sub.schemas, schema)
+	sort.Slice(sub.schemas, func(i, j int) bool { return sub.schemas[i].Version.Less(sub.schemas[j].Version) })
+}
+
+// normalize returns the definition stored for a schema, which is compared to look schemas up
+// as a typebook server stores and compares the string representations of parsed schemas.
+func normalize(schema avro.Schema) (string, error) {
+	definition, err := avro.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return string(definition), nil
+}
+
+// ServeHTTP serves the REST API of a typebook server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, route := range routes {
+		if route.method != r.Method || len(route.segments) != len(segments) {
+			continue
+		}
+		params := make([]string, 0)
+		matched := true
+		for i, segment := range route.segments {
+			if segment == "*" {
+				params = append(params, segments[i])
+			} else if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			route.handle(s, w, params, body)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+type route struct {
+	method   string
+	segments []string // `*` is a path parameter
+	handle   func(s *Server, w http.ResponseWriter, params []string, body []byte)
+}
+
+func newRoute(method, path string, handle func(s *Server, w http.ResponseWriter, params []string, body []byte)) route {
+	return route{method: method, segments: strings.Split(strings.Trim(path, "/"), "/"), handle: handle}
+}
+
+var routes = []route{
+	newRoute(http.MethodGet, "/health", (*Server).health),
+	newRoute(http.MethodGet, "/subjects", (*Server).listSubjects),
+	newRoute(http.MethodPost, "/subjects/*", (*Server).createSubject),
+	newRoute(http.MethodGet, "/subjects/*", (*Server).getSubject),
+	newRoute(http.MethodPut, "/subjects/*", (*Server).updateSubject),
+	newRoute(http.MethodDelete, "/subjects/*", (*Server).deleteSubject),
+	newRoute(http.MethodPost, "/subjects/*/versions", (*Server).registerSchema),
+	newRoute(http.MethodGet, "/subjects/*/versions", (*Server).listVersions),
+	newRoute(http.MethodGet, "/subjects/*/versions/*", (*Server).getSchemaByVersion),
+	newRoute(http.MethodPost, "/subjects/*/schema/lookup", (*Server).lookupSchema),
+	newRoute(http.MethodPost, "/subjects/*/schema/lookupAll", (*Server).lookupAllSchemas),
+	newRoute(http.MethodGet, "/schemas/ids/*", (*Server).getSchemaById),
+	newRoute(http.MethodPost, "/compatibility/subjects/*/versions/*", (*Server).checkCompatibility),
+	newRoute(http.MethodPut, "/config/*", (*Server).setConfig),
+	newRoute(http.MethodGet, "/config/*", (*Server).getConfig),
+	newRoute(http.MethodDelete, "/config/*", (*Server).deleteConfig),
+	newRoute(http.MethodPut, "/config/*/properties/*", (*Server).setProperty),
+	newRoute(http.MethodGet, "/config/*/properties/*", (*Server).getProperty),
+	newRoute(http.MethodDelete, "/config/*/properties/*", (*Server).deleteProperty),
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	w.Write([]byte(text))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, model.ServerError{ErrorCode: status, Message: message})
+}
+
+// writeBackendError responds as a typebook server does when MySQL fails with the error code.
+func writeBackendError(w http.ResponseWriter, code int, message string) {
+	writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error Code (%d) - %s", code, message))
+}
+
+const (
+	mysqlDuplicateEntry   = 1062
+	mysqlRowIsReferenced  = 1451
+	mysqlNoReferencedRow  = 1452
+	foreignKeyFailMessage = "Cannot add or update a child row: a foreign key constraint fails"
+)
+
+func (s *Server) health(w http.ResponseWriter, params []string, body []byte) {
+	writeText(w, http.StatusOK, "OK")
+}
+
+// GET /subjects
+func (s *Server) listSubjects(w http.ResponseWriter, params []string, body []byte) {
+	names := make([]string, 0, len(s.subjects))
+	for name := range s.subjects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+// POST /subjects/(subject string)
+func (s *Server) createSubject(w http.ResponseWriter, params []string, body []byte) {
+	name := params[0]
+	if _, ok := s.subjects[name]; ok {
+		writeBackendError(w, mysqlDuplicateEntry, fmt.Sprintf("Duplicate entry '%s' for key 'PRIMARY'", name))
+		return
+	}
+	s.subjects[name] = &subject{description: string(body), configs: make(map[string]string)}
+	writeText(w, http.StatusCreated, "0") // subjects have no auto increment ID
+}
+
+// GET /subjects/(subject string)
+func (s *Server) getSubject(w http.ResponseWriter, params []string, body []byte) {
+	sub, ok := s.subjects[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Subject Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, model.Subject{Name: params[0], Description: sub.description})
+}
+
+// PUT /subjects/(subject string)
+func (s *Server) updateSubject(w http.ResponseWriter, params []string, body []byte) {
+	sub, ok := s.subjects[params[0]]
+	if !ok {
+		writeText(w, http.StatusOK, "0")
+		return
+	}
+	sub.description = string(body)
+	writeText(w, http.StatusOK, "1")
+}
+
+// DELETE /subjects/(subject string)
+func (s *Server) deleteSubject(w http.ResponseWriter, params []string, body []byte) {
+	sub, ok := s.subjects[params[0]]
+	if !ok {
+		writeText(w, http.StatusOK, "0")
+		return
+	}
+	if len(sub.schemas) > 0 || len(sub.configs) > 0 {
+		writeBackendError(w, mysqlRowIsReferenced, "Cannot delete or update a parent row: a foreign key constraint fails")
+		return
+	}
+	delete(s.subjects, params[0])
+	writeText(w, http.StatusOK, "1")
+}
+
+// parseSchema parses the body as a schema. It responds with 422 Unprocessable Entity if the body is invalid.
+func parseSchema(w http.ResponseWriter, body []byte) (avro.Schema, string, bool) {
+	schema, err := avro.Parse(string(body))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return nil, "", false
+	}
+	definition, err := normalize(schema)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return nil, "", false
+	}
+	return schema, definition, true
+}
+
+// latestMajorSchemas returns the schemas with the latest major version under the subject in descending order by version.
+func (s *Server) latestMajorSchemas(name string) []model.Schema {
+	latest := make([]model.Schema, 0)
+	sub, ok := s.subjects[name]
+	if !ok || len(sub.schemas) == 0 {
+		return latest
+	}
+	major := sub.schemas[len(sub.schemas)-1].Version.Major
+	for i := len(sub.schemas) - 1; i >= 0 && sub.schemas[i].Version.Major == major; i-- {
+		latest = append(latest, sub.schemas[i])
+	}
+	return latest
+}
+
+// restriction returns the compatibility configured to the subject, or NONE by default.
+func (s *Server) restriction(name string) compatibility.Level {
+	if sub, ok := s.subjects[name]; ok {
+		if value, ok := sub.configs[model.CompatibilityProp]; ok {
+			return compatibility.Level(value)
+		}
+	}
+	return compatibility.None
+}
+
+// POST /subjects/(subject string)/versions
+func (s *Server) registerSchema(w http.ResponseWriter, params []string, body []byte) {
+	target, definition, ok := parseSchema(w, body)
+	if !ok {
+		return
+	}
+	name := params[0]
+	latestMajorSchemas := s.latestMajorSchemas(name)
+	restriction := s.restriction(name)
+
+	existing := make([]avro.Schema, 0, len(latestMajorSchemas))
+	for _, schema := range latestMajorSchemas {
+		parsed, err := avro.Parse(schema.Definition)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		existing = append(existing, parsed)
+	}
+	if !compatibility.Check(target, existing, restriction) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("Illegal schema that violates compatibility restriction (%s) of this subject", restriction))
+		return
+	}
+	if len(existing) > 0 && version.IsSame(target, existing[0]) {
+		writeJSON(w, http.StatusOK, model.SchemaId{Id: latestMajorSchemas[0].Id})
+		return
+	}
+
+	sub, ok := s.subjects[name]
+	if !ok {
+		writeBackendError(w, mysqlNoReferencedRow, foreignKeyFailMessage)
+		return
+	}
+	next, _, err := version.Next(target, latestMajorSchemas)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.lastId++
+	schema := model.Schema{Id: s.lastId, Subject: name, Version: next, Definition: definition}
+	s.insert(sub, schema)
+	writeJSON(w, http.StatusCreated, model.SchemaId{Id: schema.Id})
+}
+
+// GET /subjects/(subject string)/versions
+func (s *Server) listVersions(w http.ResponseWriter, params []string, body []byte) {
+	versions := make([]string, 0)
+	if sub, ok := s.subjects[params[0]]; ok {
+		for _, schema := range sub.schemas {
+			versions = append(versions, schema.Version.String())
+		}
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+var majorVersionPattern = regexp.MustCompile(`^v([1-9][0-9]*)$`)
+
+// findByVersion finds a schema by `latest`, a major version such as `v1` or a semver such as `v1.0.0`.
+// It responds with 422 Unprocessable Entity if the version is invalid or 404 Not Found if the schema does not exist.
+func (s *Server) findByVersion(w http.ResponseWriter, name, v string) (*model.Schema, bool) {
+	var found *model.Schema
+	v = strings.TrimSpace(v)
+	if v == "latest" {
+		if latest := s.latestMajorSchemas(name); len(latest) > 0 {
+			found = &latest[0]
+		}
+	} else if m := majorVersionPattern.FindStringSubmatch(v); m != nil {
+		major, _ := strconv.Atoi(m[1])
+		if sub, ok := s.subjects[name]; ok {
+			for i := len(sub.schemas) - 1; i >= 0; i-- {
+				if sub.schemas[i].Version.Major == major {
+					found = &sub.schemas[i]
+					break
+				}
+			}
+		}
+	} else {
+		semver, err := model.NewSemVer(v)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return nil, false
+		}
+		if sub, ok := s.subjects[name]; ok {
+			for i := range sub.schemas {
+				if sub.schemas[i].Version == *semver {
+					found = &sub.schemas[i]
+				}
+			}
+		}
+	}
+	if found == nil {
+		writeError(w, http.StatusNotFound, "Schema Not Found")
+		return nil, false
+	}
+	return found, true
+}
+
+// GET /subjects/(subject string)/versions/(version string)
+func (s *Server) getSchemaByVersion(w http.ResponseWriter, params []string, body []byte) {
+	if schema, ok := s.findByVersion(w, params[0], params[1]); ok {
+		writeJSON(w, http.StatusOK, schema)
+	}
+}
+
+// lookup returns schemas under the subject with the same definition in descending order by version.
+func (s *Server) lookup(name, definition string) []model.Schema {
+	found := make([]model.Schema, 0)
+	if sub, ok := s.subjects[name]; ok {
+		for i := len(sub.schemas) - 1; i >= 0; i-- {
+			if sub.schemas[i].Definition == definition {
+				found = append(found, sub.schemas[i])
+			}
+		}
+	}
+	return found
+}
+
+// POST /subjects/(subject string)/schema/lookup
+func (s *Server) lookupSchema(w http.ResponseWriter, params []string, body []byte) {
+	_, definition, ok := parseSchema(w, body)
+	if !ok {
+		return
+	}
+	found := s.lookup(params[0], definition)
+	if len(found) == 0 {
+		writeError(w, http.StatusNotFound, "Schema Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, found[0])
+}
+
+// POST /subjects/(subject string)/schema/lookupAll
+func (s *Server) lookupAllSchemas(w http.ResponseWriter, params []string, body []byte) {
+	_, definition, ok := parseSchema(w, body)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.lookup(params[0], definition))
+}
+
+// GET /schemas/ids/(id int)
+func (s *Server) getSchemaById(w http.ResponseWriter, params []string, body []byte) {
+	id, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil || id < 0 {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation failed: id should be a natural number: %s", params[0]))
+		return
+	}
+	schema, ok := s.schemas[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Schema Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, schema)
+}
+
+// POST /compatibility/subjects/(subject string)/versions/(version string)
+func (s *Server) checkCompatibility(w http.ResponseWriter, params []string, body []byte) {
+	target, _, ok := parseSchema(w, body)
+	if !ok {
+		return
+	}
+	existing, ok := s.findByVersion(w, params[0], params[1])
+	if !ok {
+		return
+	}
+	comparison, err := avro.Parse(existing.Definition)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, model.Compatibility{IsCompatible: compatibility.IsCompatible(target, comparison)})
+}
+
+// set sets a property of the subject. It responds with 422 Unprocessable Entity if the property or the value is invalid.
+func (s *Server) set(w http.ResponseWriter, name, property, value string) bool {
+	if property != model.CompatibilityProp {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid value %s is provided to %s", value, property))
+		return false
+	}
+	if _, err := compatibility.ParseLevel(value); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid value %s is provided to %s", value, property))
+		return false
+	}
+	sub, ok := s.subjects[name]
+	if !ok {
+		writeBackendError(w, mysqlNoReferencedRow, foreignKeyFailMessage)
+		return false
+	}
+	sub.configs[property] = value
+	return true
+}
+
+// PUT /config/(subject string)
+func (s *Server) setConfig(w http.ResponseWriter, params []string, body []byte) {
+	var config model.Config
+	if err := json.Unmarshal(body, &config); err != nil || config.Compatibility == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid config")
+		return
+	}
+	if _, err := compatibility.ParseLevel(config.Compatibility); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid compatibility value")
+		return
+	}
+	if s.set(w, params[0], model.CompatibilityProp, config.Compatibility) {
+		writeText(w, http.StatusOK, "1")
+	}
+}
+
+// GET /config/(subject string)
+func (s *Server) getConfig(w http.ResponseWriter, params []string, body []byte) {
+	if _, ok := s.subjects[params[0]]; !ok {
+		writeError(w, http.StatusNotFound, "Non existent subject")
+		return
+	}
+	writeJSON(w, http.StatusOK, model.Config{Compatibility: string(s.restriction(params[0]))})
+}
+
+// DELETE /config/(subject string)
+func (s *Server) deleteConfig(w http.ResponseWriter, params []string, body []byte) {
+	deleted := 0
+	if sub, ok := s.subjects[params[0]]; ok {
+		deleted = len(sub.configs)
+		sub.configs = make(map[string]string)
+	}
+	writeText(w, http.StatusOK, strconv.Itoa(deleted))
+}
+
+// PUT /config/(subject string)/properties/(property string)
+func (s *Server) setProperty(w http.ResponseWriter, params []string, body []byte) {
+	if s.set(w, params[0], params[1], string(body)) {
+		writeText(w, http.StatusOK, "1")
+	}
+}
+
+// GET /config/(subject string)/properties/(property string)
+func (s *Server) getProperty(w http.ResponseWriter, params []string, body []byte) {
+	if params[1] != model.CompatibilityProp {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation failed: property should be a valid property: %s", params[1]))
+		return
+	}
+	writeText(w, http.StatusOK, string(s.restriction(params[0])))
+}
+
+// DELETE /config/(subject string)/properties/(property string)
+func (s *Server) deleteProperty(w http.ResponseWriter, params []string, body []byte) {
+	deleted := 0
+	if sub, ok := s.subjects[params[0]]; ok {
+		if _, ok := sub.configs[params[1]]; ok {
+			delete(sub.configs, params[1])
+			deleted = 1
+		}
+	}
+	writeText(w, http.StatusOK, strconv.Itoa(deleted))
+}
@@ -0,0 +1,234 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package typebooktest_test
+
+import (
+	"net/http"
+	"testing"
+
+	typebook "github.com/cyberagent/typebook/client/go"
+	"github.com/cyberagent/typebook/client/go/model"
+	"github.com/cyberagent/typebook/client/go/typebooktest"
+)
+
+const (
+	subject = "user-events"
+	v1      = `{"type": "record", "name": "User", "fields": [{"name": "id", "type": "int"}]}`
+	// FULL to v1 since the new field has a default
+	v1Patch = `{"type": "record", "name": "User", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string", "default": ""}]}`
+	// BACKWARD to v1Patch since int is promoted to long
+	v1Minor = `{"type": "record", "name": "User", "fields": [{"name": "id", "type": "long"}, {"name": "name", "type": "string", "default": ""}]}`
+	// NONE to the others
+	v2 = `{"type": "record", "name": "User", "fields": [{"name": "id", "type": "string"}]}`
+)
+
+func statusOf(err *model.Error) int {
+	if err == nil || err.ServerError == nil {
+		return 0
+	}
+	return err.ErrorCode
+}
+
+func TestSubjects(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	client := typebook.NewClient(server.URL)
+
+	if _, err := client.CreateSubject(subject, "events of users"); err != nil {
+		t.Fatalf("CreateSubject should not be an error but an error was occurred: %v", err)
+	}
+	if _, err := client.CreateSubject(subject, "events of users"); statusOf(err) != http.StatusInternalServerError {
+		t.Errorf("a subject is expected not to be created twice but got %v", err)
+	}
+	if _, err := client.UpdateDescription(subject, "all events of users"); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := client.GetSubject(subject); err != nil || actual.Description != "all events of users" {
+		t.Errorf("GetSubject = %v, %v, wants the updated description", actual, err)
+	}
+	if _, err := client.CreateSubject("page-views", ""); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := client.ListSubjects(); err != nil || len(names) != 2 || names[0] != "page-views" {
+		t.Errorf("ListSubjects = %v, %v, wants subjects sorted by name", names, err)
+	}
+
+	if _, err := client.RegisterSchema(subject, v1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteSubject(subject); statusOf(err) != http.StatusInternalServerError {
+		t.Errorf("a subject with schemas is expected not to be deleted but got %v", err)
+	}
+	if deleted, err := client.DeleteSubject("page-views"); err != nil || deleted != 1 {
+		t.Errorf("DeleteSubject = %d, %v, wants 1", deleted, err)
+	}
+	if _, err := client.GetSubject("page-views"); statusOf(err) != http.StatusNotFound {
+		t.Errorf("a deleted subject is expected not to be found but got %v", err)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	client := typebook.NewClient(server.URL)
+
+	if _, err := client.RegisterSchema(subject, v1); statusOf(err) != http.StatusInternalServerError {
+		t.Errorf("a schema is expected not to be registered under a non-existent subject but got %v", err)
+	}
+	client.CreateSubject(subject, "")
+
+	cases := []struct {
+		definition string
+		version    model.SemVer
+	}{
+		{v1, model.SemVer{Major: 1}},
+		{v1Patch, model.SemVer{Major: 1, Patch: 1}},
+		{v1Minor, model.SemVer{Major: 1, Minor: 1}},
+		{v2, model.SemVer{Major: 2}},
+	}
+	for i, c := range cases {
+		id, err := client.RegisterSchema(subject, c.definition)
+		if err != nil {
+			t.Fatalf("RegisterSchema should not be an error but an error was occurred: %v", err)
+		}
+		if id.Id != int64(i+1) {
+			t.Errorf("RegisterSchema = %d, wants %d", id.Id, i+1)
+		}
+		schema, err := client.GetSchemaById(id.Id)
+		if err != nil || schema.Version != c.version {
+			t.Errorf("the schema is expected to be registered as %s but got %v, %v", c.version.String(), schema, err)
+		}
+	}
+
+	// the same definition as the latest is not registered again
+	if id, err := client.RegisterSchema(subject, v2); err != nil || id.Id != 4 {
+		t.Errorf("RegisterSchema of the latest = %v, %v, wants the existing ID 4", id, err)
+	}
+
+	if versions, err := client.ListVersions(subject); err != nil || len(versions) != 4 || versions[3] != (model.SemVer{Major: 2}) {
+		t.Errorf("ListVersions = %v, %v, wants 4 versions", versions, err)
+	}
+	if latest, err := client.GetLatestSchema(subject); err != nil || latest.Id != 4 {
+		t.Errorf("GetLatestSchema = %v, %v, wants ID 4", latest, err)
+	}
+	if schema, err := client.GetSchemaByMajorVersion(subject, 1); err != nil || schema.Id != 3 {
+		t.Errorf("GetSchemaByMajorVersion = %v, %v, wants ID 3", schema, err)
+	}
+	if schema, err := client.GetSchemaBySemVer(subject, model.SemVer{Major: 1, Patch: 1}); err != nil || schema.Id != 2 {
+		t.Errorf("GetSchemaBySemVer = %v, %v, wants ID 2", schema, err)
+	}
+	if _, err := client.GetSchemaBySemVer(subject, model.SemVer{Major: 3}); statusOf(err) != http.StatusNotFound {
+		t.Errorf("a non-existent version is expected not to be found but got %v", err)
+	}
+	if _, err := client.GetSchemaById(5); statusOf(err) != http.StatusNotFound {
+		t.Errorf("a non-existent ID is expected not to be found but got %v", err)
+	}
+
+	// schemas are looked up regardless of formatting
+	formatted := `{
+		"type": "record",
+		"name": "User",
+		"fields": [{"name": "id", "type": "int"}]
+	}`
+	if schema, err := client.LookupSchema(subject, formatted); err != nil || schema.Id != 1 {
+		t.Errorf("LookupSchema = %v, %v, wants ID 1", schema, err)
+	}
+	if schemas, err := client.LookupAllSchemas(subject, v1Patch); err != nil || len(schemas) != 1 || schemas[0].Id != 2 {
+		t.Errorf("LookupAllSchemas = %v, %v, wants ID 2", schemas, err)
+	}
+	if _, err := client.LookupSchema("page-views", v1); statusOf(err) != http.StatusNotFound {
+		t.Errorf("a schema is expected not to be found under another subject but got %v", err)
+	}
+	if _, err := client.RegisterSchema(subject, `{"type": "record"`); err == nil {
+		t.Errorf("an invalid schema is expected to be rejected")
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	client := typebook.NewClient(server.URL)
+	client.CreateSubject(subject, "")
+
+	if restriction, err := client.GetProperty(subject, model.CompatibilityProp); err != nil || restriction != "NONE" {
+		t.Errorf("the compatibility is expected to be NONE by default but got %s, %v", restriction, err)
+	}
+	if _, err := client.SetConfig(subject, model.Config{Compatibility: "BACKWARD"}); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := client.GetConfig(subject); err != nil || config.Compatibility != "BACKWARD" {
+		t.Errorf("GetConfig = %v, %v, wants BACKWARD", config, err)
+	}
+	if _, err := client.SetProperty(subject, model.CompatibilityProp, "SIDEWAYS"); statusOf(err) != http.StatusUnprocessableEntity {
+		t.Errorf("an invalid compatibility is expected to be rejected but got %v", err)
+	}
+	if _, err := client.GetConfig("page-views"); statusOf(err) != http.StatusNotFound {
+		t.Errorf("the config of a non-existent subject is expected not to be found but got %v", err)
+	}
+
+	client.RegisterSchema(subject, v1)
+	if isCompatible, err := client.CheckCompatibilityWithLatest(subject, v2); err != nil || isCompatible.IsCompatible {
+		t.Errorf("CheckCompatibilityWithLatest = %v, %v, wants false", isCompatible, err)
+	}
+	if _, err := client.RegisterSchema(subject, v2); statusOf(err) != http.StatusConflict {
+		t.Errorf("a schema violating the compatibility is expected to be rejected but got %v", err)
+	}
+	prediction, err := client.PredictNextVersion(subject, v1Minor)
+	if err != nil || !prediction.IsAllowed || prediction.Version != (model.SemVer{Major: 1, Minor: 1}) {
+		t.Fatalf("PredictNextVersion = %v, %v, wants v1.1.0", prediction, err)
+	}
+	if id, err := client.RegisterSchema(subject, v1Minor); err != nil || id.Id != 2 {
+		t.Errorf("a schema satisfying the compatibility is expected to be registered but got %v, %v", id, err)
+	}
+
+	if deleted, err := client.DeleteProperty(subject, model.CompatibilityProp); err != nil || deleted != 1 {
+		t.Errorf("DeleteProperty = %d, %v, wants 1", deleted, err)
+	}
+	if _, err := client.RegisterSchema(subject, v2); err != nil {
+		t.Errorf("any schema is expected to be registered without the compatibility but got %v", err)
+	}
+}
+
+func TestAddSchema(t *testing.T) {
+	server := typebooktest.NewServer()
+	defer server.Close()
+	client := typebook.NewClient(server.URL)
+
+	seed := model.Schema{Id: 10, Subject: subject, Version: model.SemVer{Major: 1, Minor: 10}, Definition: v1}
+	if err := server.AddSchema(seed); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddSchema(seed); err == nil {
+		t.Errorf("a schema with the same ID is expected not to be added twice")
+	}
+	if _, err := client.GetSubject(subject); err != nil {
+		t.Errorf("the subject is expected to be created but got %v", err)
+	}
+	// IDs are assigned after the seeded ones
+	if id, err := client.RegisterSchema(subject, v1Patch); err != nil || id.Id != 11 {
+		t.Errorf("RegisterSchema = %v, %v, wants ID 11", id, err)
+	}
+	if schema, err := client.GetSchemaById(11); err != nil || schema.Version != (model.SemVer{Major: 1, Minor: 10, Patch: 1}) {
+		t.Errorf("the schema is expected to follow the seeded version but got %v, %v", schema, err)
+	}
+}
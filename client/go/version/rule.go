@@ -0,0 +1,153 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package version decides semantic versions of schemas locally in the same way as VersioningRule of a typebook server.
+package version
+
+import (
+	"reflect"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/compatibility"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+// Next calculates the version that a typebook server gives target when it is registered
+// under a subject whose schemas with the latest major version are latestMajorSchemas.
+// It complies with the following policy as VersioningRule.nextVersion does.
+//  1. Schemas under the same major version should have at least backward compatibility.
+//  2. Schemas under the same minor version should have full compatibility.
+//
+// The version is decided by the schema with the lowest compatibility, which is marked as decisive in the comparisons returned.
+func Next(target avro.Schema, latestMajorSchemas []model.Schema) (model.SemVer, []model.VersionComparison, error) {
+	schemas := make([]model.Schema, len(latestMajorSchemas))
+	copy(schemas, latestMajorSchemas)
+	sortDescending(schemas)
+
+	comparisons := make([]model.VersionComparison, 0, len(schemas))
+	if len(schemas) == 0 {
+		return model.SemVer{Major: 1, Minor: 0, Patch: 0}, comparisons, nil
+	}
+
+	lowest, lowestLevel := 0, compatibility.Full
+	for i, schema := range schemas {
+		comparison, err := avro.Parse(schema.Definition)
+		if err != nil {
+			return model.SemVer{}, nil, err
+		}
+		level := compatibility.Calculate(target, comparison)
+		if isLower(level, lowestLevel) {
+			lowest, lowestLevel = i, level
+		}
+		comparisons = append(comparisons, model.VersionComparison{
+			Id:            schema.Id,
+			Version:       schema.Version,
+			Compatibility: string(level),
+		})
+	}
+	comparisons[lowest].IsDecisive = true
+
+	latest := schemas[0].Version
+	switch {
+	case lowestLevel == compatibility.None || lowestLevel == compatibility.Forward:
+		return model.SemVer{Major: latest.Major + 1, Minor: 0, Patch: 0}, comparisons, nil
+	case lowestLevel == compatibility.Backward && schemas[lowest].Version.Minor == latest.Minor:
+		return model.SemVer{Major: latest.Major, Minor: latest.Minor + 1, Patch: 0}, comparisons, nil
+	default:
+		return model.SemVer{Major: latest.Major, Minor: latest.Minor, Patch: latest.Patch + 1}, comparisons, nil
+	}
+}
+
+// isLower reports whether level is lower than current, the lowest compatibility found so far.
+func isLower(level, current compatibility.Level) bool {
+	switch current {
+	case compatibility.Backward:
+		return level == compatibility.None || level == compatibility.Forward
+	case compatibility.Full:
+		return level != compatibility.Full
+	}
+	return false
+}
+
+func sortDescending(schemas []model.Schema) {
+	for i := 1; i < len(schemas); i++ {
+		for j := i; j > 0 && schemas[j-1].Version.Less(schemas[j].Version); j-- {
+			schemas[j-1], schemas[j] = schemas[j], schemas[j-1]
+		}
+	}
+}
+
+// IsSame reports whether a typebook server regards a and b as the same definition, in which case
+// registering one of them does not create a new version when the other is the latest.
+// Documentation and aliases are ignored as Avro does.
+func IsSame(a, b avro.Schema) bool {
+	if avro.CanonicalForm(a) != avro.CanonicalForm(b) {
+		return false
+	}
+	// Parsing Canonical Form strips attributes that Avro takes into account, so compare them node by node.
+	return sameAttributes(a, b, make(map[string]bool))
+}
+
+func sameAttributes(a, b avro.Schema, visited map[string]bool) bool {
+	switch a := a.(type) {
+	case *avro.PrimitiveSchema:
+		return reflect.DeepEqual(a.LogicalType, b.(*avro.PrimitiveSchema).LogicalType)
+	case *avro.FixedSchema:
+		return reflect.DeepEqual(a.LogicalType, b.(*avro.FixedSchema).LogicalType)
+	case *avro.EnumSchema:
+		return a.Default == b.(*avro.EnumSchema).Default
+	case *avro.ArraySchema:
+		return sameAttributes(a.Items, b.(*avro.ArraySchema).Items, visited)
+	case *avro.MapSchema:
+		return sameAttributes(a.Values, b.(*avro.MapSchema).Values, visited)
+	case *avro.UnionSchema:
+		for i, t := range a.Types {
+			if !sameAttributes(t, b.(*avro.UnionSchema).Types[i], visited) {
+				return false
+			}
+		}
+		return true
+	case *avro.RecordSchema:
+		if visited[a.Name] {
+			return true
+		}
+		visited[a.Name] = true
+		for i, f := range a.Fields {
+			g := b.(*avro.RecordSchema).Fields[i]
+			if f.HasDefault != g.HasDefault || !reflect.DeepEqual(f.Default, g.Default) || orderOf(f) != orderOf(g) {
+				return false
+			}
+			if !sameAttributes(f.Type, g.Type, visited) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func orderOf(f *avro.Field) string {
+	if f.Order == "" {
+		return "ascending"
+	}
+	return f.Order
+}
@@ -0,0 +1,106 @@
+// The MIT License (MIT)
+//
+// Copyright © 2017 CyberAgent, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package version
+
+import (
+	"testing"
+
+	"github.com/cyberagent/typebook/client/go/avro"
+	"github.com/cyberagent/typebook/client/go/model"
+)
+
+const (
+	base           = `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "int"}]}`
+	withDefault    = `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string", "default": ""}]}`
+	withoutDefault = `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string"}]}`
+	changedType    = `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "string"}]}`
+)
+
+func mustParse(t *testing.T, definition string) avro.Schema {
+	s, err := avro.Parse(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func schemaOf(id int64, major, minor, patch int, definition string) model.Schema {
+	return model.Schema{Id: id, Subject: "person", Version: model.SemVer{Major: major, Minor: minor, Patch: patch}, Definition: definition}
+}
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		name     string
+		target   string
+		existing []model.Schema
+		expect   model.SemVer
+		decisive int64
+	}{
+		{"first version", base, nil, model.SemVer{Major: 1}, 0},
+		{"full", withDefault, []model.Schema{schemaOf(1, 1, 0, 0, base)}, model.SemVer{Major: 1, Minor: 0, Patch: 1}, 1},
+		{"backward", base, []model.Schema{schemaOf(1, 1, 0, 0, withoutDefault)}, model.SemVer{Major: 1, Minor: 1, Patch: 0}, 1},
+		{"forward", withoutDefault, []model.Schema{schemaOf(1, 1, 0, 0, base)}, model.SemVer{Major: 2, Minor: 0, Patch: 0}, 1},
+		{"none", changedType, []model.Schema{schemaOf(1, 1, 0, 0, base)}, model.SemVer{Major: 2, Minor: 0, Patch: 0}, 1},
+		// backward only with an older minor version, which the server regards as a patch
+		{"backward with older minor", base, []model.Schema{schemaOf(1, 1, 0, 0, withoutDefault), schemaOf(2, 1, 1, 0, base)}, model.SemVer{Major: 1, Minor: 1, Patch: 1}, 1},
+		{"lowest decides", base, []model.Schema{schemaOf(2, 1, 1, 0, withoutDefault), schemaOf(1, 1, 0, 0, changedType)}, model.SemVer{Major: 2, Minor: 0, Patch: 0}, 1},
+	}
+
+	for _, c := range cases {
+		actual, comparisons, err := Next(mustParse(t, c.target), c.existing)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if actual != c.expect {
+			t.Errorf("%s: Next = %s, wants %s", c.name, actual.String(), c.expect.String())
+		}
+		if len(comparisons) != len(c.existing) {
+			t.Errorf("%s: %d comparisons are expected but %d", c.name, len(c.existing), len(comparisons))
+		}
+		for _, comparison := range comparisons {
+			if comparison.IsDecisive != (comparison.Id == c.decisive) {
+				t.Errorf("%s: the version is expected to be decided by %d but %v", c.name, c.decisive, comparisons)
+			}
+		}
+	}
+}
+
+func TestIsSame(t *testing.T) {
+	documented := `{"type": "record", "name": "Person", "doc": "a person", "fields": [{"name": "id", "type": "int", "doc": "ID"}]}`
+	otherDefault := `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": "int"}, {"name": "name", "type": "string", "default": "none"}]}`
+	logical := `{"type": "record", "name": "Person", "fields": [{"name": "id", "type": {"type": "int", "logicalType": "date"}}]}`
+
+	if !IsSame(mustParse(t, base), mustParse(t, documented)) {
+		t.Errorf("schemas that differ only in documentation should be the same")
+	}
+	if IsSame(mustParse(t, withDefault), mustParse(t, otherDefault)) {
+		t.Errorf("schemas with different defaults should not be the same")
+	}
+	if IsSame(mustParse(t, base), mustParse(t, logical)) {
+		t.Errorf("schemas with different logical types should not be the same")
+	}
+	if IsSame(mustParse(t, base), mustParse(t, withDefault)) {
+		t.Errorf("schemas with different fields should not be the same")
+	}
+}
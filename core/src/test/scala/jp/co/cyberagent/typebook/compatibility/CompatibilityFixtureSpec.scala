@@ -0,0 +1,53 @@
+package jp.co.cyberagent.typebook.compatibility
+
+import scala.io.Source
+
+import io.circe.Json
+import io.circe.parser.parse
+import org.apache.avro.{Schema => AvroSchema}
+import org.scalatest._
+
+import jp.co.cyberagent.typebook.UnitTest
+
+
+/**
+  * The fixtures are shared with the compatibility package of the Go client
+  * to confirm that the both calculate the same compatibility.
+  */
+object CompatibilityFixtureSpec {
+
+  case class Fixture(name: String, target: Json, comparison: Json, compatibility: String)
+
+  lazy val fixtures: Seq[Fixture] = {
+    val source = Source.fromInputStream(getClass.getResourceAsStream("/compatibility/fixtures.json"), "UTF-8")
+    try {
+      parse(source.mkString).right.get.asArray.get.map { json =>
+        val cursor = json.hcursor
+        Fixture(
+          cursor.get[String]("name").right.get,
+          cursor.downField("target").focus.get,
+          cursor.downField("comparison").focus.get,
+          cursor.get[String]("compatibility").right.get
+        )
+      }
+    } finally {
+      source.close()
+    }
+  }
+
+  def parseSchema(json: Json): AvroSchema = new AvroSchema.Parser().parse(json.noSpaces)
+}
+
+
+class CompatibilityFixtureSpec extends WordSpec with Matchers {
+
+  import CompatibilityFixtureSpec._
+
+  "A method `calcCompatibility`" should {
+    fixtures.foreach { fixture =>
+      s"agree with the shared fixture: ${fixture.name}" taggedAs UnitTest in {
+        CompatibilityUtil.calcCompatibility(parseSchema(fixture.target))(parseSchema(fixture.comparison)) should equal (SchemaCompatibility(fixture.compatibility))
+      }
+    }
+  }
+}